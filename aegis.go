@@ -0,0 +1,23 @@
+package main
+
+import "junjie.pro/informer/pkg/aegis"
+
+func init() {
+	formats["aegis"] = format{read: readAegis}
+}
+
+// readAegis Secures of an Aegis backup, the password is only asked for encrypted ones
+func readAegis(content []byte, options formatOptions) (imported, error) {
+	password := ""
+	if aegis.NeedsPassword(content) {
+		password = options.filePassword()
+	}
+	database, err := aegis.Parse(content, password)
+	if err != nil {
+		return imported{}, err
+	}
+
+	secures, unmapped := database.Secures()
+
+	return imported{secures: secures, unmapped: unmapped}, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const ageHeader = "age-encryption.org/v1\n"
+
+// listFlag Flag given once for every value
+type listFlag []string
+
+func (list *listFlag) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *listFlag) Set(value string) error {
+	*list = append(*list, value)
+
+	return nil
+}
+
+// ageEncrypt Writer encrypting to recipients, or by passphrase if there are no recipients.
+// Close it to finish the file.
+func ageEncrypt(w io.Writer, options formatOptions) (io.WriteCloser, error) {
+	var recipients []age.Recipient
+	for _, recipient := range options.recipients {
+		parsed, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %s: %w", recipient, err)
+		}
+		recipients = append(recipients, parsed)
+	}
+	//age doesn't mix passphrases with other recipients
+	if len(recipients) == 0 {
+		recipient, err := age.NewScryptRecipient(options.passphrase)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	} else if options.passphrase != "" {
+		return nil, errors.New("either recipients or passphrase, not both")
+	}
+
+	return age.Encrypt(w, recipients...)
+}
+
+// ageDecrypt Decrypt content if it's an age file, by identity files or passphrase.
+// Other content is returned as it is.
+func ageDecrypt(content []byte, options formatOptions) ([]byte, error) {
+	var r io.Reader = bytes.NewReader(content)
+	switch {
+	case bytes.HasPrefix(content, []byte(armor.Header)):
+		r = armor.NewReader(r)
+	case bytes.HasPrefix(content, []byte(ageHeader)):
+	default:
+		return content, nil
+	}
+
+	var identities []age.Identity
+	for _, file := range options.identities {
+		identityFile, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := age.ParseIdentities(bufio.NewReader(identityFile))
+		_ = identityFile.Close()
+		if err != nil {
+			return nil, fmt.Errorf("identity file %s: %w", file, err)
+		}
+		identities = append(identities, parsed...)
+	}
+	if len(identities) == 0 {
+		passphrase := options.passphrase
+		if passphrase == "" {
+			fmt.Print("age passphrase: ")
+			stdin.Scan()
+			passphrase = stdin.Text()
+		}
+		identity, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	plain, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(plain)
+}
@@ -0,0 +1,23 @@
+package main
+
+import "junjie.pro/informer/pkg/andotp"
+
+func init() {
+	formats["andotp"] = format{read: readAndOTP}
+}
+
+// readAndOTP Secures of an andOTP backup, the password is only asked for encrypted ones
+func readAndOTP(content []byte, options formatOptions) (imported, error) {
+	password := ""
+	if andotp.NeedsPassword(content) {
+		password = options.filePassword()
+	}
+	backup, err := andotp.Parse(content, password)
+	if err != nil {
+		return imported{}, err
+	}
+
+	secures, unmapped := backup.Secures()
+
+	return imported{secures: secures, unmapped: unmapped}, nil
+}
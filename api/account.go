@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
 	"junjie.pro/informer/conf"
@@ -68,59 +69,74 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//If login information correctly and successfully login, return 200 success,
-	//else return 401 login information not correctly or 500
-	if informerConfig.CheckUser(user) {
-		tokenId := conf.GenerateToken()
-		createDate := time.Now()
-		token := conf.Token{ID: tokenId, CreateDate: createDate}
-		expire := time.Now().AddDate(0, 0, informerConfig.RenewalCycle)
-
-		//Save token
-		informerConfig.User.AddToken(token)
-		err = informerConfig.WriteConfig()
+	//Password login is refused once WebAuthn has taken its place, unless two-factor authentication guards it,
+	//so clients without WebAuthn such as sync can still log in
+	if informerConfig.WebAuthn.DisablePassword && len(informerConfig.User.Credentials) > 0 && !informerConfig.User.TwoFactor.Enabled {
+		w.WriteHeader(403)
+		err = json.NewEncoder(w).Encode(PasswordDisabledMessage)
 		if err != nil {
-			w.WriteHeader(500)
 			log.Println(err)
 		}
 
-		//Set cookie: username and token
-		usernameCookie := http.Cookie{
-			Name:       "username",
-			Value:      user.Username,
-			Path:       "",
-			Domain:     "",
-			Expires:    expire,
-			RawExpires: "",
-			MaxAge:     0,
-			Secure:     false,
-			HttpOnly:   true,
-			SameSite:   0,
-			Raw:        "",
-			Unparsed:   nil,
-		}
-		http.SetCookie(w, &usernameCookie)
-		tokenCookie := http.Cookie{
-			Name:       "token",
-			Value:      tokenId,
-			Path:       "",
-			Domain:     "",
-			Expires:    expire,
-			RawExpires: "",
-			MaxAge:     0,
-			Secure:     false,
-			HttpOnly:   true,
-			SameSite:   0,
-			Raw:        "",
-			Unparsed:   nil,
-		}
-		http.SetCookie(w, &tokenCookie)
+		return
+	}
 
-		w.WriteHeader(200)
-		err = json.NewEncoder(w).Encode(SuccessMessage)
-		if err != nil {
-			log.Println(err)
+	//If login information correctly and successfully login, return 200 success,
+	//else return 401 login information not correctly or 500
+	if informerConfig.CheckUser(user) {
+		//With two-factor authentication enabled, login needs a code besides the password
+		if informerConfig.User.TwoFactor.Enabled {
+			if user.Code == "" {
+				w.WriteHeader(401)
+				err = json.NewEncoder(w).Encode(TwoFactorNeededMessage)
+				if err != nil {
+					log.Println(err)
+				}
+
+				return
+			}
+
+			verified, err := verifyTwoFactor(user.Password, user.Code)
+			if errors.Is(err, errTwoFactorThrottled) {
+				w.WriteHeader(429)
+				err = json.NewEncoder(w).Encode(TwoFactorThrottledMessage)
+				if err != nil {
+					log.Println(err)
+				}
+
+				return
+			}
+			if err != nil {
+				log.Println(err)
+				w.WriteHeader(500)
+				err = json.NewEncoder(w).Encode(DataNotCorrectMessage)
+				if err != nil {
+					log.Println(err)
+				}
+
+				return
+			}
+			if !verified {
+				w.WriteHeader(401)
+				err = json.NewEncoder(w).Encode(TwoFactorNotCorrectMessage)
+				if err != nil {
+					log.Println(err)
+				}
+
+				return
+			}
+
+			//Verification has written what the code used up
+			informerConfig, err = conf.ReadConfig()
+			if err != nil {
+				w.WriteHeader(500)
+				log.Println(err)
+
+				return
+			}
 		}
+
+		issueLoginToken(w, informerConfig, user.Username)
 	} else {
 		w.WriteHeader(401)
 		message := Message{Message: "username or password not correctly"}
@@ -131,6 +147,60 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// issueLoginToken Save a new login token of username, set it as cookies and return 200 success
+func issueLoginToken(w http.ResponseWriter, informerConfig conf.InformerConfig, username string) {
+	tokenId := conf.GenerateToken()
+	createDate := time.Now()
+	token := conf.Token{ID: tokenId, CreateDate: createDate}
+	expire := time.Now().AddDate(0, 0, informerConfig.RenewalCycle)
+
+	//Save token
+	informerConfig.User.AddToken(token)
+	err := informerConfig.WriteConfig()
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println(err)
+	}
+
+	//Set cookie: username and token
+	usernameCookie := http.Cookie{
+		Name:       "username",
+		Value:      username,
+		Path:       "",
+		Domain:     "",
+		Expires:    expire,
+		RawExpires: "",
+		MaxAge:     0,
+		Secure:     false,
+		HttpOnly:   true,
+		SameSite:   0,
+		Raw:        "",
+		Unparsed:   nil,
+	}
+	http.SetCookie(w, &usernameCookie)
+	tokenCookie := http.Cookie{
+		Name:       "token",
+		Value:      tokenId,
+		Path:       "",
+		Domain:     "",
+		Expires:    expire,
+		RawExpires: "",
+		MaxAge:     0,
+		Secure:     false,
+		HttpOnly:   true,
+		SameSite:   0,
+		Raw:        "",
+		Unparsed:   nil,
+	}
+	http.SetCookie(w, &tokenCookie)
+
+	w.WriteHeader(200)
+	err = json.NewEncoder(w).Encode(SuccessMessage)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
 func Logout(w http.ResponseWriter, r *http.Request) {
 	//Response message is json
 	w.Header().Add("Content-Type", "application/json")
@@ -154,9 +224,10 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 
 	//If user already logged in, remove token
 	if username != nil && tokenId != nil && informerConfig.CheckLogin(username.Value, tokenId.Value) {
-		//Remove token
+		//Remove token, and wipe library unlocked for it
 		token := conf.Token{ID: tokenId.Value}
 		informerConfig.RemoveToken(token)
+		forgetSessionLibrary(tokenId.Value)
 
 		//Write informer configurations
 		err = informerConfig.WriteConfig()
@@ -238,6 +309,19 @@ func ChangePassword(w http.ResponseWriter, r *http.Request) {
 	//Confirm and change password
 	user := conf.User{Username: username.Value, Password: passwords.OldPassword}
 	if passwords.NewPassword == passwords.ConfirmPassword && informerConfig.CheckUser(user) {
+		//Two-factor secret is encrypted by the password
+		err = informerConfig.User.TwoFactor.Rekey(passwords.OldPassword, passwords.NewPassword)
+		if err != nil {
+			log.Println(err.Error())
+			w.WriteHeader(500)
+			err = json.NewEncoder(w).Encode(DataNotCorrectMessage)
+			if err != nil {
+				log.Println(err.Error())
+			}
+
+			return
+		}
+
 		user.Password = passwords.NewPassword
 		informerConfig.ChangePassword(user)
 	} else {
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"junjie.pro/informer/conf"
+	"junjie.pro/informer/pkg/backup"
+	"junjie.pro/informer/pkg/library"
+	"log"
+	"net/http"
+)
+
+// Backup Return an encrypted backup of library, passphrase of request body encrypts it.
+// It's never taken from query parameters, they end up in access logs and browser history.
+func Backup(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	//Read informer configurations
+	informerConfig, err := conf.ReadConfig()
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println(err)
+	}
+
+	//Read login token from cookie
+	username, err := r.Cookie("username")
+	if err != nil {
+		log.Println(err)
+	}
+	tokenId, err := r.Cookie("token")
+	if err != nil {
+		log.Println(err)
+	}
+
+	//Check user is already logged in whether
+	if username == nil || tokenId == nil || !informerConfig.CheckLogin(username.Value, tokenId.Value) {
+		w.WriteHeader(403)
+		err = json.NewEncoder(w).Encode(NotLoggedInMessage)
+		if err != nil {
+			log.Println(err)
+		}
+
+		return
+	}
+
+	//Read passphrase from request body, then wipe it
+	body, err := ioutil.ReadAll(io.Reader(r.Body))
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println(err.Error())
+
+		return
+	}
+	err = r.Body.Close()
+	if err != nil {
+		log.Println(err.Error())
+	}
+	var bundle BackupBundle
+	err = json.Unmarshal(body, &bundle)
+	library.Secret(body).Wipe()
+	defer bundle.Passphrase.Wipe()
+	if err != nil || len(bundle.Passphrase) == 0 {
+		w.WriteHeader(400)
+		err = json.NewEncoder(w).Encode(DataNotCorrectMessage)
+		if err != nil {
+			log.Println(err)
+		}
+
+		return
+	}
+
+	contents, err := backup.Collect(bundle.IncludeUser)
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println(err.Error())
+
+		return
+	}
+	archive, err := backup.Create(contents, bundle.Passphrase.Reveal())
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println(err.Error())
+
+		return
+	}
+
+	w.Header().Add("Content-Disposition", `attachment; filename="informer.backup"`)
+	w.WriteHeader(200)
+	_, err = w.Write(archive)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
@@ -1,17 +1,39 @@
 package api
 
-import "junjie.pro/informer/pkg/library"
+import (
+	"junjie.pro/informer/pkg/library"
+	"junjie.pro/informer/pkg/webauthn"
+)
 
 var (
 	SuccessMessage        = Message{Message: "success"}
 	NotLoggedInMessage    = Message{Message: "not logged in"}
 	DataNotCorrectMessage = Message{Message: "data not correctly"}
+	NotFoundMessage       = Message{Message: "not found"}
+	LockedMessage         = Message{Message: "library is locked"}
+	WrongKeyMessage       = Message{Message: "key not correctly"}
+	LibraryChangedMessage = Message{Message: "library changed meanwhile, try again"}
+
+	TwoFactorNeededMessage     = Message{Message: "two-factor code needed"}
+	TwoFactorNotCorrectMessage = Message{Message: "two-factor code not correctly"}
+	TwoFactorThrottledMessage  = Message{Message: "too many two-factor codes tried, try again later"}
+	PasswordDisabledMessage    = Message{Message: "password login is disabled, log in by WebAuthn"}
+
+	WebAuthnNotConfiguredMessage = Message{Message: "WebAuthn isn't configured, set its rp-id and origin"}
 )
 
 type Message struct {
 	Message string `json:"message"`
 }
 
+// MessageWithTestCodes Message of added or updated secures, with current OTP codes by id to confirm against providers,
+// and warnings about weak passwords by id
+type MessageWithTestCodes struct {
+	Message          string            `json:"message"`
+	TestCodes        map[string]string `json:"testCodes,omitempty"`
+	PasswordWarnings map[string]string `json:"passwordWarnings,omitempty"`
+}
+
 type PrimaryKeys struct {
 	PrimaryKey []string `json:"primaryKey"`
 	Key        string   `json:"key"`
@@ -28,3 +50,50 @@ type PasswordBundle struct {
 	NewPassword     string `json:"newPassword"`
 	ConfirmPassword string `json:"confirmPassword"`
 }
+
+// PasswordStrengthBundle Password to estimate strength of, with user inputs such as username and site
+type PasswordStrengthBundle struct {
+	Password   string   `json:"password"`
+	UserInputs []string `json:"userInputs"`
+}
+
+// BackupBundle Passphrase encrypting a backup, user and login tokens are included if IncludeUser is true
+type BackupBundle struct {
+	Passphrase  library.Secret `json:"passphrase"`
+	IncludeUser bool           `json:"includeUser"`
+}
+
+// TwoFactorBundle Password confirming changes of two-factor authentication, and a code of it
+type TwoFactorBundle struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// TwoFactorEnrollment Secret of enrolled two-factor authentication, to be added to an authenticator
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodesWithMessage Recovery codes of enabled two-factor authentication, shown once
+type RecoveryCodesWithMessage struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// WebAuthnRegistration Credential created by browser for registration, and the name it's listed by
+type WebAuthnRegistration struct {
+	Name       string                       `json:"name"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+// PasswordRulesBundle Password rules of a platform in passwordrules syntax
+type PasswordRulesBundle struct {
+	Rules string `json:"rules"`
+}
+
+type SyncBundle struct {
+	Revision  uint64                 `json:"revision"`
+	Changes   []library.SyncChange   `json:"changes"`
+	Conflicts []library.SyncConflict `json:"conflicts"`
+}
@@ -0,0 +1,23 @@
+//go:build linux
+// +build linux
+
+package api
+
+import "syscall"
+
+const prSetDumpable = 4
+
+// disableCoreDumps Keep decrypted secures out of core dumps and away from ptrace of other processes.
+func disableCoreDumps() error {
+	err := syscall.Setrlimit(syscall.RLIMIT_CORE, &syscall.Rlimit{Cur: 0, Max: 0})
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetDumpable, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package api
+
+// disableCoreDumps Core dumps are only disabled on Linux.
+func disableCoreDumps() error {
+	return nil
+}
@@ -2,11 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
 	"io"
 	"io/ioutil"
 	"junjie.pro/informer/conf"
 	"junjie.pro/informer/pkg/library"
+	"junjie.pro/informer/pkg/strength"
 	"log"
 	"net/http"
 )
@@ -44,16 +46,16 @@ func List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//Read informer library
-	informerLibrary, err := library.ReadLibrary()
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-
-	//Using query parameters to query secures. If key is given, informer will decrypt secures
+	//Using query parameters to query secures. If key is given, library unlocked for this session is used
+	var informerLibrary library.InformerLibrary
 	queryParams := r.URL.Query()
 	if queryParams["key"] != nil && queryParams["key"][0] != "" {
-		err = informerLibrary.Unlock([]byte(queryParams["key"][0]))
+		informerLibrary, err = sessionLibrary(tokenId.Value, queryParams["key"][0])
+		if errors.Is(err, library.ErrWrongKey) {
+			writeMessage(w, 403, WrongKeyMessage)
+
+			return
+		}
 		if err != nil {
 			log.Println(err.Error())
 			w.WriteHeader(500)
@@ -64,10 +66,18 @@ func List(w http.ResponseWriter, r *http.Request) {
 
 			return
 		}
+		defer informerLibrary.Wipe()
+	} else {
+		//Read informer library
+		informerLibrary, err = library.ReadLibrary()
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
 	}
 	//Find secures by query string, and results is encoded in json
 	if queryParams["query"] != nil {
 		found, secures := informerLibrary.Query(queryParams["query"][0])
+		defer library.WipeCopies(secures)
 		if found {
 			w.WriteHeader(200)
 			err = json.NewEncoder(w).Encode(secures)
@@ -80,7 +90,9 @@ func List(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//If not given any query string, just list all of secures without decrypt
-	err = json.NewEncoder(w).Encode(informerLibrary.List())
+	secures := informerLibrary.List()
+	defer library.WipeCopies(secures)
+	err = json.NewEncoder(w).Encode(secures)
 	if err != nil {
 		log.Fatalln(err.Error())
 	}
@@ -135,9 +147,10 @@ func Add(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//Parse encryption key and secure(s) from request body
+	//Parse encryption key and secure(s) from request body, then wipe it
 	var secureNKey secureWithKey
 	err = json.Unmarshal(body, &secureNKey)
+	library.Secret(body).Wipe()
 	if err != nil {
 		w.WriteHeader(500)
 		err = json.NewEncoder(w).Encode(DataNotCorrectMessage)
@@ -148,12 +161,27 @@ func Add(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	//Reject invalid OTP secrets and password rules before anything is written, weak passwords are only warned about
+	testCodes, ok := normalizeOTPs(w, secureNKey.Secure)
+	if !ok {
+		return
+	}
+	if !checkPasswordRules(w, secureNKey.Secure) {
+		return
+	}
+	warnings := passwordWarnings(secureNKey.Secure)
+
 	informerLibrary, err := library.ReadLibrary()
 	if err != nil {
 		log.Fatalln(err.Error())
 	}
 
 	err = informerLibrary.Unlock([]byte(secureNKey.Key))
+	if errors.Is(err, library.ErrWrongKey) {
+		writeMessage(w, 403, WrongKeyMessage)
+
+		return
+	}
 	if err != nil {
 		log.Println(err.Error())
 		w.WriteHeader(500)
@@ -179,12 +207,13 @@ func Add(w http.ResponseWriter, r *http.Request) {
 
 	err = informerLibrary.WriteLibrary()
 	if err != nil {
-		w.WriteHeader(500)
-		log.Fatalln(err.Error())
+		writeLibraryFailed(w, err)
+
+		return
 	}
 
 	w.WriteHeader(200)
-	err = json.NewEncoder(w).Encode(SuccessMessage)
+	err = json.NewEncoder(w).Encode(MessageWithTestCodes{Message: SuccessMessage.Message, TestCodes: testCodes, PasswordWarnings: warnings})
 	if err != nil {
 		log.Fatalln(err.Error())
 	}
@@ -237,14 +266,44 @@ func Remove(w http.ResponseWriter, r *http.Request) {
 		log.Fatalln(err.Error())
 	}
 
+	//If key is given, remove secure from the set it unlocks
+	queryParams := r.URL.Query()
+	if queryParams["key"] != nil && queryParams["key"][0] != "" {
+		err = informerLibrary.Unlock([]byte(queryParams["key"][0]))
+		if errors.Is(err, library.ErrWrongKey) {
+			writeMessage(w, 403, WrongKeyMessage)
+
+			return
+		}
+		if err != nil {
+			log.Println(err.Error())
+			w.WriteHeader(500)
+			err = json.NewEncoder(w).Encode(DataNotCorrectMessage)
+			if err != nil {
+				log.Fatalln(err.Error())
+			}
+
+			return
+		}
+	}
+
 	//Find index of secure and remove it
 	informerLibrary.Remove(primaryKey)
 
+	if queryParams["key"] != nil && queryParams["key"][0] != "" {
+		err = informerLibrary.Lock([]byte(queryParams["key"][0]))
+		if err != nil {
+			w.WriteHeader(500)
+			log.Println(err.Error())
+
+			return
+		}
+	}
+
 	//Write informer library
 	err = informerLibrary.WriteLibrary()
 	if err != nil {
-		w.WriteHeader(500)
-		log.Println(err.Error())
+		writeLibraryFailed(w, err)
 
 		return
 	}
@@ -256,7 +315,7 @@ func Remove(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Update Replace a secure by other one
+// Update Update a secure by fields of other one, fields left empty keep their values
 func Update(w http.ResponseWriter, r *http.Request) {
 	//Response message is json
 	w.Header().Add("Content-Type", "application/json")
@@ -303,9 +362,10 @@ func Update(w http.ResponseWriter, r *http.Request) {
 
 	pathVars := mux.Vars(r)
 	primaryKey := pathVars["uuid"]
-	//Parse encryption key and secure(s) from request body
+	//Parse encryption key and secure(s) from request body, then wipe it
 	var secureNKey PrimaryKeyWithSecures
 	err = json.Unmarshal(body, &secureNKey)
+	library.Secret(body).Wipe()
 	if err != nil {
 		log.Println(err.Error())
 
@@ -330,6 +390,16 @@ func Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	//Reject invalid OTP secret and password rules before anything is written, weak passwords are only warned about
+	testCodes, ok := normalizeOTPs(w, secureNKey.Secures)
+	if !ok {
+		return
+	}
+	if !checkPasswordRules(w, secureNKey.Secures) {
+		return
+	}
+	warnings := passwordWarnings(secureNKey.Secures)
+
 	//Read informer library
 	informerLibrary, err := library.ReadLibrary()
 	if err != nil {
@@ -338,6 +408,11 @@ func Update(w http.ResponseWriter, r *http.Request) {
 
 	//Unlock informer library
 	err = informerLibrary.Unlock([]byte(secureNKey.Key))
+	if errors.Is(err, library.ErrWrongKey) {
+		writeMessage(w, 403, WrongKeyMessage)
+
+		return
+	}
 	if err != nil {
 		log.Println(err.Error())
 		w.WriteHeader(500)
@@ -349,8 +424,15 @@ func Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//Using origin secure to find index and replace by updated secure
-	informerLibrary.Update(primaryKey, secureNKey.Secures[0])
+	//Fields of updated secure are merged into origin secure, empty ones keep their values
+	err = informerLibrary.Edit(primaryKey, secureNKey.Secures[0])
+	if err != nil {
+		log.Println(err.Error())
+		informerLibrary.Wipe()
+		writeMessage(w, 404, NotFoundMessage)
+
+		return
+	}
 
 	//Lock informer library
 	err = informerLibrary.Lock([]byte(secureNKey.Key))
@@ -364,20 +446,37 @@ func Update(w http.ResponseWriter, r *http.Request) {
 	//Write informer library
 	err = informerLibrary.WriteLibrary()
 	if err != nil {
-		w.WriteHeader(500)
-		log.Println(err.Error())
+		writeLibraryFailed(w, err)
 
 		return
 	}
 
 	//Return 200 success
 	w.WriteHeader(200)
-	err = json.NewEncoder(w).Encode(SuccessMessage)
+	err = json.NewEncoder(w).Encode(MessageWithTestCodes{Message: SuccessMessage.Message, TestCodes: testCodes, PasswordWarnings: warnings})
 	if err != nil {
 		log.Fatalln(err.Error())
 	}
 }
 
+// normalizeOTPs Normalize OTP secrets of secures and return their test codes by id.
+// If one is invalid, 400 is written with the reason and false is returned.
+func normalizeOTPs(w http.ResponseWriter, secures []library.SecureStore) (map[string]string, bool) {
+	testCodes := map[string]string{}
+	for i := range secures {
+		testCode, err := secures[i].NormalizeOTP()
+		if err != nil {
+			writeMessage(w, 400, Message{Message: "otp of " + secures[i].ID + ": " + err.Error()})
+			return nil, false
+		}
+		if testCode != "" {
+			testCodes[secures[i].ID] = testCode
+		}
+	}
+
+	return testCodes, true
+}
+
 // ChangeMasterPassword Change user's master password
 func ChangeMasterPassword(w http.ResponseWriter, r *http.Request) {
 	//Response message is json
@@ -447,8 +546,21 @@ func ChangeMasterPassword(w http.ResponseWriter, r *http.Request) {
 
 	//Change password when they correctly
 	if passwords.NewPassword == passwords.ConfirmPassword {
+		//Reject weak master password, it's all that protects secures of library
+		result := strength.Estimate(passwords.NewPassword, username.Value, "informer")
+		if result.Weak() {
+			writeMessage(w, 400, Message{Message: "master password is too weak: " + result.Summary()})
+
+			return
+		}
+
 		//Unlock informer library using old password
 		err = informerLibrary.Unlock([]byte(passwords.OldPassword))
+		if errors.Is(err, library.ErrWrongKey) {
+			writeMessage(w, 403, WrongKeyMessage)
+
+			return
+		}
 		if err != nil {
 			log.Println(err.Error())
 			w.WriteHeader(500)
@@ -469,11 +581,15 @@ func ChangeMasterPassword(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		//Secures are encrypted differently now, offline copies must pull all of them again
+		informerLibrary.Touch()
+
 		//Write informer library
 		err = informerLibrary.WriteLibrary()
 		if err != nil {
-			w.WriteHeader(500)
-			log.Fatalln(err.Error())
+			writeLibraryFailed(w, err)
+
+			return
 		}
 	} else {
 		//If passwords not correctly, return 500 data not correctly
@@ -2,23 +2,163 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
+	"image/png"
 	"junjie.pro/informer/conf"
 	"junjie.pro/informer/pkg/library"
 	"junjie.pro/informer/pkg/otp"
+	"junjie.pro/informer/pkg/qr"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 )
 
+// GeneratePassCode Return OTP code of a secure, with the time it stays valid and the code following it
 func GeneratePassCode(w http.ResponseWriter, r *http.Request) {
 	//Response message is json
 	w.Header().Add("Content-Type", "application/json")
 
+	informerLibrary, ok := unlockedLibrary(w, r)
+	if !ok {
+		return
+	}
+	defer informerLibrary.Wipe()
+
+	pathVars := mux.Vars(r)
+	primaryKey := pathVars["uuid"]
+	secure, ok := informerLibrary.SecureStore[primaryKey]
+	if !ok || len(secure.OTP) == 0 {
+		writeMessage(w, 404, NotFoundMessage)
+		return
+	}
+
+	//Every code issued of counter based types moves the counter on
+	account := secure.OTPAccount()
+	var passCode otpPassCode
+	var err error
+	if otp.CounterBased(account.Type) {
+		passCode.PassCode, err = library.IssueHOTPCode(&informerLibrary, []byte(r.URL.Query().Get("key")), primaryKey)
+	} else {
+		passCode, err = timedPassCode(account, time.Now())
+	}
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+	passCode.PrimaryKey = primaryKey
+	passCode.ID = secure.ID
+
+	err = json.NewEncoder(w).Encode(passCode)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// PassCodeQR Return otpauth URI of a secure as PNG of QR code, for enrolling another authenticator
+func PassCodeQR(w http.ResponseWriter, r *http.Request) {
+	//Error messages are json
+	w.Header().Add("Content-Type", "application/json")
+
+	informerLibrary, ok := unlockedLibrary(w, r)
+	if !ok {
+		return
+	}
+	defer informerLibrary.Wipe()
+
+	pathVars := mux.Vars(r)
+	secure, ok := informerLibrary.SecureStore[pathVars["uuid"]]
+	if !ok || len(secure.OTP) == 0 {
+		writeMessage(w, 404, NotFoundMessage)
+		return
+	}
+	if !otp.HasURI(secure.OTPType) {
+		writeMessage(w, 400, Message{Message: "otp of " + secure.ID + " can't be given by QR code, enter its secret instead"})
+		return
+	}
+
+	scale, err := strconv.Atoi(r.URL.Query().Get("scale"))
+	if err != nil || scale < 1 || scale > 32 {
+		scale = 8
+	}
+	img, err := qr.Encode(secure.OTPAccount().URI(), scale)
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+
+	//The code holds the secret, it mustn't be kept by caches
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	err = png.Encode(w, img)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// GeneratePassCodes Return current OTP codes of all secures matching query string, or of all secures if it's empty.
+// Codes of counter based types are left out, as issuing them moves their counters on.
+func GeneratePassCodes(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	informerLibrary, ok := unlockedLibrary(w, r)
+	if !ok {
+		return
+	}
+	defer informerLibrary.Wipe()
+
+	var secures map[string]library.SecureStore
+	if query := r.URL.Query().Get("query"); query != "" {
+		_, secures = informerLibrary.Query(query)
+	} else {
+		secures = informerLibrary.List()
+	}
+	defer library.WipeCopies(secures)
+
+	now := time.Now()
+	passCodes := []otpPassCode{}
+	for k, secure := range secures {
+		account := secure.OTPAccount()
+		if len(secure.OTP) == 0 || otp.CounterBased(account.Type) {
+			continue
+		}
+
+		passCode, err := timedPassCode(account, now)
+		if err != nil {
+			log.Println(secure.ID, err.Error())
+			continue
+		}
+		passCode.PrimaryKey = k
+		passCode.ID = secure.ID
+		passCodes = append(passCodes, passCode)
+	}
+	sort.Slice(passCodes, func(i, j int) bool {
+		return passCodes[i].ID < passCodes[j].ID
+	})
+
+	err := json.NewEncoder(w).Encode(passCodes)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// unlockedLibrary Library unlocked by key of query parameters for a logged in user.
+// Otherwise the response is written and false is returned.
+func unlockedLibrary(w http.ResponseWriter, r *http.Request) (library.InformerLibrary, bool) {
 	//Read informer configurations
 	informerConfig, err := conf.ReadConfig()
 	if err != nil {
-		w.WriteHeader(500)
 		log.Println(err)
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return library.InformerLibrary{}, false
 	}
 
 	//Read login token from cookie
@@ -33,54 +173,82 @@ func GeneratePassCode(w http.ResponseWriter, r *http.Request) {
 
 	//Check user is already logged in whether
 	if username == nil || tokenId == nil || !informerConfig.CheckLogin(username.Value, tokenId.Value) {
-		w.WriteHeader(403)
-		err = json.NewEncoder(w).Encode(NotLoggedInMessage)
-		if err != nil {
-			log.Println(err)
-		}
+		writeMessage(w, 403, NotLoggedInMessage)
 
-		return
+		return library.InformerLibrary{}, false
+	}
+
+	//Secrets of a locked library are cipher text, codes can't be generated of them
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeMessage(w, 409, LockedMessage)
+
+		return library.InformerLibrary{}, false
 	}
 
-	//Read informer library
-	informerLibrary, err := library.ReadLibrary()
+	//Library unlocked for this session is used, it's only decrypted again when it changes
+	informerLibrary, err := sessionLibrary(tokenId.Value, key)
+	if errors.Is(err, library.ErrWrongKey) {
+		writeMessage(w, 403, WrongKeyMessage)
+
+		return library.InformerLibrary{}, false
+	}
 	if err != nil {
 		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
 
-		return
+		return library.InformerLibrary{}, false
 	}
 
-	queryParams := r.URL.Query()
-	if queryParams["key"] != nil && queryParams["key"][0] != "" {
-		err = informerLibrary.Unlock([]byte(queryParams["key"][0]))
-		if err != nil {
-			log.Println(err.Error())
-			w.WriteHeader(500)
-			err = json.NewEncoder(w).Encode(DataNotCorrectMessage)
-			if err != nil {
-				log.Println(err.Error())
-			}
-
-			return
-		}
-	}
+	return informerLibrary, true
+}
 
-	pathVars := mux.Vars(r)
-	primaryKey := pathVars["uuid"]
-	otpSecret := informerLibrary.SecureStore[primaryKey].OTP
-	if otpSecret == "" {
-		//TODO return 404 if otp secret is empty string
-		return
+// timedPassCode Code of account at time t, with the time it stays valid and the code following it
+func timedPassCode(account otp.Account, t time.Time) (otpPassCode, error) {
+	passCode, err := otp.GenerateCode(account, t)
+	if err != nil {
+		return otpPassCode{}, err
+	}
+	remaining := otp.Remaining(account, t)
+	nextPassCode, err := otp.GenerateCode(account, t.Add(time.Duration(remaining)*time.Second))
+	if err != nil {
+		return otpPassCode{}, err
 	}
 
-	passCode := otp.GenerateTotpPassCode(otpSecret)
-	passCodeJson := otpPassCode{PassCode: passCode}
-	err = json.NewEncoder(w).Encode(passCodeJson)
+	return otpPassCode{
+		PassCode:     passCode,
+		NextPassCode: nextPassCode,
+		Period:       otp.Period(account),
+		Remaining:    remaining,
+	}, nil
+}
+
+// writeMessage Write message as response of status code
+func writeMessage(w http.ResponseWriter, statusCode int, message Message) {
+	w.WriteHeader(statusCode)
+	err := json.NewEncoder(w).Encode(message)
 	if err != nil {
 		log.Println(err.Error())
 	}
 }
 
+// writeLibraryFailed Answer a library which couldn't be written, 409 if someone else wrote it since it was read
+func writeLibraryFailed(w http.ResponseWriter, err error) {
+	log.Println(err.Error())
+	if errors.Is(err, library.ErrLibraryChanged) {
+		writeMessage(w, 409, LibraryChangedMessage)
+
+		return
+	}
+	w.WriteHeader(500)
+}
+
+// otpPassCode OTP code of a secure, codes of counter based types have no period or next code
 type otpPassCode struct {
-	PassCode string `json:"passCode"`
+	PrimaryKey   string `json:"primaryKey"`
+	ID           string `json:"id"`
+	PassCode     string `json:"passCode"`
+	NextPassCode string `json:"nextPassCode,omitempty"`
+	Period       int    `json:"period,omitempty"`
+	Remaining    int    `json:"remaining,omitempty"`
 }
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"io/ioutil"
+	"junjie.pro/informer/conf"
+	"junjie.pro/informer/pkg/library"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUnlockedLibrary(t *testing.T) {
+	home, err := ioutil.TempDir("", "informer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv("XDG_DATA_HOME", os.Getenv("XDG_DATA_HOME"))
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	err = os.MkdirAll(filepath.Join(home, "data", "informer"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	informerConfig := conf.InformerConfig{Version: "0.1", RenewalCycle: 1,
+		User: conf.User{Username: "bob", Tokens: []conf.Token{{ID: "token", CreateDate: time.Now()}}}}
+	err = informerConfig.WriteConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "0123456789abcdef"
+	informerLibrary := library.InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*library.SecureStore{}}
+	informerLibrary.Add(library.SecureStore{ID: "mail", Password: library.NewSecret("password")})
+	err = informerLibrary.Lock([]byte(key))
+	if err == nil {
+		err = informerLibrary.WriteLibrary()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer forgetSessionLibrary("token")
+
+	//A wrong key is refused, not taken as a server error
+	statuses := map[string]int{key: 200, "fedcba9876543210": 403, "short": 403, "": 409}
+	for requestKey, status := range statuses {
+		r := httptest.NewRequest(http.MethodGet, "/otp?key="+requestKey, nil)
+		r.AddCookie(&http.Cookie{Name: "username", Value: "bob"})
+		r.AddCookie(&http.Cookie{Name: "token", Value: "token"})
+		w := httptest.NewRecorder()
+
+		unlocked, ok := unlockedLibrary(w, r)
+		if ok {
+			unlocked.Wipe()
+			w.WriteHeader(200)
+		}
+		if w.Code != status {
+			t.Errorf("key %q: status %d, expected %d", requestKey, w.Code, status)
+		}
+	}
+}
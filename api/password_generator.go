@@ -2,50 +2,196 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"junjie.pro/informer/pkg/diceware"
+	"junjie.pro/informer/pkg/generator"
 	"log"
-	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
 )
 
-var (
-	lowerCase = "abcdefghijklmnopqrstuvwxyz"
-	upperCase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	number    = "0123456789"
-	symbol    = "$&()*+[]@#^-_!?"
-
-	passwordDict = lowerCase + upperCase + number + symbol
-)
-
-func GeneratePassword(w http.ResponseWriter, _ *http.Request) {
+// GeneratePassword Generate a password of options in query parameters, defaults are 16 characters of every class.
+// Password rules of a site in rules parameter take the place of the other options, except length.
+func GeneratePassword(w http.ResponseWriter, r *http.Request) {
 	//Response message is json
 	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(200)
 
-	//Generate 16 characters password
-	password := generatePassword(16)
+	var password string
+	var err error
+	if query := r.URL.Query(); query.Get("rules") != "" {
+		password, err = generateByRules(query)
+	} else {
+		var options generator.Options
+		options, err = generatorOptions(query)
+		if err == nil {
+			password, err = generator.Generate(options)
+		}
+	}
+	if err != nil {
+		writeMessage(w, 400, Message{Message: err.Error()})
+		return
+	}
 
 	//Return generated password and success
+	w.WriteHeader(200)
 	message := SinglePasswordWithMessage{Password: password, Message: "success"}
 
-	err := json.NewEncoder(w).Encode(message)
+	err = json.NewEncoder(w).Encode(message)
 	if err != nil {
 		log.Println(err.Error())
 	}
 }
 
-func generatePassword(length uint) string {
-	var password string
+// GeneratePassphrase Generate a Diceware passphrase of options in query parameters, with its entropy in bits.
+// Defaults are 6 words of EFF's large wordlist separated by hyphens.
+func GeneratePassphrase(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	wordlistName := query.Get("wordlist")
+	if wordlistName == "" {
+		wordlistName = "large"
+	}
+	wordlist, err := diceware.Embedded(wordlistName)
+	if err != nil {
+		writeMessage(w, 400, Message{Message: err.Error()})
+		return
+	}
+	options, err := passphraseOptions(query)
+	if err != nil {
+		writeMessage(w, 400, Message{Message: err.Error()})
+		return
+	}
+	passphrase, err := wordlist.Generate(options)
+	if err != nil {
+		writeMessage(w, 400, Message{Message: err.Error()})
+		return
+	}
+
+	//Return generated passphrase and success
+	w.WriteHeader(200)
+	message := PassphraseWithMessage{Passphrase: passphrase.Passphrase, Entropy: passphrase.Entropy, Message: "success"}
+
+	err = json.NewEncoder(w).Encode(message)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// generateByRules Generate a password satisfying rules parameter, of length parameter if it's within rules
+func generateByRules(query url.Values) (string, error) {
+	rules, err := generator.ParseRules(query.Get("rules"))
+	if err != nil {
+		return "", err
+	}
+
+	length := 0
+	if query.Get("length") != "" {
+		length, err = strconv.Atoi(query.Get("length"))
+		if err != nil {
+			return "", fmt.Errorf("length must be a number")
+		}
+	}
+
+	return generator.GenerateByRules(rules, length)
+}
+
+// passphraseOptions Passphrase options of query parameters, which are named like flags of generate command
+func passphraseOptions(query url.Values) (diceware.Options, error) {
+	options := diceware.DefaultOptions()
+
+	booleans := map[string]*bool{
+		"capitalize": &options.Capitalize,
+		"number":     &options.Number,
+		"symbol":     &options.Symbol,
+	}
+	for name, value := range booleans {
+		if query.Get(name) == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(query.Get(name))
+		if err != nil {
+			return diceware.Options{}, fmt.Errorf("%s must be true or false", name)
+		}
+		*value = parsed
+	}
+
+	if query.Get("words") != "" {
+		words, err := strconv.Atoi(query.Get("words"))
+		if err != nil {
+			return diceware.Options{}, fmt.Errorf("words must be a number")
+		}
+		options.Words = words
+	}
+	//An empty separator is kept, words are joined as they are then
+	if _, ok := query["separator"]; ok {
+		options.Separator = query.Get("separator")
+	}
+
+	return options, nil
+}
 
-	var i uint = 0
-	for ; i < length; i++ {
-		index := rand.Intn(len(passwordDict))
-		password += string(passwordDict[index])
+// generatorOptions Generator options of query parameters, which are named like flags of generate command
+func generatorOptions(query url.Values) (generator.Options, error) {
+	options := generator.DefaultOptions()
+
+	booleans := map[string]*bool{
+		"lower":               &options.Lower,
+		"upper":               &options.Upper,
+		"digits":              &options.Digits,
+		"symbols":             &options.Symbols,
+		"exclude-look-alikes": &options.ExcludeLookAlikes,
+	}
+	for name, value := range booleans {
+		if query.Get(name) == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(query.Get(name))
+		if err != nil {
+			return generator.Options{}, fmt.Errorf("%s must be true or false", name)
+		}
+		*value = parsed
 	}
 
-	return password
+	numbers := map[string]*int{
+		"length":      &options.Length,
+		"min-lower":   &options.MinLower,
+		"min-upper":   &options.MinUpper,
+		"min-digits":  &options.MinDigits,
+		"min-symbols": &options.MinSymbols,
+	}
+	for name, value := range numbers {
+		if query.Get(name) == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(query.Get(name))
+		if err != nil {
+			return generator.Options{}, fmt.Errorf("%s must be a number", name)
+		}
+		*value = parsed
+	}
+
+	//Minimums of excluded classes are dropped, unless they're asked for
+	minimums := map[string]*int{"lower": &options.MinLower, "upper": &options.MinUpper, "digits": &options.MinDigits, "symbols": &options.MinSymbols}
+	for name, included := range booleans {
+		if minimum, ok := minimums[name]; ok && !*included && query.Get("min-"+name) == "" {
+			*minimum = 0
+		}
+	}
+	options.SymbolSet = query.Get("symbol-set")
+
+	return options, nil
 }
 
 type SinglePasswordWithMessage struct {
 	Password string `json:"password"`
 	Message  string `json:"message"`
 }
+
+type PassphraseWithMessage struct {
+	Passphrase string  `json:"passphrase"`
+	Entropy    float64 `json:"entropy"`
+	Message    string  `json:"message"`
+}
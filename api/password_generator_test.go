@@ -1,10 +1,75 @@
 package api
 
 import (
-	"fmt"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 )
 
 func TestGeneratePassword(t *testing.T) {
-	fmt.Println(generatePassword(16))
+	recorder := httptest.NewRecorder()
+	GeneratePassword(recorder, httptest.NewRequest("GET", "/generate-password?length=24&symbols=false&min-digits=5", nil))
+
+	var message SinglePasswordWithMessage
+	if err := json.NewDecoder(recorder.Body).Decode(&message); err != nil || recorder.Code != 200 {
+		t.Fatal(recorder.Code, err)
+	}
+	digits := 0
+	for _, c := range message.Password {
+		if strings.ContainsRune("0123456789", c) {
+			digits++
+		}
+	}
+	if len(message.Password) != 24 || digits < 5 || strings.ContainsAny(message.Password, "$&()*+[]@#^-_!?") {
+		t.Errorf("%q doesn't follow query", message.Password)
+	}
+
+	recorder = httptest.NewRecorder()
+	GeneratePassword(recorder, httptest.NewRequest("GET", "/generate-password?length=2&min-upper=3", nil))
+	if recorder.Code != 400 {
+		t.Errorf("invalid options answered by %d", recorder.Code)
+	}
+}
+
+func TestGeneratePasswordByRules(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	GeneratePassword(recorder, httptest.NewRequest("GET", "/generate-password?length=30&rules="+url.QueryEscape("required: digit; allowed: upper; maxlength: 12"), nil))
+
+	var message SinglePasswordWithMessage
+	if err := json.NewDecoder(recorder.Body).Decode(&message); err != nil || recorder.Code != 200 {
+		t.Fatal(recorder.Code, err)
+	}
+	if len(message.Password) != 12 || strings.Trim(message.Password, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") != "" || !strings.ContainsAny(message.Password, "0123456789") {
+		t.Errorf("%q doesn't satisfy rules", message.Password)
+	}
+
+	recorder = httptest.NewRecorder()
+	GeneratePassword(recorder, httptest.NewRequest("GET", "/generate-password?rules=required:+emoji", nil))
+	if recorder.Code != 400 {
+		t.Errorf("invalid rules answered by %d", recorder.Code)
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	GeneratePassphrase(recorder, httptest.NewRequest("GET", "/generate-passphrase?words=5&separator=.&wordlist=short&number=true", nil))
+
+	var message PassphraseWithMessage
+	if err := json.NewDecoder(recorder.Body).Decode(&message); err != nil || recorder.Code != 200 {
+		t.Fatal(recorder.Code, err)
+	}
+	if words := strings.Split(message.Passphrase, "."); len(words) != 5 || !strings.ContainsAny(message.Passphrase, "0123456789") {
+		t.Errorf("%q doesn't follow query", message.Passphrase)
+	}
+	if message.Entropy < 5*10 {
+		t.Errorf("entropy %v is lower than 5 words of short wordlist", message.Entropy)
+	}
+
+	recorder = httptest.NewRecorder()
+	GeneratePassphrase(recorder, httptest.NewRequest("GET", "/generate-passphrase?wordlist=medium", nil))
+	if recorder.Code != 400 {
+		t.Errorf("unknown wordlist answered by %d", recorder.Code)
+	}
 }
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"io"
+	"io/ioutil"
+	"junjie.pro/informer/pkg/generator"
+	"junjie.pro/informer/pkg/library"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// ListPasswordRules Return password rules by platform, of the set of secures key in query parameters unlocks
+func ListPasswordRules(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	informerLibrary, ok := rulesLibrary(w, r)
+	if !ok {
+		return
+	}
+	defer informerLibrary.Wipe()
+
+	platformRules := informerLibrary.PlatformRules
+	if platformRules == nil {
+		platformRules = map[string]string{}
+	}
+	err := json.NewEncoder(w).Encode(platformRules)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// SetPasswordRules Set password rules of a platform, empty rules remove them.
+// Like ListPasswordRules, rules of the set key unlocks are set, those of the visible set without key.
+func SetPasswordRules(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	//Read request body and close it
+	body, err := ioutil.ReadAll(io.Reader(r.Body))
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+	err = r.Body.Close()
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	var bundle PasswordRulesBundle
+	err = json.Unmarshal(body, &bundle)
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+
+	informerLibrary, ok := rulesLibrary(w, r)
+	if !ok {
+		return
+	}
+	defer informerLibrary.Wipe()
+
+	err = informerLibrary.SetPlatformRules(mux.Vars(r)["platform"], bundle.Rules)
+	if err != nil {
+		writeMessage(w, 400, Message{Message: err.Error()})
+		return
+	}
+	if key := r.URL.Query().Get("key"); key != "" {
+		err = informerLibrary.Lock([]byte(key))
+		if err != nil {
+			log.Println(err.Error())
+			writeMessage(w, 500, DataNotCorrectMessage)
+
+			return
+		}
+	}
+
+	//Write informer library
+	err = informerLibrary.WriteLibrary()
+	if err != nil {
+		writeLibraryFailed(w, err)
+
+		return
+	}
+
+	writeMessage(w, 200, SuccessMessage)
+}
+
+// RegeneratePassword Replace password of a secure by a generated one satisfying its rules or rules of its platform,
+// and return the password. Length in query parameters is kept within rules.
+func RegeneratePassword(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	informerLibrary, ok := unlockedLibrary(w, r)
+	if !ok {
+		return
+	}
+	defer informerLibrary.Wipe()
+
+	primaryKey := mux.Vars(r)["uuid"]
+	if _, ok := informerLibrary.SecureStore[primaryKey]; !ok {
+		writeMessage(w, 404, NotFoundMessage)
+		return
+	}
+
+	length := 0
+	if r.URL.Query().Get("length") != "" {
+		var err error
+		length, err = strconv.Atoi(r.URL.Query().Get("length"))
+		if err != nil {
+			writeMessage(w, 400, Message{Message: "length must be a number"})
+			return
+		}
+	}
+	password, err := informerLibrary.RegeneratePassword(primaryKey, length)
+	if err != nil {
+		writeMessage(w, 400, Message{Message: err.Error()})
+		return
+	}
+
+	//Lock informer library by the key it's unlocked
+	err = informerLibrary.Lock([]byte(r.URL.Query().Get("key")))
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+
+	//Write informer library
+	err = informerLibrary.WriteLibrary()
+	if err != nil {
+		writeLibraryFailed(w, err)
+
+		return
+	}
+
+	w.WriteHeader(200)
+	err = json.NewEncoder(w).Encode(SinglePasswordWithMessage{Password: password, Message: SuccessMessage.Message})
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// checkPasswordRules Check password rules of secures. If some are invalid, 400 is written with the reason
+// and false is returned.
+func checkPasswordRules(w http.ResponseWriter, secures []library.SecureStore) bool {
+	for _, secure := range secures {
+		if secure.PasswordRules == "" {
+			continue
+		}
+		if _, err := generator.ParseRules(secure.PasswordRules); err != nil {
+			writeMessage(w, 400, Message{Message: "password rules of " + secure.ID + ": " + err.Error()})
+			return false
+		}
+	}
+
+	return true
+}
+
+// rulesLibrary Library whose platform rules are listed or set for a logged in user, unlocked by key of query
+// parameters if given, as rules of the sealed set are only seen by its key. Otherwise the response is written
+// and false is returned.
+func rulesLibrary(w http.ResponseWriter, r *http.Request) (library.InformerLibrary, bool) {
+	if r.URL.Query().Get("key") != "" {
+		return unlockedLibrary(w, r)
+	}
+
+	if _, ok := loggedInConfig(w, r); !ok {
+		return library.InformerLibrary{}, false
+	}
+	informerLibrary, err := library.ReadLibrary()
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return library.InformerLibrary{}, false
+	}
+
+	return informerLibrary, true
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"junjie.pro/informer/pkg/library"
+	"junjie.pro/informer/pkg/strength"
+	"log"
+	"net/http"
+)
+
+// PasswordStrength Estimate strength of password in request body, like zxcvbn does.
+// User inputs, such as username and site, are guessed first as they're known to attackers.
+func PasswordStrength(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	//Read request body and close it
+	body, err := ioutil.ReadAll(io.Reader(r.Body))
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println(err.Error())
+
+		return
+	}
+	err = r.Body.Close()
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	//Parse password, and wipe it from request body
+	var bundle PasswordStrengthBundle
+	err = json.Unmarshal(body, &bundle)
+	library.Secret(body).Wipe()
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 400, DataNotCorrectMessage)
+
+		return
+	}
+
+	w.WriteHeader(200)
+	err = json.NewEncoder(w).Encode(strength.Estimate(bundle.Password, bundle.UserInputs...))
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// passwordWarnings Warnings about weak passwords of secures by id. Weak passwords are still saved,
+// as they may be all a platform allows.
+func passwordWarnings(secures []library.SecureStore) map[string]string {
+	warnings := map[string]string{}
+	for _, secure := range secures {
+		if len(secure.Password) == 0 {
+			continue
+		}
+
+		result := strength.Estimate(secure.Password.Reveal(), secure.ID, secure.Platform, secure.FriendlyName, secure.Username, secure.URL)
+		if result.Weak() {
+			warnings[secure.ID] = "weak password, " + result.Summary()
+		}
+	}
+
+	return warnings
+}
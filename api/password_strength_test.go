@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/json"
+	"junjie.pro/informer/pkg/library"
+	"junjie.pro/informer/pkg/strength"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPasswordStrength(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	PasswordStrength(recorder, httptest.NewRequest("POST", "/password/strength", strings.NewReader(`{"password":"junjie1990","userInputs":["junjie"]}`)))
+
+	var result strength.Result
+	if err := json.NewDecoder(recorder.Body).Decode(&result); err != nil || recorder.Code != 200 {
+		t.Fatal(recorder.Code, err)
+	}
+	if !result.Weak() || len(result.CrackTimes) != 4 || result.Sequence[0].Dictionary != "user_inputs" {
+		t.Errorf("estimate of password of username: %+v", result)
+	}
+
+	recorder = httptest.NewRecorder()
+	PasswordStrength(recorder, httptest.NewRequest("POST", "/password/strength", strings.NewReader(`{"password":`)))
+	if recorder.Code != 400 {
+		t.Errorf("invalid body answered by %d", recorder.Code)
+	}
+}
+
+func TestPasswordWarnings(t *testing.T) {
+	warnings := passwordWarnings([]library.SecureStore{
+		{ID: "weak", Password: library.NewSecret("password1")},
+		{ID: "strong", Password: library.NewSecret("correct horse battery staple")},
+		{ID: "empty"},
+	})
+	if len(warnings) != 1 || warnings["weak"] == "" {
+		t.Errorf("warnings of weak passwords: %v", warnings)
+	}
+}
@@ -5,10 +5,26 @@ import (
 	"junjie.pro/informer/conf"
 	"log"
 	"net/http"
+	"time"
 )
 
 func Serve() {
 	log.Println("Starting server")
+
+	//Server keeps decrypted secures in memory while handling requests
+	err := disableCoreDumps()
+	if err != nil {
+		log.Println("Unable to disable core dumps:", err.Error())
+	}
+	//Libraries unlocked for sessions are wiped once idle
+	go func() {
+		for now := range time.Tick(time.Minute) {
+			sessionLibrariesMutex.Lock()
+			sweepSessionLibraries(now)
+			sessionLibrariesMutex.Unlock()
+		}
+	}()
+
 	router := mux.NewRouter().StrictSlash(true)
 
 	for _, route := range routes {
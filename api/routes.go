@@ -24,6 +24,48 @@ var routes = Routes{
 		Pattern:     "/logout",
 		HandlerFunc: Logout,
 	},
+	Route{
+		Name:        "Enroll two-factor authentication",
+		Method:      "POST",
+		Pattern:     "/two-factor/enroll",
+		HandlerFunc: EnrollTwoFactor,
+	},
+	Route{
+		Name:        "Enable two-factor authentication",
+		Method:      "POST",
+		Pattern:     "/two-factor/enable",
+		HandlerFunc: EnableTwoFactor,
+	},
+	Route{
+		Name:        "Disable two-factor authentication",
+		Method:      "POST",
+		Pattern:     "/two-factor/disable",
+		HandlerFunc: DisableTwoFactor,
+	},
+	Route{
+		Name:        "WebAuthn registration options",
+		Method:      "GET",
+		Pattern:     "/webauthn/register",
+		HandlerFunc: WebAuthnRegisterOptions,
+	},
+	Route{
+		Name:        "WebAuthn register",
+		Method:      "POST",
+		Pattern:     "/webauthn/register",
+		HandlerFunc: WebAuthnRegister,
+	},
+	Route{
+		Name:        "WebAuthn login options",
+		Method:      "GET",
+		Pattern:     "/webauthn/login",
+		HandlerFunc: WebAuthnLoginOptions,
+	},
+	Route{
+		Name:        "WebAuthn login",
+		Method:      "POST",
+		Pattern:     "/webauthn/login",
+		HandlerFunc: WebAuthnLogin,
+	},
 	Route{
 		Name:        "Change password",
 		Method:      "PUT",
@@ -60,16 +102,76 @@ var routes = Routes{
 		Pattern:     "/library/{uuid}/otp",
 		HandlerFunc: GeneratePassCode,
 	},
+	Route{
+		Name:        "OTP QR code",
+		Method:      "GET",
+		Pattern:     "/library/{uuid}/otp/qr",
+		HandlerFunc: PassCodeQR,
+	},
+	Route{
+		Name:        "Generate OTPs",
+		Method:      "GET",
+		Pattern:     "/otp",
+		HandlerFunc: GeneratePassCodes,
+	},
 	Route{
 		Name:        "Change master password",
 		Method:      "PUT",
 		Pattern:     "/change-master-password",
 		HandlerFunc: ChangeMasterPassword,
 	},
+	Route{
+		Name:        "Pull changes",
+		Method:      "GET",
+		Pattern:     "/sync",
+		HandlerFunc: Pull,
+	},
+	Route{
+		Name:        "Push changes",
+		Method:      "POST",
+		Pattern:     "/sync",
+		HandlerFunc: Push,
+	},
 	Route{
 		Name:        "Generate password",
 		Method:      "GET",
 		Pattern:     "/generate-password",
 		HandlerFunc: GeneratePassword,
 	},
+	Route{
+		Name:        "Regenerate password",
+		Method:      "POST",
+		Pattern:     "/library/{uuid}/password",
+		HandlerFunc: RegeneratePassword,
+	},
+	Route{
+		Name:        "List password rules",
+		Method:      "GET",
+		Pattern:     "/password-rules",
+		HandlerFunc: ListPasswordRules,
+	},
+	Route{
+		Name:        "Set password rules",
+		Method:      "PUT",
+		Pattern:     "/password-rules/{platform}",
+		HandlerFunc: SetPasswordRules,
+	},
+	Route{
+		Name:        "Generate passphrase",
+		Method:      "GET",
+		Pattern:     "/generate-passphrase",
+		HandlerFunc: GeneratePassphrase,
+	},
+	Route{
+		Name:        "Password strength",
+		Method:      "POST",
+		Pattern:     "/password/strength",
+		HandlerFunc: PasswordStrength,
+	},
+	Route{
+		Name:        "Backup",
+		Method:      "POST",
+		Pattern:     "/backup",
+		HandlerFunc: Backup,
+	},
 }
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"crypto/sha256"
+	"junjie.pro/informer/pkg/library"
+	"os"
+	"sync"
+	"time"
+)
+
+// sessionLibraryIdle Time a library unlocked for a session is kept without being used
+const sessionLibraryIdle = 5 * time.Minute
+
+// cachedLibrary Library unlocked for a login session, with the library file it was read from
+type cachedLibrary struct {
+	informerLibrary library.InformerLibrary
+	keyDigest       [sha256.Size]byte
+	modified        time.Time
+	size            int64
+	used            time.Time
+}
+
+var (
+	sessionLibraries      = map[string]*cachedLibrary{}
+	sessionLibrariesMutex sync.Mutex
+)
+
+// sessionLibrary Library unlocked by key for login session of token. It's only read and decrypted again
+// when key or library file changes, and a copy is returned, so wiping it leaves the cached library as it is.
+func sessionLibrary(token string, key string) (library.InformerLibrary, error) {
+	location, err := library.DataPath()
+	if err != nil {
+		return library.InformerLibrary{}, err
+	}
+
+	sessionLibrariesMutex.Lock()
+	defer sessionLibrariesMutex.Unlock()
+	now := time.Now()
+	sweepSessionLibraries(now)
+
+	//A library file not written yet is the default library, nothing is worth caching
+	info, err := os.Stat(location)
+	if err != nil {
+		informerLibrary, err := library.ReadLibrary()
+		if err != nil {
+			return library.InformerLibrary{}, err
+		}
+
+		return informerLibrary, informerLibrary.Unlock([]byte(key))
+	}
+
+	keyDigest := sha256.Sum256([]byte(key))
+	cached, ok := sessionLibraries[token]
+	if !ok || cached.keyDigest != keyDigest || !cached.modified.Equal(info.ModTime()) || cached.size != info.Size() {
+		forgetSessionLibraryLocked(token)
+
+		informerLibrary, err := library.ReadLibrary()
+		if err != nil {
+			return library.InformerLibrary{}, err
+		}
+		err = informerLibrary.Unlock([]byte(key))
+		if err != nil {
+			informerLibrary.Wipe()
+			return library.InformerLibrary{}, err
+		}
+
+		cached = &cachedLibrary{informerLibrary: informerLibrary, keyDigest: keyDigest, modified: info.ModTime(), size: info.Size()}
+		sessionLibraries[token] = cached
+	}
+	cached.used = now
+
+	return cached.informerLibrary.Copy(), nil
+}
+
+// forgetSessionLibrary Wipe library unlocked for session of token, such as when logging out
+func forgetSessionLibrary(token string) {
+	sessionLibrariesMutex.Lock()
+	defer sessionLibrariesMutex.Unlock()
+
+	forgetSessionLibraryLocked(token)
+}
+
+func forgetSessionLibraryLocked(token string) {
+	if cached, ok := sessionLibraries[token]; ok {
+		cached.informerLibrary.Wipe()
+		delete(sessionLibraries, token)
+	}
+}
+
+// sweepSessionLibraries Wipe libraries of sessions idle for too long, such as expired ones.
+// sessionLibrariesMutex must be held.
+func sweepSessionLibraries(now time.Time) {
+	for token, cached := range sessionLibraries {
+		if now.Sub(cached.used) > sessionLibraryIdle {
+			forgetSessionLibraryLocked(token)
+		}
+	}
+}
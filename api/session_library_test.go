@@ -0,0 +1,82 @@
+package api
+
+import (
+	"io/ioutil"
+	"junjie.pro/informer/pkg/library"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionLibrary(t *testing.T) {
+	dataHome, err := ioutil.TempDir("", "informer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataHome)
+	defer os.Setenv("XDG_DATA_HOME", os.Getenv("XDG_DATA_HOME"))
+	os.Setenv("XDG_DATA_HOME", dataHome)
+	err = os.MkdirAll(filepath.Join(dataHome, "informer"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "0123456789abcdef"
+	informerLibrary := library.InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*library.SecureStore{}}
+	informerLibrary.Add(library.SecureStore{ID: "mail", Password: library.NewSecret("password")})
+	err = informerLibrary.Lock([]byte(key))
+	if err == nil {
+		err = informerLibrary.WriteLibrary()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Wiping a session library leaves the cached one as it is
+	first, err := sessionLibrary("token", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Wipe()
+	second, err := sessionLibrary("token", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secure := range second.SecureStore {
+		if secure.Password.Reveal() != "password" {
+			t.Error("cached library was wiped")
+		}
+	}
+	if _, err = sessionLibrary("token", "fedcba9876543210"); err == nil {
+		t.Error("library unlocked by a wrong key")
+	}
+
+	//Changes of library file are read again
+	err = second.Lock([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Add(library.SecureStore{ID: "bank"})
+	time.Sleep(10 * time.Millisecond)
+	err = second.WriteLibrary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := sessionLibrary("token", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(third.SecureStore) != 2 {
+		t.Errorf("library of %d secures is cached, file has 2", len(third.SecureStore))
+	}
+
+	//Libraries of idle sessions are wiped
+	sessionLibrariesMutex.Lock()
+	sweepSessionLibraries(time.Now().Add(2 * sessionLibraryIdle))
+	cached := len(sessionLibraries)
+	sessionLibrariesMutex.Unlock()
+	if cached != 0 {
+		t.Errorf("%d idle libraries are kept", cached)
+	}
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"junjie.pro/informer/conf"
+	"junjie.pro/informer/pkg/library"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Pull Return secures changed after the revision given by query parameter since, secures stay encrypted.
+// Key given by query parameter key must open the visible set, the only set which is synced
+func Pull(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	//Read informer configurations
+	informerConfig, err := conf.ReadConfig()
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println(err)
+
+		return
+	}
+
+	//Read login token from cookie
+	username, err := r.Cookie("username")
+	if err != nil {
+		log.Println(err)
+	}
+	tokenId, err := r.Cookie("token")
+	if err != nil {
+		log.Println(err)
+	}
+
+	//Check user is already logged in whether
+	if username == nil || tokenId == nil || !informerConfig.CheckLogin(username.Value, tokenId.Value) {
+		w.WriteHeader(403)
+		err = json.NewEncoder(w).Encode(NotLoggedInMessage)
+		if err != nil {
+			log.Println(err)
+		}
+
+		return
+	}
+
+	//Revision which the client has pulled last time, 0 pulls all of secures
+	var since uint64
+	queryParams := r.URL.Query()
+	if queryParams["since"] != nil && queryParams["since"][0] != "" {
+		since, err = strconv.ParseUint(queryParams["since"][0], 10, 64)
+		if err != nil {
+			w.WriteHeader(400)
+			err = json.NewEncoder(w).Encode(DataNotCorrectMessage)
+			if err != nil {
+				log.Println(err.Error())
+			}
+
+			return
+		}
+	}
+
+	//Read informer library
+	informerLibrary, err := library.ReadLibrary()
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println(err.Error())
+
+		return
+	}
+
+	//Secures of another key are never given to the offline copy
+	err = informerLibrary.CheckSyncKey([]byte(queryParams.Get("key")))
+	if err != nil {
+		writeMessage(w, 403, WrongKeyMessage)
+
+		return
+	}
+
+	bundle := SyncBundle{Revision: informerLibrary.Revision, Changes: informerLibrary.ChangesSince(since)}
+	w.WriteHeader(200)
+	err = json.NewEncoder(w).Encode(bundle)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// Push Apply changes of an offline copy. Changes based on outdated revisions never overwrite secures,
+// they are returned as conflicts instead. Key given by query parameter key must open the visible set and pushed secures
+func Push(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	//Read request body and close it
+	body, err := ioutil.ReadAll(io.Reader(r.Body))
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println(err)
+	}
+	err = r.Body.Close()
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println(err)
+	}
+
+	//Read informer configurations
+	informerConfig, err := conf.ReadConfig()
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println(err)
+
+		return
+	}
+
+	//Read login token from cookie
+	username, err := r.Cookie("username")
+	if err != nil {
+		log.Println(err)
+	}
+	tokenId, err := r.Cookie("token")
+	if err != nil {
+		log.Println(err)
+	}
+
+	//Check user is already logged in whether
+	if username == nil || tokenId == nil || !informerConfig.CheckLogin(username.Value, tokenId.Value) {
+		w.WriteHeader(403)
+		err = json.NewEncoder(w).Encode(NotLoggedInMessage)
+		if err != nil {
+			log.Println(err)
+		}
+
+		return
+	}
+
+	//Parse changes from request body
+	var bundle SyncBundle
+	err = json.Unmarshal(body, &bundle)
+	if err != nil {
+		log.Println(err.Error())
+		w.WriteHeader(400)
+		err = json.NewEncoder(w).Encode(DataNotCorrectMessage)
+		if err != nil {
+			log.Println(err.Error())
+		}
+
+		return
+	}
+
+	//Library file stays locked while changes are applied, applied changes are returned with their revisions
+	revision, applied, conflicts, err := library.ApplyPushed([]byte(r.URL.Query().Get("key")), bundle.Changes)
+	if errors.Is(err, library.ErrWrongKey) {
+		writeMessage(w, 403, WrongKeyMessage)
+
+		return
+	}
+	if err != nil {
+		writeLibraryFailed(w, err)
+
+		return
+	}
+
+	w.WriteHeader(200)
+	err = json.NewEncoder(w).Encode(SyncBundle{Revision: revision, Changes: applied, Conflicts: conflicts})
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
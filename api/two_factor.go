@@ -0,0 +1,296 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"junjie.pro/informer/conf"
+	"junjie.pro/informer/pkg/otp"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	//Wrong codes a user may try before being throttled, then every wrong code doubles the wait up to the longest
+	twoFactorAttempts    = 5
+	twoFactorWait        = 30 * time.Second
+	twoFactorLongestWait = time.Hour
+)
+
+// errTwoFactorThrottled Too many wrong codes were tried lately, codes aren't verified until the wait is over
+var errTwoFactorThrottled = errors.New("too many two-factor attempts")
+
+// twoFactorThrottle Wrong codes tried by a user since the last correct one, and when the next may be tried
+type twoFactorThrottle struct {
+	failures int
+	until    time.Time
+}
+
+var (
+	// twoFactorMutex Serializes verifying two-factor codes, so what a code used up is written before the next is verified
+	twoFactorMutex sync.Mutex
+	//twoFactorThrottles is keyed by username, guarded by twoFactorMutex
+	twoFactorThrottles = map[string]*twoFactorThrottle{}
+)
+
+// EnrollTwoFactor Enroll a new TOTP secret for the user, it's enabled by EnableTwoFactor once a code of it is confirmed
+func EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	informerConfig, bundle, ok := confirmPassword(w, r)
+	if !ok {
+		return
+	}
+	if informerConfig.User.TwoFactor.Enabled {
+		writeMessage(w, 409, Message{Message: "two-factor authentication is enabled, disable it first"})
+		return
+	}
+
+	twoFactor, secret, err := conf.NewTwoFactor(bundle.Password)
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+	informerConfig.User.TwoFactor = twoFactor
+
+	err = informerConfig.WriteConfig()
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+
+	account := otp.Account{Issuer: "Informer", Name: informerConfig.User.Username, Secret: secret, Type: "totp"}
+	err = json.NewEncoder(w).Encode(TwoFactorEnrollment{Secret: secret, URI: account.URI()})
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// EnableTwoFactor Enable enrolled two-factor authentication by a code of it, and return new recovery codes
+func EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	informerConfig, bundle, ok := confirmPassword(w, r)
+	if !ok {
+		return
+	}
+	if informerConfig.User.TwoFactor.Enabled || informerConfig.User.TwoFactor.Secret == "" {
+		writeMessage(w, 409, Message{Message: "two-factor authentication isn't enrolled or is enabled already"})
+		return
+	}
+	if !checkTwoFactor(w, bundle) {
+		return
+	}
+
+	//Verification has written what the code used up
+	informerConfig, err := conf.ReadConfig()
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+	recoveryCodes, err := informerConfig.User.TwoFactor.NewRecoveryCodes()
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+	informerConfig.User.TwoFactor.Enabled = true
+
+	err = informerConfig.WriteConfig()
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(RecoveryCodesWithMessage{Message: SuccessMessage.Message, RecoveryCodes: recoveryCodes})
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// DisableTwoFactor Disable two-factor authentication by a code of it or a recovery code, its secret is removed
+func DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	informerConfig, bundle, ok := confirmPassword(w, r)
+	if !ok {
+		return
+	}
+	if !informerConfig.User.TwoFactor.Enabled {
+		writeMessage(w, 409, Message{Message: "two-factor authentication isn't enabled"})
+		return
+	}
+	if !checkTwoFactor(w, bundle) {
+		return
+	}
+
+	informerConfig, err := conf.ReadConfig()
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+	informerConfig.User.TwoFactor = conf.TwoFactor{}
+
+	err = informerConfig.WriteConfig()
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+
+	writeMessage(w, 200, SuccessMessage)
+}
+
+// confirmPassword Configurations and request body of a logged in user, whose password in body is correct.
+// Otherwise the response is written and false is returned.
+func confirmPassword(w http.ResponseWriter, r *http.Request) (conf.InformerConfig, TwoFactorBundle, bool) {
+	//Read request body and close it
+	body, err := ioutil.ReadAll(io.Reader(r.Body))
+	if err != nil {
+		log.Println(err)
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return conf.InformerConfig{}, TwoFactorBundle{}, false
+	}
+	err = r.Body.Close()
+	if err != nil {
+		log.Println(err)
+	}
+
+	//Read informer configurations
+	informerConfig, err := conf.ReadConfig()
+	if err != nil {
+		log.Println(err)
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return conf.InformerConfig{}, TwoFactorBundle{}, false
+	}
+
+	//Read login token from cookie
+	username, err := r.Cookie("username")
+	if err != nil {
+		log.Println(err)
+	}
+	tokenId, err := r.Cookie("token")
+	if err != nil {
+		log.Println(err)
+	}
+
+	//Check user is already logged in whether
+	if username == nil || tokenId == nil || !informerConfig.CheckLogin(username.Value, tokenId.Value) {
+		writeMessage(w, 403, NotLoggedInMessage)
+
+		return conf.InformerConfig{}, TwoFactorBundle{}, false
+	}
+
+	var bundle TwoFactorBundle
+	err = json.Unmarshal(body, &bundle)
+	if err != nil {
+		log.Println(err)
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return conf.InformerConfig{}, TwoFactorBundle{}, false
+	}
+	if !informerConfig.CheckUser(conf.User{Username: username.Value, Password: bundle.Password}) {
+		writeMessage(w, 401, Message{Message: "password not correctly"})
+
+		return conf.InformerConfig{}, TwoFactorBundle{}, false
+	}
+
+	return informerConfig, bundle, true
+}
+
+// checkTwoFactor Verify code of bundle, otherwise the response is written and false is returned
+func checkTwoFactor(w http.ResponseWriter, bundle TwoFactorBundle) bool {
+	verified, err := verifyTwoFactor(bundle.Password, bundle.Code)
+	if errors.Is(err, errTwoFactorThrottled) {
+		writeMessage(w, 429, TwoFactorThrottledMessage)
+
+		return false
+	}
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return false
+	}
+	if !verified {
+		writeMessage(w, 401, TwoFactorNotCorrectMessage)
+
+		return false
+	}
+
+	return true
+}
+
+// verifyTwoFactor Verify two-factor code by the secret password decrypts, and write what the code used up,
+// so neither a TOTP code nor a recovery code can be used again. Users trying too many wrong codes are throttled
+// and get errTwoFactorThrottled.
+func verifyTwoFactor(password string, code string) (bool, error) {
+	twoFactorMutex.Lock()
+	defer twoFactorMutex.Unlock()
+
+	informerConfig, err := conf.ReadConfig()
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	username := informerConfig.User.Username
+	throttle, ok := twoFactorThrottles[username]
+	if ok && now.Before(throttle.until) {
+		return false, errTwoFactorThrottled
+	}
+
+	secret, err := informerConfig.User.TwoFactor.RevealSecret(password)
+	if err != nil {
+		return false, err
+	}
+	if !informerConfig.User.TwoFactor.Verify(secret, code, now) {
+		if !ok {
+			throttle = &twoFactorThrottle{}
+			twoFactorThrottles[username] = throttle
+		}
+		throttle.failures++
+		throttle.until = now.Add(twoFactorThrottleWait(throttle.failures))
+
+		return false, nil
+	}
+	delete(twoFactorThrottles, username)
+
+	return true, informerConfig.WriteConfig()
+}
+
+// twoFactorThrottleWait Wait after failures wrong codes in a row
+func twoFactorThrottleWait(failures int) time.Duration {
+	if failures < twoFactorAttempts {
+		return 0
+	}
+
+	wait := twoFactorWait
+	for i := twoFactorAttempts; i < failures && wait < twoFactorLongestWait; i++ {
+		wait *= 2
+	}
+	if wait > twoFactorLongestWait {
+		wait = twoFactorLongestWait
+	}
+
+	return wait
+}
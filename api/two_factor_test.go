@@ -0,0 +1,64 @@
+package api
+
+import (
+	"io/ioutil"
+	"junjie.pro/informer/conf"
+	"junjie.pro/informer/pkg/otp"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestVerifyTwoFactorThrottle(t *testing.T) {
+	configHome, err := ioutil.TempDir("", "informer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(configHome)
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", configHome)
+
+	twoFactor, secret, err := conf.NewTwoFactor("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	twoFactor.Enabled = true
+	informerConfig := conf.InformerConfig{Version: "0.1", User: conf.User{Username: "bob", TwoFactor: twoFactor}}
+	err = informerConfig.WriteConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(twoFactorThrottles, "bob")
+
+	//Wrong codes are only refused until the user has tried too many
+	for i := 0; i < twoFactorAttempts; i++ {
+		if verified, err := verifyTwoFactor("password", "000000"); verified || err != nil {
+			t.Fatalf("wrong code %d: verified %t, %v", i+1, verified, err)
+		}
+	}
+
+	//Even a correct code waits once the user is throttled
+	code, err := otp.GenerateCode(otp.Account{Secret: secret}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified, err := verifyTwoFactor("password", code); verified || err != errTwoFactorThrottled {
+		t.Errorf("throttled code: verified %t, %v", verified, err)
+	}
+
+	//Once the wait is over a correct code is taken and the throttle is reset
+	twoFactorThrottles["bob"].until = time.Now()
+	if verified, err := verifyTwoFactor("password", code); !verified || err != nil {
+		t.Errorf("correct code: verified %t, %v", verified, err)
+	}
+	if _, ok := twoFactorThrottles["bob"]; ok {
+		t.Error("throttle is kept after a correct code")
+	}
+
+	waits := map[int]time.Duration{1: 0, twoFactorAttempts: twoFactorWait, twoFactorAttempts + 2: 4 * twoFactorWait, 100: twoFactorLongestWait}
+	for failures, expected := range waits {
+		if wait := twoFactorThrottleWait(failures); wait != expected {
+			t.Errorf("%d failures: wait %v, expected %v", failures, wait, expected)
+		}
+	}
+}
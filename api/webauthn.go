@@ -0,0 +1,262 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"junjie.pro/informer/conf"
+	"junjie.pro/informer/pkg/webauthn"
+	"log"
+	"net/http"
+	"sync"
+)
+
+var (
+	registrationChallenges webauthn.Challenges
+	loginChallenges        webauthn.Challenges
+	//webauthnMutex Serializes verifying and writing credentials, so sign counts aren't lost
+	webauthnMutex sync.Mutex
+)
+
+// WebAuthnRegisterOptions Return options for the browser to create a credential of the logged in user
+func WebAuthnRegisterOptions(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	informerConfig, ok := loggedInConfig(w, r)
+	if !ok {
+		return
+	}
+
+	rp, ok := relyingParty(w, informerConfig)
+	if !ok {
+		return
+	}
+
+	challenge, err := registrationChallenges.New()
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+	userID := sha256.Sum256([]byte(informerConfig.User.Username))
+	options := rp.CreationOptions(challenge, userID[:], informerConfig.User.Username, informerConfig.User.Credentials)
+
+	err = json.NewEncoder(w).Encode(options)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// WebAuthnRegister Register credential created by the browser for the logged in user
+func WebAuthnRegister(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	//Read request body and close it
+	body, err := ioutil.ReadAll(io.Reader(r.Body))
+	if err != nil {
+		log.Println(err)
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+	err = r.Body.Close()
+	if err != nil {
+		log.Println(err)
+	}
+
+	webauthnMutex.Lock()
+	defer webauthnMutex.Unlock()
+
+	informerConfig, ok := loggedInConfig(w, r)
+	if !ok {
+		return
+	}
+	rp, ok := relyingParty(w, informerConfig)
+	if !ok {
+		return
+	}
+
+	var registration WebAuthnRegistration
+	err = json.Unmarshal(body, &registration)
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+
+	credential, err := rp.Register(registration.Credential, &registrationChallenges, registration.Name)
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 400, Message{Message: "credential not correctly: " + err.Error()})
+
+		return
+	}
+	for _, registered := range informerConfig.User.Credentials {
+		if registered.ID == credential.ID {
+			writeMessage(w, 409, Message{Message: "credential is registered already"})
+			return
+		}
+	}
+
+	informerConfig.User.Credentials = append(informerConfig.User.Credentials, credential)
+	err = informerConfig.WriteConfig()
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(credential)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// WebAuthnLoginOptions Return options for the browser to assert one of the registered credentials
+func WebAuthnLoginOptions(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	//Read informer configurations
+	informerConfig, err := conf.ReadConfig()
+	if err != nil {
+		log.Println(err)
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+	if len(informerConfig.User.Credentials) == 0 {
+		writeMessage(w, 404, Message{Message: "no WebAuthn credential is registered"})
+		return
+	}
+	rp, ok := relyingParty(w, informerConfig)
+	if !ok {
+		return
+	}
+
+	challenge, err := loginChallenges.New()
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+	options := rp.RequestOptions(challenge, informerConfig.User.Credentials)
+
+	err = json.NewEncoder(w).Encode(options)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// WebAuthnLogin Log in by assertion of a registered credential, in place of username and password
+func WebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	//Response message is json
+	w.Header().Add("Content-Type", "application/json")
+
+	//Read request body and close it
+	body, err := ioutil.ReadAll(io.Reader(r.Body))
+	if err != nil {
+		log.Println(err)
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+	err = r.Body.Close()
+	if err != nil {
+		log.Println(err)
+	}
+
+	var assertion webauthn.AssertionResponse
+	err = json.Unmarshal(body, &assertion)
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+
+	webauthnMutex.Lock()
+	defer webauthnMutex.Unlock()
+
+	//Read informer configurations
+	informerConfig, err := conf.ReadConfig()
+	if err != nil {
+		log.Println(err)
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return
+	}
+
+	rp, ok := relyingParty(w, informerConfig)
+	if !ok {
+		return
+	}
+
+	credential, err := rp.Login(assertion, &loginChallenges, informerConfig.User.Credentials)
+	if err != nil {
+		log.Println(err.Error())
+		writeMessage(w, 401, Message{Message: "WebAuthn assertion not correctly"})
+
+		return
+	}
+
+	//Keep the new sign count, then log in like by password
+	for i := range informerConfig.User.Credentials {
+		if informerConfig.User.Credentials[i].ID == credential.ID {
+			informerConfig.User.Credentials[i] = credential
+		}
+	}
+	issueLoginToken(w, informerConfig, informerConfig.User.Username)
+}
+
+// loggedInConfig Configurations of a logged in user, otherwise the response is written and false is returned
+func loggedInConfig(w http.ResponseWriter, r *http.Request) (conf.InformerConfig, bool) {
+	//Read informer configurations
+	informerConfig, err := conf.ReadConfig()
+	if err != nil {
+		log.Println(err)
+		writeMessage(w, 500, DataNotCorrectMessage)
+
+		return conf.InformerConfig{}, false
+	}
+
+	//Read login token from cookie
+	username, err := r.Cookie("username")
+	if err != nil {
+		log.Println(err)
+	}
+	tokenId, err := r.Cookie("token")
+	if err != nil {
+		log.Println(err)
+	}
+
+	//Check user is already logged in whether
+	if username == nil || tokenId == nil || !informerConfig.CheckLogin(username.Value, tokenId.Value) {
+		writeMessage(w, 403, NotLoggedInMessage)
+
+		return conf.InformerConfig{}, false
+	}
+
+	return informerConfig, true
+}
+
+// relyingParty Relying party of configurations. ID and origin must be configured, taking them from the Host
+// header would let clients pick them, so WebAuthn is refused without them and false is returned.
+func relyingParty(w http.ResponseWriter, informerConfig conf.InformerConfig) (webauthn.RelyingParty, bool) {
+	if informerConfig.WebAuthn.RPID == "" || informerConfig.WebAuthn.Origin == "" {
+		writeMessage(w, 503, WebAuthnNotConfiguredMessage)
+
+		return webauthn.RelyingParty{}, false
+	}
+
+	//Without password login, the credential is all that logs in, so it must verify the user
+	return webauthn.RelyingParty{ID: informerConfig.WebAuthn.RPID, Name: "Informer", Origin: informerConfig.WebAuthn.Origin,
+		UserVerification: informerConfig.WebAuthn.DisablePassword}, true
+}
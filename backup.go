@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"junjie.pro/informer/pkg/backup"
+	"os"
+	"strings"
+)
+
+func init() {
+	commands["backup"] = command{
+		usage: "create [-passphrase passphrase] [-include-user] file | restore [-key key] [-passphrase passphrase] [-yes] file",
+		run:   runBackup,
+	}
+}
+
+// runBackup Run sub command of backup
+func runBackup(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: informer backup", commands["backup"].usage)
+		return errors.New("backup command is needed")
+	}
+
+	switch args[0] {
+	case "create":
+		return runBackupCreate(args[1:])
+	case "restore":
+		return runBackupRestore(args[1:])
+	}
+
+	return fmt.Errorf("unknown backup command %q", args[0])
+}
+
+// runBackupCreate Write library, and user of configuration if asked, to an archive encrypted by a passphrase.
+// Secures in library stay encrypted by master key as well.
+func runBackupCreate(args []string) error {
+	flagSet := newFlagSet("backup")
+	passphrase := flagSet.String("passphrase", "", "Passphrase of the backup, asked if not given")
+	includeUser := flagSet.Bool("include-user", false, "Include user and login tokens of configuration")
+	files := parseFlags(flagSet, args)
+	if len(files) != 1 {
+		flagSet.Usage()
+		return errors.New("file is needed")
+	}
+
+	if *passphrase == "" {
+		fmt.Print("backup passphrase: ")
+		stdin.Scan()
+		*passphrase = stdin.Text()
+		fmt.Print("confirm passphrase: ")
+		stdin.Scan()
+		if stdin.Text() != *passphrase {
+			return errors.New("passphrases don't match")
+		}
+	}
+
+	contents, err := backup.Collect(*includeUser)
+	if err != nil {
+		return err
+	}
+	archive, err := backup.Create(contents, *passphrase)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(files[0], archive, os.FileMode(0600))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("backup written to", files[0])
+
+	return nil
+}
+
+// runBackupRestore Replace library, and user of configuration if backed up, after checking integrity of the backup.
+// If key is given, the backed up library must be unlocked by it.
+func runBackupRestore(args []string) error {
+	flagSet := newFlagSet("backup")
+	passphrase := flagSet.String("passphrase", "", "Passphrase of the backup, asked if not given")
+	yes := flagSet.Bool("yes", false, "Replace without asking")
+	files := parseFlags(flagSet, args)
+	if len(files) != 1 {
+		flagSet.Usage()
+		return errors.New("file is needed")
+	}
+
+	archive, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	if *passphrase == "" {
+		fmt.Print("backup passphrase: ")
+		stdin.Scan()
+		*passphrase = stdin.Text()
+	}
+
+	header, contents, err := backup.Open(archive, *passphrase)
+	if err != nil {
+		return err
+	}
+	informerLibrary, user, err := backup.Contents(contents)
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		//Unlock a copy, the restored library stays locked
+		check, _, err := backup.Contents(contents)
+		if err != nil {
+			return err
+		}
+		err = check.Unlock([]byte(key))
+		check.Wipe()
+		if err != nil {
+			return fmt.Errorf("key doesn't unlock library of backup: %w", err)
+		}
+	}
+
+	fmt.Println("backup created", header.Created.Local().Format("2006-01-02 15:04:05"), "with",
+		len(informerLibrary.SecureStore), "secures")
+	if user != nil {
+		fmt.Println("user", user.Username, "with", len(user.Tokens), "login tokens")
+	}
+	if !*yes {
+		fmt.Print("replace current library? (y/N) ")
+		stdin.Scan()
+		if answer := strings.ToLower(strings.TrimSpace(stdin.Text())); answer != "y" && answer != "yes" {
+			return errors.New("restore aborted, nothing replaced")
+		}
+	}
+
+	err = backup.Restore(informerLibrary, user)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("backup restored")
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"junjie.pro/informer/pkg/bitwarden"
+	"junjie.pro/informer/pkg/library"
+	"log"
+)
+
+func init() {
+	formats["bitwarden"] = format{read: readBitwarden, write: writeBitwarden}
+}
+
+// readBitwarden Secures of a Bitwarden JSON export, the password is only asked for password protected ones
+func readBitwarden(content []byte, options formatOptions) (imported, error) {
+	password := ""
+	if bitwarden.NeedsPassword(content) {
+		password = options.filePassword()
+	}
+	export, err := bitwarden.Parse(content, password)
+	if err != nil {
+		return imported{}, err
+	}
+
+	secures, unmapped := export.Secures()
+
+	return imported{secures: secures, unmapped: unmapped}, nil
+}
+
+// writeBitwarden Write secures as Bitwarden JSON export, protected by password if given
+func writeBitwarden(w io.Writer, informerLibrary library.InformerLibrary, options formatOptions) error {
+	for _, secure := range informerLibrary.SecureStore {
+		if len(secure.Attachments) > 0 {
+			log.Println("Attachments of", secure.ID, "are not exported, Bitwarden exports can't hold them")
+		}
+	}
+
+	content, err := bitwarden.NewExport(informerLibrary).Marshal(options.password)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(content)
+
+	return err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command Sub command of informer, args are what follow the command name
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{}
+
+func runCommand(name string, args []string) error {
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q, available commands: %s", name, strings.Join(commandNames(), ", "))
+	}
+
+	return cmd.run(args)
+}
+
+func commandNames() []string {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// newFlagSet Flag set of a sub command, its -key defaults to the global one
+func newFlagSet(name string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ExitOnError)
+	flagSet.StringVar(&key, "key", key, "Key for encrypt/decrypt secures")
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: informer", name, commands[name].usage)
+		flagSet.PrintDefaults()
+	}
+
+	return flagSet
+}
+
+// parseFlags Parse flags placed anywhere among the arguments, and return the positional arguments
+func parseFlags(flagSet *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		// ExitOnError flag set never returns an error
+		_ = flagSet.Parse(args)
+		args = flagSet.Args()
+		if len(args) == 0 {
+			return positional
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"golang.org/x/crypto/sha3"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"junjie.pro/informer/pkg/webauthn"
 	"log"
 	"os"
 	"path/filepath"
@@ -18,12 +19,30 @@ type InformerConfig struct {
 	RenewalCycle int    `yaml:"renewal-cycle"`
 	Port         string `yaml:"port"`
 	User         User   `yaml:"user"`
+	//CSVProfiles maps profile name to column mappings of csv import, see csvimport.Profile
+	CSVProfiles map[string]map[string]string `yaml:"csv-profiles,omitempty"`
+	//WebAuthn is the relying party of WebAuthn login
+	WebAuthn WebAuthn `yaml:"webauthn,omitempty"`
+}
+
+// WebAuthn Relying party of WebAuthn login, ID and origin must be set for WebAuthn to be offered.
+// With DisablePassword, password login is refused once a credential is registered,
+// unless two-factor authentication is enabled, as clients such as sync can only log in by password.
+type WebAuthn struct {
+	RPID            string `yaml:"rp-id,omitempty"`
+	Origin          string `yaml:"origin,omitempty"`
+	DisablePassword bool   `yaml:"disable-password,omitempty"`
 }
 
 type User struct {
 	Username string  `json:"username" yaml:"username"`
 	Password string  `json:"password" yaml:"password"`
 	Tokens   []Token `json:"tokens" yaml:"tokens"`
+	//Code is the two-factor code given by login, it's never stored
+	Code      string    `json:"code,omitempty" yaml:"-"`
+	TwoFactor TwoFactor `json:"-" yaml:"two-factor,omitempty"`
+	//Credentials are WebAuthn credentials registered by the user
+	Credentials []webauthn.Credential `json:"-" yaml:"webauthn-credentials,omitempty"`
 }
 
 type Token struct {
@@ -71,6 +90,11 @@ func (informerConfig InformerConfig) WriteConfig() error {
 	if err != nil {
 		return err
 	}
+	//If config directory doesn't exists, create it
+	err = os.MkdirAll(filepath.Dir(dataLocation), 0755)
+	if err != nil {
+		return err
+	}
 
 	data, err := yaml.Marshal(informerConfig)
 	if err != nil {
@@ -116,6 +140,17 @@ func (user *User) AddToken(token Token) {
 	user.Tokens = append(user.Tokens, token)
 }
 
+// Exists Whether configuration file exists, commands which work without it check first.
+func Exists() bool {
+	configLocation, err := configPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(configLocation)
+
+	return err == nil
+}
+
 func configPath() (string, error) {
 	configPath := os.Getenv("XDG_CONFIG_HOME")
 	if configPath == "" {
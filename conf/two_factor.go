@@ -0,0 +1,171 @@
+package conf
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/sha3"
+	"junjie.pro/informer/pkg/otp"
+)
+
+// recoveryCodeCount Recovery codes given by enabling two-factor authentication, each can be used once
+const recoveryCodeCount = 10
+
+var ErrTwoFactorPassword = errors.New("two-factor secret can't be decrypted by password")
+
+// TwoFactor TOTP of user, the secret is encrypted by a key derived from the password.
+// It's enrolled first and enabled once a code of it is confirmed.
+type TwoFactor struct {
+	Enabled bool   `yaml:"enabled"`
+	Secret  string `yaml:"secret"`
+	Salt    string `yaml:"salt"`
+	//LastStep is the time step of the last accepted code, codes of it and before it are replays
+	LastStep int64 `yaml:"last-step,omitempty"`
+	//RecoveryCodes are digests of unused recovery codes, hashed like the password
+	RecoveryCodes []string `yaml:"recovery-codes,omitempty"`
+}
+
+// NewTwoFactor Enroll a new random TOTP secret encrypted by password, and return it in base32 too
+func NewTwoFactor(password string) (TwoFactor, string, error) {
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return TwoFactor{}, "", err
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+
+	twoFactor := TwoFactor{}
+	err := twoFactor.encrypt(password, secret)
+	if err != nil {
+		return TwoFactor{}, "", err
+	}
+
+	return twoFactor, secret, nil
+}
+
+// RevealSecret Decrypt TOTP secret by password
+func (twoFactor TwoFactor) RevealSecret(password string) (string, error) {
+	salt, err := base64.StdEncoding.DecodeString(twoFactor.Salt)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(twoFactor.Secret)
+	if err != nil {
+		return "", err
+	}
+
+	aesGCM, err := newTwoFactorCipher(password, salt)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aesGCM.NonceSize() {
+		return "", ErrTwoFactorPassword
+	}
+	secret, err := aesGCM.Open(nil, sealed[:aesGCM.NonceSize()], sealed[aesGCM.NonceSize():], nil)
+	if err != nil {
+		return "", ErrTwoFactorPassword
+	}
+
+	return string(secret), nil
+}
+
+// Rekey Encrypt TOTP secret by newPassword in place of oldPassword, when the password is changed
+func (twoFactor *TwoFactor) Rekey(oldPassword string, newPassword string) error {
+	if twoFactor.Secret == "" {
+		return nil
+	}
+
+	secret, err := twoFactor.RevealSecret(oldPassword)
+	if err != nil {
+		return err
+	}
+
+	return twoFactor.encrypt(newPassword, secret)
+}
+
+// Verify Whether code is a TOTP code of secret or an unused recovery code at time t.
+// The step of an accepted TOTP code is kept and a recovery code is used up, so neither can be replayed.
+func (twoFactor *TwoFactor) Verify(secret string, code string, t time.Time) bool {
+	code = strings.ReplaceAll(code, " ", "")
+	step, ok := otp.Verify(otp.Account{Secret: secret}, code, t, 1, twoFactor.LastStep)
+	if ok {
+		twoFactor.LastStep = step
+		return true
+	}
+
+	digest := hashRecoveryCode(code)
+	for i, recoveryCode := range twoFactor.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCode), []byte(digest)) == 1 {
+			twoFactor.RecoveryCodes = append(twoFactor.RecoveryCodes[:i], twoFactor.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewRecoveryCodes Replace recovery codes by new random ones, which are returned for the user to keep
+func (twoFactor *TwoFactor) NewRecoveryCodes() ([]string, error) {
+	var codes, digests []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(raw))
+		code = code[:4] + "-" + code[4:]
+
+		codes = append(codes, code)
+		digests = append(digests, hashRecoveryCode(code))
+	}
+	twoFactor.RecoveryCodes = digests
+
+	return codes, nil
+}
+
+// encrypt Encrypt secret by a key derived from password and a new salt
+func (twoFactor *TwoFactor) encrypt(password string, secret string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	aesGCM, err := newTwoFactorCipher(password, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aesGCM.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	twoFactor.Salt = base64.StdEncoding.EncodeToString(salt)
+	twoFactor.Secret = base64.StdEncoding.EncodeToString(aesGCM.Seal(nonce, nonce, []byte(secret), nil))
+
+	return nil
+}
+
+// newTwoFactorCipher AES-256-GCM of a key derived from password and salt by argon2id
+func newTwoFactorCipher(password string, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(password), salt, 3, 64*1024, 4, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// hashRecoveryCode Digest of recovery code like that of password, hyphens and case are ignored
+func hashRecoveryCode(code string) string {
+	digest := sha3.Sum512([]byte(strings.ToLower(strings.ReplaceAll(code, "-", ""))))
+
+	return hex.EncodeToString(digest[:])
+}
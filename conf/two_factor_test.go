@@ -0,0 +1,55 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"junjie.pro/informer/pkg/otp"
+)
+
+func TestTwoFactor(t *testing.T) {
+	twoFactor, secret, err := NewTwoFactor("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revealed, err := twoFactor.RevealSecret("password"); err != nil || revealed != secret {
+		t.Fatalf("revealed %q, %v", revealed, err)
+	}
+	if _, err := twoFactor.RevealSecret("wrong"); err != ErrTwoFactorPassword {
+		t.Errorf("wrong password: %v", err)
+	}
+
+	err = twoFactor.Rekey("password", "new password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revealed, err := twoFactor.RevealSecret("new password"); err != nil || revealed != secret {
+		t.Fatalf("revealed %q, %v after rekey", revealed, err)
+	}
+
+	now := time.Now()
+	code, err := otp.GenerateCode(otp.Account{Secret: secret}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !twoFactor.Verify(secret, code, now) {
+		t.Error("code rejected")
+	}
+	if twoFactor.Verify(secret, code, now) {
+		t.Error("code replayed")
+	}
+
+	recoveryCodes, err := twoFactor.NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recoveryCodes) != recoveryCodeCount || len(twoFactor.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("%d recovery codes, %d digests", len(recoveryCodes), len(twoFactor.RecoveryCodes))
+	}
+	if !twoFactor.Verify(secret, recoveryCodes[3], now) || twoFactor.Verify(secret, recoveryCodes[3], now) {
+		t.Error("recovery code isn't used once")
+	}
+	if len(twoFactor.RecoveryCodes) != recoveryCodeCount-1 {
+		t.Errorf("%d recovery codes left", len(twoFactor.RecoveryCodes))
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"junjie.pro/informer/conf"
+	"junjie.pro/informer/pkg/csvimport"
+	"junjie.pro/informer/pkg/library"
+	"log"
+)
+
+func init() {
+	formats["csv"] = format{read: readCSV, write: writeCSV, plaintext: true}
+}
+
+// readCSV Secures of a csv file, columns are mapped by the given profile or one detected by header.
+// Profiles in config take precedence over built-in ones of the same name.
+func readCSV(content []byte, options formatOptions) (imported, error) {
+	profiles := map[string]csvimport.Profile{}
+	for name, profile := range csvimport.Profiles {
+		profiles[name] = profile
+	}
+	if conf.Exists() {
+		informerConfig, err := conf.ReadConfig()
+		if err != nil {
+			return imported{}, err
+		}
+		for name, profile := range informerConfig.CSVProfiles {
+			profiles[name] = profile
+		}
+	}
+
+	name := options.profile
+	if name == "" {
+		header, err := csvimport.Header(bytes.NewReader(content))
+		if err != nil {
+			return imported{}, err
+		}
+
+		var ok bool
+		name, ok = csvimport.Detect(header, profiles)
+		if !ok {
+			return imported{}, fmt.Errorf("no profile matches columns %v, add one to csv-profiles in config", header)
+		}
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return imported{}, fmt.Errorf("unknown profile %q", name)
+	}
+	err := profile.Validate()
+	if err != nil {
+		return imported{}, fmt.Errorf("profile %s: %w", name, err)
+	}
+
+	secures, unmapped, err := csvimport.Read(bytes.NewReader(content), profile)
+	if err != nil {
+		return imported{}, err
+	}
+
+	return imported{secures: secures, unmapped: unmapped}, nil
+}
+
+// writeCSV Write secures as a csv file of the informer profile
+func writeCSV(w io.Writer, informerLibrary library.InformerLibrary, _ formatOptions) error {
+	for _, secure := range informerLibrary.SecureStore {
+		if len(secure.Attachments) > 0 {
+			log.Println("Attachments of", secure.ID, "are not exported, csv files can't hold them")
+		}
+	}
+
+	secures := informerLibrary.List()
+	defer library.WipeCopies(secures)
+
+	return csvimport.Write(w, secures)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"junjie.pro/informer/pkg/diceware"
+	"junjie.pro/informer/pkg/generator"
+	"junjie.pro/informer/pkg/strength"
+	"os"
+)
+
+func init() {
+	commands["generate"] = command{usage: "[-length n] [-lower=false] [-upper=false] [-digits=false] [-symbols=false] " +
+		"[-min-lower n] [-min-upper n] [-min-digits n] [-min-symbols n] [-exclude-look-alikes] [-symbol-set characters] [-count n]\n" +
+		"       informer generate -rules rules [-length n] [-count n]\n" +
+		"       informer generate -passphrase [-words n] [-separator s] [-capitalize] [-number] [-symbol] [-wordlist large|short|file] [-count n]", run: runGenerate}
+}
+
+// runGenerate Print random passwords or Diceware passphrases, one per line.
+// Entropy and estimated strength go to standard error, so that the output is only passwords.
+func runGenerate(args []string) error {
+	options := generator.DefaultOptions()
+	passphraseOptions := diceware.DefaultOptions()
+	flagSet := newFlagSet("generate")
+	passphrase := flagSet.Bool("passphrase", false, "Generate Diceware passphrases instead")
+	flagSet.IntVar(&passphraseOptions.Words, "words", passphraseOptions.Words, "Words of passphrase")
+	flagSet.StringVar(&passphraseOptions.Separator, "separator", passphraseOptions.Separator, "Separator between words")
+	flagSet.BoolVar(&passphraseOptions.Capitalize, "capitalize", false, "Capitalize words")
+	flagSet.BoolVar(&passphraseOptions.Number, "number", false, "Add a digit to a random word")
+	flagSet.BoolVar(&passphraseOptions.Symbol, "symbol", false, "Add a symbol to a random word")
+	wordlistName := flagSet.String("wordlist", "large", "Embedded wordlist large or short, or a wordlist file of one word per line")
+	flagSet.IntVar(&options.Length, "length", options.Length, "Characters of password")
+	flagSet.BoolVar(&options.Lower, "lower", options.Lower, "Include lowercase letters")
+	flagSet.BoolVar(&options.Upper, "upper", options.Upper, "Include uppercase letters")
+	flagSet.BoolVar(&options.Digits, "digits", options.Digits, "Include digits")
+	flagSet.BoolVar(&options.Symbols, "symbols", options.Symbols, "Include symbols")
+	flagSet.IntVar(&options.MinLower, "min-lower", options.MinLower, "Lowercase letters at least")
+	flagSet.IntVar(&options.MinUpper, "min-upper", options.MinUpper, "Uppercase letters at least")
+	flagSet.IntVar(&options.MinDigits, "min-digits", options.MinDigits, "Digits at least")
+	flagSet.IntVar(&options.MinSymbols, "min-symbols", options.MinSymbols, "Symbols at least")
+	flagSet.BoolVar(&options.ExcludeLookAlikes, "exclude-look-alikes", false, "Leave out characters mistaken for each other, such as 0 and O")
+	flagSet.StringVar(&options.SymbolSet, "symbol-set", "", "Symbols in place of "+generator.Symbols)
+	rulesText := flagSet.String("rules", "", "Password rules of a site in passwordrules syntax, in place of the class flags")
+	count := flagSet.Int("count", 1, "Passwords to generate")
+	if positional := parseFlags(flagSet, args); len(positional) > 0 {
+		flagSet.Usage()
+		return errors.New("generate takes no arguments")
+	}
+
+	set := map[string]bool{}
+	flagSet.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if *passphrase {
+		wordlist, err := loadWordlist(*wordlistName)
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < *count; i++ {
+			generated, err := wordlist.Generate(passphraseOptions)
+			if err != nil {
+				return err
+			}
+			fmt.Println(generated.Passphrase)
+			fmt.Fprintf(os.Stderr, "entropy: %.1f bits\n", generated.Entropy)
+			printStrength(generated.Passphrase)
+		}
+
+		return nil
+	}
+
+	if *rulesText != "" {
+		rules, err := generator.ParseRules(*rulesText)
+		if err != nil {
+			return err
+		}
+
+		//Length given is kept within rules, otherwise the default one is
+		length := 0
+		if set["length"] {
+			length = options.Length
+		}
+		for i := 0; i < *count; i++ {
+			password, err := generator.GenerateByRules(rules, length)
+			if err != nil {
+				return err
+			}
+			fmt.Println(password)
+			printStrength(password)
+		}
+
+		return nil
+	}
+
+	//Minimums of excluded classes are dropped, unless they're asked for
+	minimums := map[string]*int{"lower": &options.MinLower, "upper": &options.MinUpper, "digits": &options.MinDigits, "symbols": &options.MinSymbols}
+	included := map[string]bool{"lower": options.Lower, "upper": options.Upper, "digits": options.Digits, "symbols": options.Symbols}
+	for name, minimum := range minimums {
+		if !included[name] && !set["min-"+name] {
+			*minimum = 0
+		}
+	}
+
+	for i := 0; i < *count; i++ {
+		password, err := generator.Generate(options)
+		if err != nil {
+			return err
+		}
+		fmt.Println(password)
+		printStrength(password)
+	}
+
+	return nil
+}
+
+// printStrength Print estimated strength of password to standard error
+func printStrength(password string) {
+	fmt.Fprintln(os.Stderr, "strength:", strength.Estimate(password).Summary())
+}
+
+// loadWordlist Embedded wordlist of name, or wordlist of the file named so
+func loadWordlist(name string) (diceware.Wordlist, error) {
+	if name == "large" || name == "short" {
+		return diceware.Embedded(name)
+	}
+
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return diceware.ParseWordlist(content)
+}
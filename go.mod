@@ -3,9 +3,12 @@ module junjie.pro/informer
 go 1.16
 
 require (
+	filippo.io/age v1.0.0
+	github.com/boombuler/barcode v1.0.1-0.20190219062509-6c824513bacc
 	github.com/google/uuid v1.2.0
 	github.com/gorilla/mux v1.8.0
 	github.com/pquerna/otp v1.3.0
-	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
+	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
+	golang.org/x/sys v0.10.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"junjie.pro/informer/pkg/history"
+	"junjie.pro/informer/pkg/library"
+	"path/filepath"
+)
+
+func init() {
+	commands["init-history"] = command{usage: "", run: runInitHistory}
+	commands["log"] = command{usage: "", run: runLog}
+	commands["diff"] = command{usage: "[-key key] revision", run: runDiff}
+	commands["checkout"] = command{usage: "revision", run: runCheckout}
+	commands["push"] = command{usage: "remote", run: runPush}
+}
+
+// runInitHistory Keep library in a git repository, every write of it becomes a commit
+func runInitHistory(args []string) error {
+	parseFlags(newFlagSet("init-history"), args)
+
+	dataDir, _, err := dataLocation()
+	if err != nil {
+		return err
+	}
+
+	err = history.Init(dataDir)
+	if err != nil {
+		return err
+	}
+
+	return library.CommitHistory("Start history")
+}
+
+func runLog(args []string) error {
+	parseFlags(newFlagSet("log"), args)
+
+	dataDir, dataFile, err := historyLocation()
+	if err != nil {
+		return err
+	}
+
+	entries, err := history.Log(dataDir, dataFile)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		fmt.Println(entry.Revision, entry.Date, entry.Message)
+	}
+
+	return nil
+}
+
+// runDiff Show secures changed since revision, secrets are only compared if key is given
+func runDiff(args []string) error {
+	flagSet := newFlagSet("diff")
+	revisions := parseFlags(flagSet, args)
+	if len(revisions) != 1 {
+		flagSet.Usage()
+		return errors.New("revision is needed")
+	}
+
+	dataDir, dataFile, err := historyLocation()
+	if err != nil {
+		return err
+	}
+
+	libraryFile, err := history.Show(dataDir, revisions[0], dataFile)
+	if err != nil {
+		return err
+	}
+	before, err := library.ParseLibrary(libraryFile)
+	if err != nil {
+		return err
+	}
+	after, err := library.ReadLibrary()
+	if err != nil {
+		return err
+	}
+	//History never holds the sealed part, it's compared as it is now
+	before.Sealed = after.Sealed
+
+	if key != "" {
+		err = before.Unlock([]byte(key))
+		if err != nil {
+			return err
+		}
+		err = after.Unlock([]byte(key))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, difference := range library.Diff(before, after) {
+		if difference.Field == "" {
+			fmt.Println(difference.PrimaryKey, difference.ID, difference.New)
+		} else {
+			fmt.Println(difference.PrimaryKey, difference.ID, difference.Field+":", difference.Old, "->", difference.New)
+		}
+	}
+
+	return nil
+}
+
+// runCheckout Restore library as it was at revision. History is kept, the restore is a new commit
+func runCheckout(args []string) error {
+	flagSet := newFlagSet("checkout")
+	revisions := parseFlags(flagSet, args)
+	if len(revisions) != 1 {
+		flagSet.Usage()
+		return errors.New("revision is needed")
+	}
+
+	dataDir, dataFile, err := historyLocation()
+	if err != nil {
+		return err
+	}
+
+	libraryFile, err := history.Show(dataDir, revisions[0], dataFile)
+	if err != nil {
+		return err
+	}
+	restored, err := library.ParseLibrary(libraryFile)
+	if err != nil {
+		return err
+	}
+	informerLibrary, err := library.ReadLibrary()
+	if err != nil {
+		return err
+	}
+
+	informerLibrary.Restore(restored, "Checkout "+revisions[0])
+
+	return informerLibrary.WriteLibrary()
+}
+
+// runPush Back up history to another repository, such as a bare repository on a path
+func runPush(args []string) error {
+	flagSet := newFlagSet("push")
+	remotes := parseFlags(flagSet, args)
+	if len(remotes) != 1 {
+		flagSet.Usage()
+		return errors.New("remote is needed")
+	}
+
+	dataDir, _, err := historyLocation()
+	if err != nil {
+		return err
+	}
+
+	return history.Push(dataDir, remotes[0])
+}
+
+func dataLocation() (string, string, error) {
+	dataLocation, err := library.DataPath()
+	if err != nil {
+		return "", "", err
+	}
+
+	return filepath.Dir(dataLocation), filepath.Base(dataLocation), nil
+}
+
+// historyLocation Data directory and library file, if history is enabled
+func historyLocation() (string, string, error) {
+	dataDir, dataFile, err := dataLocation()
+	if err != nil {
+		return "", "", err
+	}
+
+	if !history.Enabled(dataDir) {
+		return "", "", errors.New("history is not enabled, run informer init-history first")
+	}
+
+	return dataDir, dataFile, nil
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"junjie.pro/informer/pkg/library"
+	"os"
+	"sort"
+	"strings"
+)
+
+// format File format secures are imported from or exported to. Plaintext formats are only exported
+// encrypted with age, unless asked otherwise.
+type format struct {
+	read      func(content []byte, options formatOptions) (imported, error)
+	write     func(w io.Writer, informerLibrary library.InformerLibrary, options formatOptions) error
+	plaintext bool
+}
+
+// formatOptions Options of import and export given by flags
+type formatOptions struct {
+	password string
+	keyFile  string
+	profile  string
+	//age encryption of the whole file
+	recipients listFlag
+	identities listFlag
+	passphrase string
+}
+
+// imported Secures read from a file keyed by primary key, and what the file has but secures can't keep
+type imported struct {
+	secures  map[string]library.SecureStore
+	unmapped []string
+}
+
+var formats = map[string]format{}
+
+// stdin is shared by every prompt of import and export, so piped answers aren't buffered away by another scanner
+var stdin = bufio.NewScanner(os.Stdin)
+
+func init() {
+	commands["import"] = command{usage: "-format format [-key key] [-password password] [-key-file file] [-profile profile] [-identity file]... [-passphrase passphrase] [-duplicates mode] [-dry-run] file", run: runImport}
+	commands["export"] = command{usage: "-format format [-key key] [-password password] [-key-file file] [-recipient recipient]... [-passphrase passphrase] [-plaintext] file", run: runExport}
+}
+
+// runImport Add secures of another password manager to library, secures imported before are updated
+func runImport(args []string) error {
+	flagSet, formatName, options := newFormatFlagSet("import")
+	dryRun := flagSet.Bool("dry-run", false, "Only report unmapped fields and duplicates, nothing is written")
+	duplicateMode := flagSet.String("duplicates", duplicateAsk, "Resolve duplicates by ask, skip, overwrite or keep-both")
+	files := parseFlags(flagSet, args)
+	if len(files) != 1 {
+		flagSet.Usage()
+		return errors.New("file is needed")
+	}
+	f, ok := formats[*formatName]
+	if !ok || f.read == nil {
+		return fmt.Errorf("unknown format %q, available formats: %s", *formatName, strings.Join(formatNames(), ", "))
+	}
+
+	informerLibrary, err := unlockLibrary()
+	if err != nil {
+		return err
+	}
+	defer informerLibrary.Wipe()
+
+	var resolve func(duplicate) (string, error)
+	switch *duplicateMode {
+	case duplicateAsk:
+		resolve = askDuplicate(stdin)
+	case duplicateSkip, duplicateOverwrite, duplicateKeepBoth:
+		resolve = func(duplicate) (string, error) { return *duplicateMode, nil }
+	default:
+		return fmt.Errorf("unknown duplicate resolution %q", *duplicateMode)
+	}
+	if *dryRun {
+		resolve = func(duplicate) (string, error) { return duplicateKeepBoth, nil }
+	}
+
+	content, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		return err
+	}
+	content, err = ageDecrypt(content, *options)
+	if err != nil {
+		return err
+	}
+	result, err := f.read(content, *options)
+	if err != nil {
+		return err
+	}
+
+	secures, found, err := resolveDuplicates(informerLibrary, result.secures, resolve)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		for _, unmapped := range result.unmapped {
+			fmt.Println("unmapped:", unmapped)
+		}
+		for _, d := range found {
+			fmt.Println("duplicate:", d)
+		}
+		fmt.Println(len(result.secures), "secures would be imported")
+
+		return nil
+	}
+
+	for k, secure := range secures {
+		informerLibrary.Update(k, secure)
+	}
+
+	err = informerLibrary.Lock([]byte(key))
+	if err != nil {
+		return err
+	}
+	err = informerLibrary.WriteLibrary()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(len(secures), "secures imported")
+
+	return nil
+}
+
+// runExport Write every secure to a file of another password manager, encrypted with age for recipients or passphrase if given.
+// Encrypted files are written as they're encrypted, their plain text never reaches the disk.
+func runExport(args []string) error {
+	flagSet, formatName, options := newFormatFlagSet("export")
+	plaintext := flagSet.Bool("plaintext", false, "Allow exporting plaintext formats without encryption")
+	files := parseFlags(flagSet, args)
+	if len(files) != 1 {
+		flagSet.Usage()
+		return errors.New("file is needed")
+	}
+	f, ok := formats[*formatName]
+	if !ok || f.write == nil {
+		return fmt.Errorf("unknown format %q, available formats: %s", *formatName, strings.Join(formatNames(), ", "))
+	}
+
+	encrypted := len(options.recipients) > 0 || options.passphrase != ""
+	if f.plaintext && !encrypted && !*plaintext {
+		return fmt.Errorf("%s exports are plaintext, encrypt them by -recipient or -passphrase, or give -plaintext", *formatName)
+	}
+
+	informerLibrary, err := unlockLibrary()
+	if err != nil {
+		return err
+	}
+	defer informerLibrary.Wipe()
+
+	file, err := os.OpenFile(files[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(0600))
+	if err != nil {
+		return err
+	}
+	err = writeExport(file, f, informerLibrary, *options)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		//Never leave a partial export behind
+		_ = os.Remove(files[0])
+		return err
+	}
+
+	fmt.Println(len(informerLibrary.SecureStore), "secures exported")
+
+	return nil
+}
+
+// writeExport Write library in format to file, through age encryption if recipients or passphrase are given
+func writeExport(file io.Writer, f format, informerLibrary library.InformerLibrary, options formatOptions) error {
+	if len(options.recipients) == 0 && options.passphrase == "" {
+		return f.write(file, informerLibrary, options)
+	}
+
+	w, err := ageEncrypt(file, options)
+	if err != nil {
+		return err
+	}
+	err = f.write(w, informerLibrary, options)
+	if err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+func newFormatFlagSet(name string) (*flag.FlagSet, *string, *formatOptions) {
+	flagSet := newFlagSet(name)
+	formatName := flagSet.String("format", "", "File format: "+strings.Join(formatNames(), ", "))
+	options := &formatOptions{}
+	flagSet.StringVar(&options.password, "password", "", "Password of the file, asked if needed and not given")
+	flagSet.StringVar(&options.keyFile, "key-file", "", "Key file of the file")
+	flagSet.StringVar(&options.profile, "profile", "", "Column mapping profile of csv files, detected by header if not given")
+	flagSet.StringVar(&options.passphrase, "passphrase", "", "Passphrase of age encryption, asked for passphrase encrypted imports if not given")
+	if name == "export" {
+		flagSet.Var(&options.recipients, "recipient", "Encrypt with age for recipient age1..., repeat for more recipients")
+	} else {
+		flagSet.Var(&options.identities, "identity", "age identity file decrypting the import, repeat for more identities")
+	}
+
+	return flagSet, formatName, options
+}
+
+// duplicate Imported secure having the same username and site as another secure,
+// either in library or imported before it from the same file
+type duplicate struct {
+	k         string
+	secure    library.SecureStore
+	otherKey  string
+	other     library.SecureStore
+	inLibrary bool
+}
+
+func (d duplicate) String() string {
+	where := "in file"
+	if d.inLibrary {
+		where = "in library"
+	}
+
+	return fmt.Sprintf("%s (%s) matches %s (%s) %s", d.secure.ID, d.k, d.other.ID, d.otherKey, where)
+}
+
+const (
+	duplicateAsk       = "ask"
+	duplicateSkip      = "skip"
+	duplicateOverwrite = "overwrite"
+	duplicateKeepBoth  = "keep-both"
+)
+
+// resolveDuplicates Secures to import after resolving every duplicate by resolve, which returns
+// duplicateSkip, duplicateOverwrite or duplicateKeepBoth. Overwriting keeps the primary key of the other secure.
+// Secures imported before have the same primary key and aren't duplicates.
+func resolveDuplicates(informerLibrary library.InformerLibrary, secures map[string]library.SecureStore,
+	resolve func(duplicate) (string, error)) (map[string]library.SecureStore, []duplicate, error) {
+	var otherKeys []string
+	others := map[string]library.SecureStore{}
+	for k, secure := range informerLibrary.SecureStore {
+		otherKeys = append(otherKeys, k)
+		others[k] = *secure
+	}
+	sort.Strings(otherKeys)
+
+	keys := make([]string, 0, len(secures))
+	for k := range secures {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	resolved := map[string]library.SecureStore{}
+	var found []duplicate
+	for _, k := range keys {
+		secure := secures[k]
+		choice := duplicateKeepBoth
+		target := k
+		for _, otherKey := range otherKeys {
+			if otherKey == k || !sameLogin(secure, others[otherKey]) {
+				continue
+			}
+
+			_, inLibrary := informerLibrary.SecureStore[otherKey]
+			d := duplicate{k: k, secure: secure, otherKey: otherKey, other: others[otherKey], inLibrary: inLibrary}
+			found = append(found, d)
+			var err error
+			choice, err = resolve(d)
+			if err != nil {
+				return nil, nil, err
+			}
+			if choice == duplicateOverwrite {
+				target = otherKey
+			}
+			break
+		}
+
+		if choice == duplicateSkip {
+			continue
+		}
+		resolved[target] = secure
+		if _, ok := others[target]; !ok {
+			otherKeys = append(otherKeys, target)
+		}
+		others[target] = secure
+	}
+
+	return resolved, found, nil
+}
+
+// askDuplicate Ask how to resolve a duplicate
+func askDuplicate(scanner *bufio.Scanner) func(duplicate) (string, error) {
+	return func(d duplicate) (string, error) {
+		fmt.Println("duplicate:", d)
+		for {
+			fmt.Print("(s)kip, (o)verwrite or (k)eep both: ")
+			if !scanner.Scan() {
+				return "", errors.New("import aborted, nothing written")
+			}
+
+			switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+			case "s", duplicateSkip:
+				return duplicateSkip, nil
+			case "o", duplicateOverwrite:
+				return duplicateOverwrite, nil
+			case "k", duplicateKeepBoth:
+				return duplicateKeepBoth, nil
+			}
+		}
+	}
+}
+
+// sameLogin Whether two secures have the same username on the same site
+func sameLogin(a library.SecureStore, b library.SecureStore) bool {
+	if !strings.EqualFold(a.Username, b.Username) {
+		return false
+	}
+
+	return (a.URL != "" && strings.EqualFold(a.URL, b.URL)) ||
+		strings.EqualFold(a.Platform, b.Platform) ||
+		strings.EqualFold(a.ID, b.ID)
+}
+
+func formatNames() []string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// unlockLibrary Read library and unlock it by key
+func unlockLibrary() (library.InformerLibrary, error) {
+	if key == "" {
+		return library.InformerLibrary{}, errors.New("key is needed")
+	}
+
+	informerLibrary, err := library.ReadLibrary()
+	if err != nil {
+		return library.InformerLibrary{}, err
+	}
+
+	err = informerLibrary.Unlock([]byte(key))
+	if err != nil {
+		return library.InformerLibrary{}, err
+	}
+
+	return informerLibrary, nil
+}
+
+// filePassword Password given by flag, or asked
+func (options formatOptions) filePassword() string {
+	if options.password != "" {
+		return options.password
+	}
+
+	fmt.Print("file password: ")
+	stdin.Scan()
+
+	return stdin.Text()
+}
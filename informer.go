@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"junjie.pro/informer/api"
 	"junjie.pro/informer/pkg/library"
+	"junjie.pro/informer/pkg/otp"
+	"junjie.pro/informer/pkg/strength"
 	"log"
 	"os"
 	"strconv"
@@ -23,6 +25,8 @@ var (
 	server     bool
 	flagSet    map[string]bool
 	version    bool
+	decoy      bool
+	duressKey  string
 )
 
 func init() {
@@ -35,6 +39,8 @@ func init() {
 	flag.BoolVar(&showSecure, "show-secure", false, "Show plain text secure")
 	flag.BoolVar(&server, "server", false, "Enable server mode")
 	flag.BoolVar(&version, "version", false, "Show current version")
+	flag.BoolVar(&decoy, "decoy", false, "Hide secures behind key, and start an empty decoy library for duress key")
+	flag.StringVar(&duressKey, "duress-key", "", "Key for the decoy library")
 	flag.Parse()
 
 	flagSet = map[string]bool{}
@@ -46,6 +52,7 @@ func init() {
 func main() {
 	if len(os.Args) == 1 {
 		flag.PrintDefaults()
+		fmt.Println("  commands:", strings.Join(commandNames(), ", "))
 		return
 	}
 
@@ -54,6 +61,15 @@ func main() {
 		return
 	}
 
+	if flag.NArg() > 0 {
+		err := runCommand(flag.Arg(0), flag.Args()[1:])
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		return
+	}
+
 	informerLibrary, err := library.ReadLibrary()
 	if err != nil {
 		panic(err)
@@ -83,9 +99,33 @@ func main() {
 		}
 	}
 
+	if flagSet["decoy"] {
+		if key == "" || duressKey == "" {
+			panic("key or duress key is empty")
+		}
+
+		err := informerLibrary.Conceal([]byte(key), []byte(duressKey))
+		if err != nil {
+			panic(err)
+		}
+
+		err = informerLibrary.WriteLibrary()
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	if flagSet["remove"] {
 		scanner := bufio.NewScanner(os.Stdin)
 
+		//Secures are listed after unlocking, so that both sets behave the same
+		if key != "" {
+			err := informerLibrary.Unlock([]byte(key))
+			if err != nil {
+				panic(err)
+			}
+		}
+
 		fmt.Println("Which secure do you want to remove?")
 		fmt.Println()
 
@@ -110,6 +150,13 @@ func main() {
 		if num >= 0 {
 			informerLibrary.Remove(numberMapper[num])
 
+			if key != "" {
+				err = informerLibrary.Lock([]byte(key))
+				if err != nil {
+					panic(err)
+				}
+			}
+
 			err = informerLibrary.WriteLibrary()
 			if err != nil {
 				panic(err)
@@ -125,6 +172,11 @@ func main() {
 	if flagSet["update"] {
 		scanner := bufio.NewScanner(os.Stdin)
 
+		err := informerLibrary.Unlock([]byte(key))
+		if err != nil {
+			panic(err)
+		}
+
 		fmt.Println("Which secure do you want to update?")
 		fmt.Println()
 
@@ -147,15 +199,14 @@ func main() {
 		}
 
 		if num >= 0 {
+			fmt.Println("Leave blank to keep current values.")
 			newSecure := inputSecureStore()
 
-			err = informerLibrary.Unlock([]byte(key))
+			err = informerLibrary.Edit(numberMapper[num], newSecure)
 			if err != nil {
 				panic(err)
 			}
 
-			informerLibrary.Update(numberMapper[num], newSecure)
-
 			err = informerLibrary.Lock([]byte(key))
 			if err != nil {
 				panic(err)
@@ -181,9 +232,11 @@ func main() {
 			}
 		}
 
-		for _, secure := range informerLibrary.List() {
+		secures := informerLibrary.List()
+		for _, secure := range secures {
 			printSecureStore(secure, showSecure)
 		}
+		library.WipeCopies(secures)
 	}
 
 	if flagSet["query"] {
@@ -200,6 +253,7 @@ func main() {
 				printSecureStore(secure, showSecure)
 			}
 		}
+		library.WipeCopies(secures)
 	}
 
 	if flagSet["server"] {
@@ -212,11 +266,47 @@ func printSecureStore(secure library.SecureStore, showSecure bool) {
 	fmt.Println("platform:", secure.Platform)
 	fmt.Println("friendly name:", secure.FriendlyName)
 	fmt.Println("username:", secure.Username)
+	if secure.URL != "" {
+		fmt.Println("url:", secure.URL)
+	}
+	if secure.Folder != "" {
+		fmt.Println("folder:", secure.Folder)
+	}
+	if secure.PasswordRules != "" {
+		fmt.Println("password rules:", secure.PasswordRules)
+	}
 
 	if showSecure {
-		fmt.Println("password:", secure.Password)
-		fmt.Println("otp:", secure.OTP)
+		fmt.Println("password:", secure.Password.Reveal())
+		fmt.Println("otp:", secure.OTP.Reveal())
 		fmt.Println("otp type:", secure.OTPType)
+		if secure.OTPIssuer != "" {
+			fmt.Println("otp issuer:", secure.OTPIssuer)
+		}
+		if secure.OTPAlgorithm != "" {
+			fmt.Println("otp algorithm:", secure.OTPAlgorithm)
+		}
+		if secure.OTPDigits != 0 {
+			fmt.Println("otp digits:", secure.OTPDigits)
+		}
+		if secure.OTPPeriod != 0 {
+			fmt.Println("otp period:", secure.OTPPeriod)
+		}
+		if otp.CounterBased(secure.OTPType) {
+			fmt.Println("otp counter:", secure.OTPCounter)
+		}
+		if len(secure.OTPPin) > 0 {
+			fmt.Println("otp pin:", secure.OTPPin.Reveal())
+		}
+		if len(secure.Notes) > 0 {
+			fmt.Println("notes:", secure.Notes.Reveal())
+		}
+		for _, field := range secure.Fields {
+			fmt.Println(field.Name+":", field.Value.Reveal())
+		}
+		for _, attachment := range secure.Attachments {
+			fmt.Println("attachment:", attachment.Name, len(attachment.Data), "bytes")
+		}
 	}
 
 	fmt.Println()
@@ -248,7 +338,17 @@ func inputSecureStore() library.SecureStore {
 	scanner.Scan()
 	password = scanner.Text()
 
-	fmt.Print("otp: ")
+	//Weak passwords are only warned about, as they may be required by the platform
+	if password != "" {
+		if result := strength.Estimate(password, id, platform, friendlyName, username); result.Weak() {
+			fmt.Println("warning: weak password,", result.Summary())
+			for _, suggestion := range result.Feedback.Suggestions {
+				fmt.Println("  " + suggestion)
+			}
+		}
+	}
+
+	fmt.Print("otp (secret or otpauth URI): ")
 	scanner.Scan()
 	otp = scanner.Text()
 
@@ -261,10 +361,31 @@ func inputSecureStore() library.SecureStore {
 		Platform:     platform,
 		FriendlyName: friendlyName,
 		Username:     username,
-		Password:     password,
-		OTP:          otp,
+		Password:     library.NewSecret(password),
+		OTP:          library.NewSecret(otp),
 		OTPType:      otpType,
 	}
 
+	//Ask again until OTP secret is valid, then show a code to confirm against the provider
+	for {
+		testCode, err := secure.NormalizeOTP()
+		if err == nil {
+			if testCode != "" {
+				fmt.Println("otp test code:", testCode)
+			}
+			break
+		}
+
+		fmt.Println("invalid otp:", err.Error())
+		fmt.Print("otp: ")
+		if !scanner.Scan() {
+			log.Fatalln("invalid otp:", err.Error())
+		}
+		secure.OTP = library.NewSecret(scanner.Text())
+		fmt.Print("otp type: ")
+		scanner.Scan()
+		secure.OTPType = scanner.Text()
+	}
+
 	return secure
 }
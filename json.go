@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"junjie.pro/informer/pkg/library"
+)
+
+func init() {
+	formats["json"] = format{read: readJSON, write: writeJSON, plaintext: true}
+}
+
+// readJSON Secures of an informer JSON export keyed by primary key
+func readJSON(content []byte, _ formatOptions) (imported, error) {
+	secures := map[string]library.SecureStore{}
+	err := json.Unmarshal(content, &secures)
+	if err != nil {
+		return imported{}, err
+	}
+
+	return imported{secures: secures}, nil
+}
+
+// writeJSON Write unlocked secures keyed by primary key as JSON
+func writeJSON(w io.Writer, informerLibrary library.InformerLibrary, _ formatOptions) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	secures := informerLibrary.List()
+	defer library.WipeCopies(secures)
+
+	return encoder.Encode(secures)
+}
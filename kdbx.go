@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"junjie.pro/informer/pkg/kdbx"
+	"junjie.pro/informer/pkg/library"
+)
+
+func init() {
+	formats["kdbx"] = format{read: readKDBX, write: writeKDBX}
+}
+
+// readKDBX Secures of a KeePass KDBX 4 database
+func readKDBX(content []byte, options formatOptions) (imported, error) {
+	credentials, err := kdbxCredentials(options)
+	if err != nil {
+		return imported{}, err
+	}
+
+	database, err := kdbx.Read(bytes.NewReader(content), credentials)
+	if err != nil {
+		return imported{}, err
+	}
+
+	return imported{secures: database.Secures()}, nil
+}
+
+// writeKDBX Write secures as a KeePass KDBX 4 database
+func writeKDBX(w io.Writer, informerLibrary library.InformerLibrary, options formatOptions) error {
+	credentials, err := kdbxCredentials(options)
+	if err != nil {
+		return err
+	}
+
+	return kdbx.NewDatabase(informerLibrary).Write(w, credentials)
+}
+
+func kdbxCredentials(options formatOptions) (kdbx.Credentials, error) {
+	credentials := kdbx.Credentials{}
+	if options.keyFile != "" {
+		keyFile, err := ioutil.ReadFile(options.keyFile)
+		if err != nil {
+			return kdbx.Credentials{}, err
+		}
+		credentials.KeyFile = keyFile
+	}
+	//A key file alone is enough, the password is only asked without one
+	if options.password != "" || options.keyFile == "" {
+		credentials.Password = options.filePassword()
+	}
+
+	return credentials, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"junjie.pro/informer/pkg/library"
+	"os"
+	"strings"
+)
+
+func init() {
+	commands["merge"] = command{usage: "[-key key] [-json] [-output file] base ours theirs", run: runMerge}
+}
+
+// runMerge Three-way merge of diverged library files, result is written to ours unless output is given
+func runMerge(args []string) error {
+	flagSet := newFlagSet("merge")
+	jsonOutput := flagSet.Bool("json", false, "Report conflicts as json instead of asking, nothing is written if any")
+	output := flagSet.String("output", "", "Write merged library to this file instead of ours")
+	files := parseFlags(flagSet, args)
+	if len(files) != 3 {
+		flagSet.Usage()
+		return errors.New("base, ours and theirs libraries are needed")
+	}
+
+	var libraries []library.InformerLibrary
+	for _, file := range files {
+		informerLibrary, err := library.ReadLibraryFile(file)
+		if err != nil {
+			return err
+		}
+		libraries = append(libraries, informerLibrary)
+	}
+
+	//Cipher texts change on every Lock, so locked libraries are compared unlocked
+	locked := !libraries[1].Unlocked
+	for i := range libraries {
+		if libraries[i].Unlocked {
+			continue
+		}
+		if key == "" {
+			return fmt.Errorf("%s is locked, key is needed", files[i])
+		}
+
+		err := libraries[i].Unlock([]byte(key))
+		if err != nil {
+			return fmt.Errorf("unlock %s: %w", files[i], err)
+		}
+	}
+
+	//Key must open the same set of every library, otherwise secures of different sets would be merged
+	for i := range libraries {
+		if libraries[i].Concealed() != libraries[1].Concealed() {
+			return fmt.Errorf("key doesn't unlock %s like %s", files[i], files[1])
+		}
+	}
+
+	merged, conflicts := library.Merge(libraries[0], libraries[1], libraries[2])
+
+	if *jsonOutput {
+		if conflicts == nil {
+			conflicts = []library.Conflict{}
+		}
+		err := json.NewEncoder(os.Stdout).Encode(conflicts)
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			return fmt.Errorf("%d conflicts found, nothing written", len(conflicts))
+		}
+	} else {
+		err := resolveConflicts(&merged, conflicts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if locked {
+		err := merged.Lock([]byte(key))
+		if err != nil {
+			return err
+		}
+	}
+
+	destination := files[1]
+	if *output != "" {
+		destination = *output
+	}
+
+	return merged.WriteLibraryFile(destination)
+}
+
+// resolveConflicts Ask which side to keep for every conflict
+func resolveConflicts(merged *library.InformerLibrary, conflicts []library.Conflict) error {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	for _, conflict := range conflicts {
+		fmt.Println("conflict:", conflict.ID, conflict.PrimaryKey)
+		if conflict.Field != "" {
+			fmt.Println("field:", conflict.Field)
+		}
+		fmt.Println("base:", conflict.Base)
+		fmt.Println("ours:", conflict.Ours)
+		fmt.Println("theirs:", conflict.Theirs)
+
+		for resolved := false; !resolved; {
+			fmt.Print("keep (o)urs or (t)heirs: ")
+			if !scanner.Scan() {
+				return errors.New("merge aborted, nothing written")
+			}
+
+			switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+			case "o", "ours":
+				resolved = true
+			case "t", "theirs":
+				merged.TakeTheirs(conflict)
+				resolved = true
+			}
+		}
+
+		fmt.Println()
+	}
+
+	return nil
+}
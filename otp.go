@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"junjie.pro/informer/pkg/library"
+	"junjie.pro/informer/pkg/otp"
+	"junjie.pro/informer/pkg/qr"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	commands["otp"] = command{usage: "[-key key] [-watch] [-json] query | qr [-key key] [-ansi] [-output file.png] [-scale pixels] secure | import [-key key] [-dry-run] uri|image|file... | resync [-key key] [-window counters] secure code code", run: runOTP}
+}
+
+// runOTP Run sub command of otp
+func runOTP(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: informer otp", commands["otp"].usage)
+		return errors.New("otp command is needed")
+	}
+
+	switch args[0] {
+	case "import":
+		return runOTPImport(args[1:])
+	case "resync":
+		return runOTPResync(args[1:])
+	case "qr":
+		return runOTPQR(args[1:])
+	}
+
+	return runOTPCodes(args)
+}
+
+// otpCode Code of a secure as printed by otp command, codes of counter based types have no period
+type otpCode struct {
+	PrimaryKey string `json:"primaryKey"`
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	PassCode   string `json:"passCode"`
+	Period     int    `json:"period,omitempty"`
+	Remaining  int    `json:"remaining,omitempty"`
+}
+
+// runOTPCodes Print codes of secures matching query. When watching, codes and their countdown are refreshed
+// every second until interrupted, json is printed again only when codes change.
+func runOTPCodes(args []string) error {
+	flagSet := newFlagSet("otp")
+	watch := flagSet.Bool("watch", false, "Refresh codes and countdown until interrupted")
+	asJSON := flagSet.Bool("json", false, "Print codes as json")
+	positional := parseFlags(flagSet, args)
+	if len(positional) != 1 {
+		flagSet.Usage()
+		return errors.New("query is needed")
+	}
+
+	informerLibrary, err := unlockLibrary()
+	if err != nil {
+		return err
+	}
+	defer informerLibrary.Wipe()
+
+	_, secures := informerLibrary.Query(positional[0])
+	defer library.WipeCopies(secures)
+	var keys []string
+	for k, secure := range secures {
+		if len(secure.OTP) > 0 {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no secure with OTP matches %q", positional[0])
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return secures[keys[i]].ID < secures[keys[j]].ID
+	})
+
+	//Codes of counter based types are issued once, as every code issued moves the counter on
+	issued := map[string]string{}
+	for _, k := range keys {
+		account := secures[k].OTPAccount()
+		if otp.CounterBased(account.Type) {
+			issued[k], err = library.IssueHOTPCode(&informerLibrary, []byte(key), k)
+			if err != nil {
+				return fmt.Errorf("%s: %w", secures[k].ID, err)
+			}
+		}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	printed := ""
+	for {
+		var codes []otpCode
+		passCodes := ""
+		for _, k := range keys {
+			code, err := secureCode(k, secures[k], issued, time.Now())
+			if err != nil {
+				return fmt.Errorf("%s: %w", secures[k].ID, err)
+			}
+			codes = append(codes, code)
+			passCodes += code.PassCode + " "
+		}
+
+		switch {
+		case *asJSON && passCodes != printed:
+			content, err := json.Marshal(codes)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(content))
+		case !*asJSON && *watch:
+			//Move back to the first line of codes and draw them over
+			if printed != "" {
+				fmt.Printf("\033[%dA", len(codes))
+			}
+			for _, code := range codes {
+				fmt.Printf("\033[K%s\n", formatOTPCode(code))
+			}
+		case !*asJSON:
+			for _, code := range codes {
+				fmt.Println(formatOTPCode(code))
+			}
+		}
+		printed = passCodes
+
+		if !*watch {
+			return nil
+		}
+		select {
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// secureCode Code of secure k at time t, codes of counter based types are the issued ones
+func secureCode(k string, secure library.SecureStore, issued map[string]string, t time.Time) (otpCode, error) {
+	code := otpCode{PrimaryKey: k, ID: secure.ID, Username: secure.Username}
+	if passCode, ok := issued[k]; ok {
+		code.PassCode = passCode
+		return code, nil
+	}
+
+	account := secure.OTPAccount()
+	passCode, err := otp.GenerateCode(account, t)
+	if err != nil {
+		return otpCode{}, err
+	}
+	code.PassCode = passCode
+	code.Period = otp.Period(account)
+	code.Remaining = otp.Remaining(account, t)
+
+	return code, nil
+}
+
+// formatOTPCode Line of code with a bar counting down the seconds it stays valid
+func formatOTPCode(code otpCode) string {
+	line := fmt.Sprintf("%s (%s)  %s", code.ID, code.Username, code.PassCode)
+	if code.Period < 1 {
+		return line
+	}
+
+	const width = 30
+	filled := width * code.Remaining / code.Period
+
+	return fmt.Sprintf("%s  [%s%s] %2ds", line, strings.Repeat("#", filled), strings.Repeat("-", width-filled), code.Remaining)
+}
+
+// runOTPImport Set OTP of secures matching issuer and username of imported accounts, and add secures for the rest.
+// Accounts are read from otpauth and otpauth-migration URIs, QR code images, or text files of one URI per line.
+func runOTPImport(args []string) error {
+	flagSet := newFlagSet("otp")
+	dryRun := flagSet.Bool("dry-run", false, "Only report matched and new accounts, nothing is written")
+	sources := parseFlags(flagSet, args)
+	if len(sources) == 0 {
+		flagSet.Usage()
+		return errors.New("uri, image or file is needed")
+	}
+
+	var accounts []otp.Account
+	for _, source := range sources {
+		uris, err := readOTPURIs(source)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		for _, uri := range uris {
+			parsed, err := otp.ParseURI(uri)
+			if err != nil {
+				return fmt.Errorf("%s: %w", source, err)
+			}
+			accounts = append(accounts, parsed...)
+		}
+	}
+
+	informerLibrary, err := unlockLibrary()
+	if err != nil {
+		return err
+	}
+	defer informerLibrary.Wipe()
+
+	updated, added := 0, 0
+	for _, account := range accounts {
+		name := account.Issuer
+		if name == "" {
+			name = account.Name
+		}
+		k, secure, ok := matchAccount(informerLibrary, account)
+		if ok {
+			fmt.Printf("update: %s (%s) matches %s (%s)\n", name, account.Name, secure.ID, k)
+			updated++
+		} else {
+			secure = library.SecureStore{ID: name, Platform: account.Issuer, FriendlyName: name, Username: account.Name}
+			fmt.Printf("add: %s (%s)\n", name, account.Name)
+			added++
+		}
+		if *dryRun {
+			continue
+		}
+
+		secure.SetOTPAccount(account)
+		if ok {
+			informerLibrary.Update(k, secure)
+		} else {
+			informerLibrary.Add(secure)
+		}
+	}
+
+	if *dryRun {
+		fmt.Println(updated, "secures would be updated,", added, "would be added")
+		return nil
+	}
+
+	err = informerLibrary.Lock([]byte(key))
+	if err != nil {
+		return err
+	}
+	err = informerLibrary.WriteLibrary()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(updated, "secures updated,", added, "added")
+
+	return nil
+}
+
+// readOTPURIs URIs of a source, which is a URI itself, a QR code image or a text file of one URI per line
+func readOTPURIs(source string) ([]string, error) {
+	if strings.HasPrefix(source, "otpauth://") || strings.HasPrefix(source, "otpauth-migration://") {
+		return []string{source}, nil
+	}
+
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if img, _, err := image.Decode(bytes.NewReader(content)); err == nil {
+		text, err := qr.Decode(img)
+		if err != nil {
+			return nil, err
+		}
+		return []string{text}, nil
+	}
+
+	var uris []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			uris = append(uris, line)
+		}
+	}
+	if len(uris) == 0 {
+		return nil, errors.New("no URI found")
+	}
+
+	return uris, nil
+}
+
+// matchAccount Secure having the username of account, on a site named by its issuer.
+// The secure sorted first wins when several match.
+func matchAccount(informerLibrary library.InformerLibrary, account otp.Account) (string, library.SecureStore, bool) {
+	if account.Name == "" || account.Issuer == "" {
+		return "", library.SecureStore{}, false
+	}
+
+	var keys []string
+	for k := range informerLibrary.SecureStore {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	issuer := strings.ToLower(account.Issuer)
+	for _, k := range keys {
+		secure := informerLibrary.SecureStore[k]
+		if !strings.EqualFold(secure.Username, account.Name) {
+			continue
+		}
+
+		if strings.EqualFold(secure.Platform, issuer) ||
+			strings.EqualFold(secure.ID, issuer) ||
+			strings.EqualFold(secure.FriendlyName, issuer) ||
+			(secure.URL != "" && strings.Contains(strings.ToLower(urlHost(secure.URL)), issuer)) {
+			return k, *secure, true
+		}
+	}
+
+	return "", library.SecureStore{}, false
+}
+
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	return u.Host
+}
+
+// runOTPResync Find HOTP counter of secure from two consecutive codes shown by the server, and persist the counter following them
+func runOTPResync(args []string) error {
+	flagSet := newFlagSet("otp")
+	window := flagSet.Uint64("window", 100, "Counters to search ahead of the stored one")
+	positional := parseFlags(flagSet, args)
+	if len(positional) != 3 {
+		flagSet.Usage()
+		return errors.New("secure and two consecutive codes are needed")
+	}
+
+	informerLibrary, err := unlockLibrary()
+	if err != nil {
+		return err
+	}
+	defer informerLibrary.Wipe()
+
+	k, err := findSecure(informerLibrary, positional[0])
+	if err != nil {
+		return err
+	}
+	account := informerLibrary.SecureStore[k].OTPAccount()
+	if account.Type != "hotp" {
+		return fmt.Errorf("%s doesn't use HOTP", informerLibrary.SecureStore[k].ID)
+	}
+
+	counter, ok := otp.Resync(account, positional[1], positional[2], *window)
+	if !ok {
+		return fmt.Errorf("codes don't follow each other within %d counters of counter %d", *window, account.Counter)
+	}
+	err = library.SetOTPCounter(&informerLibrary, []byte(key), k, counter)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("counter of", informerLibrary.SecureStore[k].ID, "is now", counter)
+
+	return nil
+}
+
+// runOTPQR Show otpauth URI of secure as QR code in terminal for enrolling another authenticator, or write it as PNG
+func runOTPQR(args []string) error {
+	flagSet := newFlagSet("otp")
+	ansi := flagSet.Bool("ansi", false, "Draw by colors of ANSI escapes instead of Unicode blocks, for light terminals")
+	output := flagSet.String("output", "", "PNG file to write instead of showing the code")
+	scale := flagSet.Int("scale", 8, "Pixels per module of PNG")
+	positional := parseFlags(flagSet, args)
+	if len(positional) != 1 {
+		flagSet.Usage()
+		return errors.New("secure is needed")
+	}
+
+	informerLibrary, err := unlockLibrary()
+	if err != nil {
+		return err
+	}
+	defer informerLibrary.Wipe()
+
+	k, err := findSecure(informerLibrary, positional[0])
+	if err != nil {
+		return err
+	}
+	secure := informerLibrary.SecureStore[k]
+	if len(secure.OTP) == 0 {
+		return fmt.Errorf("%s has no OTP", secure.ID)
+	}
+	if !otp.HasURI(secure.OTPType) {
+		return fmt.Errorf("OTP of %s can't be given by QR code, enter its secret instead", secure.ID)
+	}
+	uri := secure.OTPAccount().URI()
+
+	if *output == "" {
+		code, err := qr.Terminal(uri, *ansi)
+		if err != nil {
+			return err
+		}
+		fmt.Print(code)
+
+		return nil
+	}
+
+	img, err := qr.Encode(uri, *scale)
+	if err != nil {
+		return err
+	}
+	//The code holds the secret, so the file is only readable by its owner
+	file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(file, img)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// findSecure Primary key of the secure whose primary key or id is name, ids are matched case-insensitively
+func findSecure(informerLibrary library.InformerLibrary, name string) (string, error) {
+	if _, ok := informerLibrary.SecureStore[name]; ok {
+		return name, nil
+	}
+
+	var found []string
+	for k, secure := range informerLibrary.SecureStore {
+		if strings.EqualFold(secure.ID, name) {
+			found = append(found, k)
+		}
+	}
+	sort.Strings(found)
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no secure has id %q", name)
+	case 1:
+		return found[0], nil
+	}
+
+	return "", fmt.Errorf("several secures have id %q, give a primary key instead: %s", name, strings.Join(found, ", "))
+}
@@ -0,0 +1,247 @@
+// Package aegis reads JSON backups of Aegis Authenticator, plaintext or encrypted by password slots.
+package aegis
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+	"junjie.pro/informer/pkg/library"
+)
+
+// slotPassword Type of slots wrapping the master key by a key derived from password
+const slotPassword = 1
+
+var ErrPassword = errors.New("invalid password or corrupted backup")
+
+// Backup Aegis vault file, Database is the encrypted vault when slots are set
+type Backup struct {
+	Version  int             `json:"version"`
+	Header   Header          `json:"header"`
+	Database json.RawMessage `json:"db"`
+}
+
+type Header struct {
+	Slots  []Slot      `json:"slots"`
+	Params *CipherInfo `json:"params"`
+}
+
+// Slot Master key encrypted by a key of password, biometrics or raw key
+type Slot struct {
+	Type      int        `json:"type"`
+	UUID      string     `json:"uuid"`
+	Key       string     `json:"key"`
+	KeyParams CipherInfo `json:"key_params"`
+	N         int        `json:"n"`
+	R         int        `json:"r"`
+	P         int        `json:"p"`
+	Salt      string     `json:"salt"`
+}
+
+// CipherInfo Nonce and tag of AES-256-GCM in hex
+type CipherInfo struct {
+	Nonce string `json:"nonce"`
+	Tag   string `json:"tag"`
+}
+
+type Database struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+	Groups  []Group `json:"groups"`
+}
+
+type Group struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+type Entry struct {
+	Type   string `json:"type"`
+	UUID   string `json:"uuid"`
+	Name   string `json:"name"`
+	Issuer string `json:"issuer"`
+	Note   string `json:"note"`
+	Icon   string `json:"icon"`
+	//Group is the group name of vault version 2, Groups are UUIDs of groups since version 3
+	Group  string   `json:"group"`
+	Groups []string `json:"groups"`
+	Info   Info     `json:"info"`
+}
+
+type Info struct {
+	Secret  string `json:"secret"`
+	Algo    string `json:"algo"`
+	Digits  int    `json:"digits"`
+	Period  int    `json:"period"`
+	Counter uint64 `json:"counter"`
+	Pin     string `json:"pin"`
+}
+
+// NeedsPassword Whether content is an encrypted backup
+func NeedsPassword(content []byte) bool {
+	var backup Backup
+	if err := json.Unmarshal(content, &backup); err != nil {
+		return false
+	}
+
+	return len(backup.Header.Slots) > 0
+}
+
+// Parse Database of a backup, encrypted backups are decrypted by password
+func Parse(content []byte, password string) (Database, error) {
+	var backup Backup
+	if err := json.Unmarshal(content, &backup); err != nil {
+		return Database{}, err
+	}
+
+	plainText := []byte(backup.Database)
+	if len(backup.Header.Slots) > 0 {
+		var encrypted string
+		if err := json.Unmarshal(backup.Database, &encrypted); err != nil {
+			return Database{}, err
+		}
+
+		var err error
+		plainText, err = backup.Header.decrypt(encrypted, password)
+		if err != nil {
+			return Database{}, err
+		}
+	}
+
+	var database Database
+	if err := json.Unmarshal(plainText, &database); err != nil {
+		return Database{}, err
+	}
+
+	return database, nil
+}
+
+// decrypt Vault encrypted by the master key, which is unwrapped by the first password slot accepting password
+func (header Header) decrypt(encrypted string, password string) ([]byte, error) {
+	if header.Params == nil {
+		return nil, errors.New("cipher parameters of vault are missing")
+	}
+
+	for _, slot := range header.Slots {
+		if slot.Type != slotPassword {
+			continue
+		}
+
+		salt, err := hex.DecodeString(slot.Salt)
+		if err != nil {
+			return nil, err
+		}
+		slotKey, err := scrypt.Key([]byte(password), salt, slot.N, slot.R, slot.P, 32)
+		if err != nil {
+			return nil, err
+		}
+		wrapped, err := hex.DecodeString(slot.Key)
+		if err != nil {
+			return nil, err
+		}
+		masterKey, err := open(slotKey, slot.KeyParams, wrapped)
+		if err != nil {
+			continue
+		}
+
+		vault, err := base64.StdEncoding.DecodeString(encrypted)
+		if err != nil {
+			return nil, err
+		}
+
+		return open(masterKey, *header.Params, vault)
+	}
+
+	return nil, ErrPassword
+}
+
+// open Decrypt AES-256-GCM cipher text, the tag is kept apart from it
+func open(key []byte, info CipherInfo, cipherText []byte) ([]byte, error) {
+	nonce, err := hex.DecodeString(info.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := hex.DecodeString(info.Tag)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aesGCM, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	if err != nil {
+		return nil, err
+	}
+
+	plainText, err := aesGCM.Open(nil, nonce, append(append([]byte{}, cipherText...), tag...), nil)
+	if err != nil {
+		return nil, ErrPassword
+	}
+
+	return plainText, nil
+}
+
+// Secures Entries as secures keyed by entry UUID, and what of them can't be kept.
+// PINs of mOTP and Yandex entries are kept as OTP PINs.
+func (database Database) Secures() (map[string]library.SecureStore, []string) {
+	groups := map[string]string{}
+	for _, group := range database.Groups {
+		groups[group.UUID] = group.Name
+	}
+
+	secures := map[string]library.SecureStore{}
+	var unmapped []string
+	for _, entry := range database.Entries {
+		name := entry.Issuer
+		if name == "" {
+			name = entry.Name
+		}
+		if entry.Info.Secret == "" {
+			unmapped = append(unmapped, name+": entry has no secret")
+			continue
+		}
+
+		secure := library.SecureStore{
+			ID:           name,
+			Platform:     entry.Issuer,
+			FriendlyName: name,
+			Username:     entry.Name,
+			OTP:          library.NewSecret(strings.ToUpper(entry.Info.Secret)),
+			OTPType:      strings.ToLower(entry.Type),
+			OTPIssuer:    entry.Issuer,
+			OTPAlgorithm: strings.ToUpper(entry.Info.Algo),
+			OTPDigits:    entry.Info.Digits,
+			OTPPeriod:    entry.Info.Period,
+			OTPCounter:   entry.Info.Counter,
+			OTPPin:       library.NewSecret(entry.Info.Pin),
+			Folder:       entry.Group,
+		}
+		if entry.Note != "" {
+			secure.Notes = library.NewSecret(entry.Note)
+		}
+
+		//Secures have only one folder, the other groups are reported
+		for i, group := range entry.Groups {
+			if i == 0 {
+				secure.Folder = groups[group]
+				continue
+			}
+			unmapped = append(unmapped, fmt.Sprintf("%s: group %q", name, groups[group]))
+		}
+		if entry.Icon != "" {
+			unmapped = append(unmapped, name+": icon")
+		}
+
+		secures[entry.UUID] = secure
+	}
+
+	return secures, unmapped
+}
@@ -0,0 +1,102 @@
+package aegis
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const database = `{"version":3,"entries":[
+{"type":"totp","uuid":"3ae6f1ad-2e65-4ed2-a953-1ec0dff2386d","name":"octocat","issuer":"GitHub","note":"work",
+"info":{"secret":"jbswy3dpehpk3pxp","algo":"SHA256","digits":8,"period":60},"groups":["a1"]},
+{"type":"hotp","uuid":"9c2b6f43-1c6d-4d05-8b4b-4e8f0a7e1a11","name":"alice","issuer":"ACME",
+"info":{"secret":"ORSXG5A","algo":"SHA1","digits":6,"counter":7}}],
+"groups":[{"uuid":"a1","name":"Work"}]}`
+
+// seal Encrypt plain text by AES-256-GCM, returning cipher text and its parameters
+func seal(t *testing.T, key []byte, plainText []byte) ([]byte, CipherInfo) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, aesGCM.NonceSize())
+	sealed := aesGCM.Seal(nil, nonce, plainText, nil)
+	tagStart := len(sealed) - aesGCM.Overhead()
+
+	return sealed[:tagStart], CipherInfo{Nonce: hex.EncodeToString(nonce), Tag: hex.EncodeToString(sealed[tagStart:])}
+}
+
+func TestParseEncrypted(t *testing.T) {
+	masterKey := []byte("0123456789abcdef0123456789abcdef")
+	salt := []byte("salt of the password slot")
+	slotKey, err := scrypt.Key([]byte("password"), salt, 1024, 8, 1, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, keyParams := seal(t, slotKey, masterKey)
+	vault, params := seal(t, masterKey, []byte(database))
+
+	encryptedVault, _ := json.Marshal(base64.StdEncoding.EncodeToString(vault))
+	backup, _ := json.Marshal(Backup{
+		Version: 1,
+		Header: Header{
+			Slots: []Slot{
+				{Type: 2, Key: "00"},
+				{Type: slotPassword, Key: hex.EncodeToString(wrapped), KeyParams: keyParams, N: 1024, R: 8, P: 1, Salt: hex.EncodeToString(salt)},
+			},
+			Params: &params,
+		},
+		Database: encryptedVault,
+	})
+
+	if !NeedsPassword(backup) {
+		t.Error("encrypted backup needs password")
+	}
+	if _, err := Parse(backup, "wrong"); err != ErrPassword {
+		t.Errorf("expected ErrPassword, got %v", err)
+	}
+
+	parsed, err := Parse(backup, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secures, unmapped := parsed.Secures()
+	if len(secures) != 2 || len(unmapped) != 0 {
+		t.Fatalf("secures %v, unmapped %v", secures, unmapped)
+	}
+
+	github := secures["3ae6f1ad-2e65-4ed2-a953-1ec0dff2386d"]
+	if github.ID != "GitHub" || github.Username != "octocat" || github.OTP.Reveal() != "JBSWY3DPEHPK3PXP" ||
+		github.OTPType != "totp" || github.OTPAlgorithm != "SHA256" || github.OTPDigits != 8 || github.OTPPeriod != 60 ||
+		github.Folder != "Work" || github.Notes.Reveal() != "work" {
+		t.Errorf("unexpected secure %+v", github)
+	}
+	acme := secures["9c2b6f43-1c6d-4d05-8b4b-4e8f0a7e1a11"]
+	if acme.OTPType != "hotp" || acme.OTPCounter != 7 {
+		t.Errorf("unexpected secure %+v", acme)
+	}
+}
+
+func TestParsePlain(t *testing.T) {
+	backup := []byte(`{"version":1,"header":{"slots":null,"params":null},"db":` + database + `}`)
+	if NeedsPassword(backup) {
+		t.Error("plain backup needs no password")
+	}
+
+	parsed, err := Parse(backup, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Entries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(parsed.Entries))
+	}
+}
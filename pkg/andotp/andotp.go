@@ -0,0 +1,164 @@
+// Package andotp reads JSON backups of andOTP, plaintext or encrypted by AES-256-GCM.
+package andotp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/pbkdf2"
+	"junjie.pro/informer/pkg/library"
+)
+
+const (
+	nonceLength = 12
+	saltLength  = 12
+	//Iterations of PBKDF2 are chosen at random between these by andOTP
+	minIterations = 140000
+	maxIterations = 160000
+)
+
+var ErrPassword = errors.New("invalid password or corrupted backup")
+
+// namespace UUIDs of entries, which have no ids, are derived in it
+var namespace = uuid.MustParse("6a5c8b51-3c47-4e36-9d0f-5b9b6f1f7b52")
+
+// Backup Entries of an andOTP backup
+type Backup []Entry
+
+type Entry struct {
+	Secret    string   `json:"secret"`
+	Issuer    string   `json:"issuer"`
+	Label     string   `json:"label"`
+	Digits    int      `json:"digits"`
+	Type      string   `json:"type"`
+	Algorithm string   `json:"algorithm"`
+	Period    int      `json:"period"`
+	Counter   uint64   `json:"counter"`
+	Tags      []string `json:"tags"`
+	Thumbnail string   `json:"thumbnail"`
+}
+
+// NeedsPassword Whether content is an encrypted backup rather than a JSON array
+func NeedsPassword(content []byte) bool {
+	return !bytes.HasPrefix(bytes.TrimSpace(content), []byte("["))
+}
+
+// Parse Entries of a backup, encrypted backups are decrypted by password.
+// Encrypted backups are iterations, salt, nonce and cipher text, or nonce and cipher text
+// by a key of SHA-256 of password for backups before andOTP 0.6.3.
+func Parse(content []byte, password string) (Backup, error) {
+	plainText := content
+	if NeedsPassword(content) {
+		var err error
+		plainText, err = decrypt(content, password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var backup Backup
+	if err := json.Unmarshal(plainText, &backup); err != nil {
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+func decrypt(content []byte, password string) ([]byte, error) {
+	if len(content) > 4+saltLength+nonceLength {
+		iterations := int(binary.BigEndian.Uint32(content))
+		if iterations >= minIterations && iterations <= maxIterations {
+			salt := content[4 : 4+saltLength]
+			key := pbkdf2.Key([]byte(password), salt, iterations, 32, sha1.New)
+			if plainText, err := open(key, content[4+saltLength:]); err == nil {
+				return plainText, nil
+			}
+		}
+	}
+
+	key := sha256.Sum256([]byte(password))
+
+	return open(key[:], content)
+}
+
+// open Decrypt AES-256-GCM cipher text following its nonce
+func open(key []byte, sealed []byte) ([]byte, error) {
+	if len(sealed) < nonceLength {
+		return nil, ErrPassword
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plainText, err := aesGCM.Open(nil, sealed[:nonceLength], sealed[nonceLength:], nil)
+	if err != nil {
+		return nil, ErrPassword
+	}
+
+	return plainText, nil
+}
+
+// Secures Entries as secures keyed by UUIDs derived from their type, issuer and label,
+// so importing a backup again updates the same secures. The first tag becomes the folder.
+func (backup Backup) Secures() (map[string]library.SecureStore, []string) {
+	secures := map[string]library.SecureStore{}
+	var unmapped []string
+	for _, entry := range backup {
+		issuer, username := entry.Issuer, entry.Label
+		//Labels of older backups hold the issuer
+		if i := strings.Index(username, ":"); i >= 0 && (issuer == "" || strings.EqualFold(username[:i], issuer)) {
+			if issuer == "" {
+				issuer = strings.TrimSpace(username[:i])
+			}
+			username = strings.TrimSpace(username[i+1:])
+		}
+		name := issuer
+		if name == "" {
+			name = username
+		}
+		if entry.Secret == "" {
+			unmapped = append(unmapped, name+": entry has no secret")
+			continue
+		}
+
+		secure := library.SecureStore{
+			ID:           name,
+			Platform:     issuer,
+			FriendlyName: name,
+			Username:     username,
+			OTP:          library.NewSecret(strings.ToUpper(entry.Secret)),
+			OTPType:      strings.ToLower(entry.Type),
+			OTPIssuer:    issuer,
+			OTPAlgorithm: strings.ToUpper(entry.Algorithm),
+			OTPDigits:    entry.Digits,
+			OTPPeriod:    entry.Period,
+			OTPCounter:   entry.Counter,
+		}
+		for i, tag := range entry.Tags {
+			if i == 0 {
+				secure.Folder = tag
+				continue
+			}
+			unmapped = append(unmapped, name+": tag "+tag)
+		}
+
+		k := uuid.NewSHA1(namespace, []byte(secure.OTPType+"\x00"+entry.Issuer+"\x00"+entry.Label)).String()
+		secures[k] = secure
+	}
+
+	return secures, unmapped
+}
@@ -0,0 +1,70 @@
+package andotp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const entries = `[
+{"secret":"jbswy3dpehpk3pxp","issuer":"GitHub","label":"octocat","digits":8,"type":"TOTP","algorithm":"SHA512","period":60,"tags":["Work","Code"]},
+{"secret":"ORSXG5A","label":"ACME:alice","digits":6,"type":"HOTP","algorithm":"SHA1","counter":3}]`
+
+func seal(t *testing.T, key []byte, plainText []byte) []byte {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, nonceLength)
+
+	return aesGCM.Seal(nonce, nonce, plainText, nil)
+}
+
+func TestParse(t *testing.T) {
+	salt := []byte("twelve bytes")
+	backup := make([]byte, 4)
+	binary.BigEndian.PutUint32(backup, minIterations)
+	backup = append(backup, salt...)
+	backup = append(backup, seal(t, pbkdf2.Key([]byte("password"), salt, minIterations, 32, sha1.New), []byte(entries))...)
+
+	oldKey := sha256.Sum256([]byte("password"))
+	for _, content := range [][]byte{[]byte(entries), backup, seal(t, oldKey[:], []byte(entries))} {
+		parsed, err := Parse(content, "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		secures, unmapped := parsed.Secures()
+		if len(secures) != 2 || len(unmapped) != 1 {
+			t.Fatalf("secures %v, unmapped %v", secures, unmapped)
+		}
+		for _, secure := range secures {
+			switch secure.ID {
+			case "GitHub":
+				if secure.Username != "octocat" || secure.OTP.Reveal() != "JBSWY3DPEHPK3PXP" || secure.OTPType != "totp" ||
+					secure.OTPAlgorithm != "SHA512" || secure.OTPDigits != 8 || secure.OTPPeriod != 60 || secure.Folder != "Work" {
+					t.Errorf("unexpected secure %+v", secure)
+				}
+			case "ACME":
+				if secure.Username != "alice" || secure.OTPType != "hotp" || secure.OTPCounter != 3 {
+					t.Errorf("unexpected secure %+v", secure)
+				}
+			default:
+				t.Errorf("unexpected secure %+v", secure)
+			}
+		}
+	}
+
+	if _, err := Parse(backup, "wrong"); err != ErrPassword {
+		t.Errorf("expected ErrPassword, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+// Package backup writes and reads portable backups of informer. A backup is a JSON archive describing how it's encrypted,
+// its contents are files encrypted by AES-256-GCM with a key derived from a passphrase by Argon2id.
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	Format  = "informer-backup"
+	Version = 1
+
+	cipherAESGCM = "aes-256-gcm"
+	kdfArgon2id  = "argon2id"
+)
+
+var ErrPassphrase = errors.New("invalid passphrase or corrupted backup")
+
+// Header Everything needed to decrypt a backup, it's authenticated along with the contents
+type Header struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	KDF     KDF       `json:"kdf"`
+	Cipher  string    `json:"cipher"`
+	Nonce   []byte    `json:"nonce"`
+}
+
+// KDF Argon2id parameters, memory is in KiB
+type KDF struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	Time      uint32 `json:"time"`
+	Memory    uint32 `json:"memory"`
+	Threads   uint8  `json:"threads"`
+}
+
+// Archive Header and encrypted contents
+type Archive struct {
+	Header
+	Contents []byte `json:"contents"`
+}
+
+// File File in backup, SHA256 is checked before restoring it
+type File struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Data   []byte `json:"data"`
+}
+
+type contents struct {
+	Files []File `json:"files"`
+}
+
+// Create Archive of files encrypted by passphrase
+func Create(files []File, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase is needed")
+	}
+
+	archive := Archive{Header: Header{
+		Format:  Format,
+		Version: Version,
+		Created: time.Now().UTC().Truncate(time.Second),
+		KDF:     KDF{Algorithm: kdfArgon2id, Salt: make([]byte, 16), Time: 3, Memory: 64 * 1024, Threads: 4},
+		Cipher:  cipherAESGCM,
+		Nonce:   make([]byte, 12),
+	}}
+	if _, err := io.ReadFull(rand.Reader, archive.KDF.Salt); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, archive.Nonce); err != nil {
+		return nil, err
+	}
+
+	plain := contents{Files: make([]File, len(files))}
+	for i, file := range files {
+		digest := sha256.Sum256(file.Data)
+		plain.Files[i] = File{Name: file.Name, SHA256: hex.EncodeToString(digest[:]), Data: file.Data}
+	}
+	plainText, err := json.Marshal(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	aesGCM, additionalData, err := archive.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	archive.Contents = aesGCM.Seal(nil, archive.Nonce, plainText, additionalData)
+
+	return json.MarshalIndent(archive, "", "  ")
+}
+
+// Open Files of archive decrypted by passphrase, after checking integrity of archive and every file
+func Open(content []byte, passphrase string) (Header, []File, error) {
+	var archive Archive
+	if err := json.Unmarshal(content, &archive); err != nil {
+		return Header{}, nil, err
+	}
+	if archive.Format != Format {
+		return Header{}, nil, errors.New("not an informer backup")
+	}
+	if archive.Version > Version {
+		return Header{}, nil, fmt.Errorf("backup version %d is newer than supported", archive.Version)
+	}
+	if archive.Cipher != cipherAESGCM || archive.KDF.Algorithm != kdfArgon2id {
+		return Header{}, nil, errors.New("unsupported cipher or KDF")
+	}
+
+	aesGCM, additionalData, err := archive.cipher(passphrase)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	if len(archive.Nonce) != aesGCM.NonceSize() {
+		return Header{}, nil, ErrPassphrase
+	}
+	plainText, err := aesGCM.Open(nil, archive.Nonce, archive.Contents, additionalData)
+	if err != nil {
+		return Header{}, nil, ErrPassphrase
+	}
+
+	var plain contents
+	if err := json.Unmarshal(plainText, &plain); err != nil {
+		return Header{}, nil, err
+	}
+	for _, file := range plain.Files {
+		digest := sha256.Sum256(file.Data)
+		if hex.EncodeToString(digest[:]) != file.SHA256 {
+			return Header{}, nil, fmt.Errorf("checksum of %s mismatches", file.Name)
+		}
+	}
+
+	return archive.Header, plain.Files, nil
+}
+
+// cipher AES-GCM of key derived from passphrase, and header as its additional data
+func (archive Archive) cipher(passphrase string) (cipher.AEAD, []byte, error) {
+	kdf := archive.KDF
+	if kdf.Time == 0 || kdf.Memory == 0 || kdf.Threads == 0 {
+		return nil, nil, errors.New("invalid KDF parameters")
+	}
+	key := argon2.IDKey([]byte(passphrase), kdf.Salt, kdf.Time, kdf.Memory, kdf.Threads, 32)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	additionalData, err := json.Marshal(archive.Header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return aesGCM, additionalData, nil
+}
@@ -0,0 +1,37 @@
+package backup
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCreateOpen(t *testing.T) {
+	files := []File{{Name: LibraryFile, Data: []byte("libraries: {}\n")}, {Name: UserFile, Data: []byte("username: admin\n")}}
+	archive, err := Create(files, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, opened, err := Open(archive, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Format != Format || len(opened) != 2 || string(opened[1].Data) != "username: admin\n" {
+		t.Errorf("unexpected backup %+v %+v", header, opened)
+	}
+
+	if _, _, err := Open(archive, "wrong"); err != ErrPassphrase {
+		t.Errorf("expected ErrPassphrase, got %v", err)
+	}
+
+	//Header is authenticated along with contents
+	var tampered Archive
+	if err := json.Unmarshal(archive, &tampered); err != nil {
+		t.Fatal(err)
+	}
+	tampered.Created = tampered.Created.Add(1)
+	content, _ := json.Marshal(tampered)
+	if _, _, err := Open(content, "correct horse"); err != ErrPassphrase {
+		t.Errorf("expected ErrPassphrase of tampered header, got %v", err)
+	}
+}
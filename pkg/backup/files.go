@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+	"junjie.pro/informer/conf"
+	"junjie.pro/informer/pkg/library"
+)
+
+// Names of files in backups
+const (
+	LibraryFile = "libraries.yaml"
+	UserFile    = "user.yaml"
+)
+
+// Collect Files of library as it's stored, with its attachments and every secure set.
+// User and login tokens of configuration are included if includeUser.
+func Collect(includeUser bool) ([]File, error) {
+	location, err := library.DataPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+	files := []File{{Name: LibraryFile, Data: data}}
+
+	if includeUser {
+		if !conf.Exists() {
+			return nil, errors.New("configuration file doesn't exist, there is no user to back up")
+		}
+		informerConfig, err := conf.ReadConfig()
+		if err != nil {
+			return nil, err
+		}
+		user, err := yaml.Marshal(informerConfig.User)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: UserFile, Data: user})
+	}
+
+	return files, nil
+}
+
+// Contents Library and user of files, verifying that both are readable
+func Contents(files []File) (library.InformerLibrary, *conf.User, error) {
+	var informerLibrary *library.InformerLibrary
+	var user *conf.User
+	for _, file := range files {
+		switch file.Name {
+		case LibraryFile:
+			parsed, err := library.ParseLibrary(file.Data)
+			if err != nil {
+				return library.InformerLibrary{}, nil, fmt.Errorf("library of backup is unreadable: %w", err)
+			}
+			informerLibrary = &parsed
+		case UserFile:
+			user = &conf.User{}
+			if err := yaml.Unmarshal(file.Data, user); err != nil {
+				return library.InformerLibrary{}, nil, fmt.Errorf("user of backup is unreadable: %w", err)
+			}
+		default:
+			return library.InformerLibrary{}, nil, fmt.Errorf("unknown file %s in backup", file.Name)
+		}
+	}
+	if informerLibrary == nil {
+		return library.InformerLibrary{}, nil, errors.New("backup has no library")
+	}
+
+	return *informerLibrary, user, nil
+}
+
+// Restore Replace library, and user of configuration if user isn't nil
+func Restore(informerLibrary library.InformerLibrary, user *conf.User) error {
+	//Data directory doesn't exist when restoring on another machine
+	location, err := library.DataPath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(location), 0755)
+	if err != nil {
+		return err
+	}
+
+	err = informerLibrary.WriteLibrary()
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	informerConfig := conf.InformerConfig{}
+	if conf.Exists() {
+		informerConfig, err = conf.ReadConfig()
+		if err != nil {
+			return err
+		}
+	}
+	informerConfig.User = *user
+
+	return informerConfig.WriteConfig()
+}
@@ -0,0 +1,140 @@
+// Package bitwarden reads and writes Bitwarden JSON exports, unencrypted or protected by a file password.
+package bitwarden
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const (
+	ItemLogin      = 1
+	ItemSecureNote = 2
+	ItemCard       = 3
+	ItemIdentity   = 4
+
+	FieldText    = 0
+	FieldHidden  = 1
+	FieldBoolean = 2
+	FieldLinked  = 3
+)
+
+// Export Content of a Bitwarden JSON export.
+type Export struct {
+	Encrypted bool     `json:"encrypted"`
+	Folders   []Folder `json:"folders"`
+	Items     []Item   `json:"items"`
+}
+
+// Folder Folder of items.
+type Folder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Item Login, secure note, card or identity. Parts Informer doesn't keep are left raw.
+type Item struct {
+	ID              string             `json:"id"`
+	OrganizationID  *string            `json:"organizationId"`
+	FolderID        *string            `json:"folderId"`
+	Type            int                `json:"type"`
+	Reprompt        int                `json:"reprompt"`
+	Name            string             `json:"name"`
+	Notes           *string            `json:"notes"`
+	Favorite        bool               `json:"favorite"`
+	Fields          []Field            `json:"fields,omitempty"`
+	Login           *Login             `json:"login,omitempty"`
+	Card            map[string]*string `json:"card,omitempty"`
+	Identity        map[string]*string `json:"identity,omitempty"`
+	CollectionIDs   []string           `json:"collectionIds"`
+	PasswordHistory json.RawMessage    `json:"passwordHistory,omitempty"`
+	RevisionDate    string             `json:"revisionDate,omitempty"`
+	CreationDate    string             `json:"creationDate,omitempty"`
+}
+
+// Field Custom field of item.
+type Field struct {
+	Name     string  `json:"name"`
+	Value    *string `json:"value"`
+	Type     int     `json:"type"`
+	LinkedID *int    `json:"linkedId"`
+}
+
+// Login Login part of item.
+type Login struct {
+	URIs             []URI           `json:"uris,omitempty"`
+	Username         *string         `json:"username"`
+	Password         *string         `json:"password"`
+	TOTP             *string         `json:"totp"`
+	Fido2Credentials json.RawMessage `json:"fido2Credentials,omitempty"`
+}
+
+// URI Website of login.
+type URI struct {
+	Match *int   `json:"match"`
+	URI   string `json:"uri"`
+}
+
+// passwordProtected Export encrypted by a file password
+type passwordProtected struct {
+	Encrypted         bool   `json:"encrypted"`
+	PasswordProtected bool   `json:"passwordProtected"`
+	Salt              string `json:"salt"`
+	KdfType           int    `json:"kdfType"`
+	KdfIterations     int    `json:"kdfIterations"`
+	KdfMemory         int    `json:"kdfMemory,omitempty"`
+	KdfParallelism    int    `json:"kdfParallelism,omitempty"`
+	KeyValidation     string `json:"encKeyValidation_DO_NOT_EDIT"`
+	Data              string `json:"data"`
+}
+
+// NeedsPassword Whether content is an export protected by a file password.
+func NeedsPassword(content []byte) bool {
+	protected := passwordProtected{}
+
+	return json.Unmarshal(content, &protected) == nil && protected.Encrypted && protected.PasswordProtected
+}
+
+// Parse Parse export, password is only used for password protected exports.
+func Parse(content []byte, password string) (*Export, error) {
+	protected := passwordProtected{}
+	err := json.Unmarshal(content, &protected)
+	if err != nil {
+		return nil, err
+	}
+
+	if protected.Encrypted {
+		//Exports encrypted by the account key can only be read by the account
+		if !protected.PasswordProtected {
+			return nil, errors.New("export is encrypted by account key, export it again using a file password")
+		}
+
+		content, err = protected.decrypt(password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	export := &Export{}
+	err = json.Unmarshal(content, export)
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// Marshal Serialize export, encrypted by password unless it's empty.
+func (export *Export) Marshal(password string) ([]byte, error) {
+	export.Encrypted = false
+	content, err := json.MarshalIndent(export, "", "  ")
+	if err != nil || password == "" {
+		return content, err
+	}
+
+	protected, err := encryptExport(content, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(protected, "", "  ")
+}
@@ -0,0 +1,110 @@
+package bitwarden
+
+import (
+	"testing"
+
+	"junjie.pro/informer/pkg/library"
+)
+
+const plainExport = `{
+  "encrypted": false,
+  "folders": [{"id": "f0e1d2c3-b4a5-4968-8776-655443322110", "name": "Work"}],
+  "items": [
+    {
+      "id": "1c9a0d4e-2b3f-4a5b-8c6d-7e8f90a1b2c3",
+      "organizationId": null,
+      "folderId": "f0e1d2c3-b4a5-4968-8776-655443322110",
+      "type": 1,
+      "name": "GitHub",
+      "notes": "note",
+      "fields": [
+        {"name": "PIN", "value": "1234", "type": 1, "linkedId": null},
+        {"name": "Login", "value": null, "type": 3, "linkedId": 100}
+      ],
+      "login": {
+        "uris": [{"match": null, "uri": "https://github.com/login"}, {"match": null, "uri": "https://gist.github.com"}],
+        "username": "octocat",
+        "password": "hunter2",
+        "totp": "otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP&issuer=GitHub"
+      },
+      "collectionIds": null,
+      "revisionDate": "2023-01-02T03:04:05.000Z",
+      "creationDate": "2022-01-02T03:04:05.000Z"
+    },
+    {
+      "id": "2d9a0d4e-2b3f-4a5b-8c6d-7e8f90a1b2c3",
+      "type": 3,
+      "name": "Visa",
+      "card": {"cardholderName": "Octo Cat", "number": "4111111111111111", "code": null}
+    }
+  ]
+}`
+
+func TestSecures(t *testing.T) {
+	export, err := Parse([]byte(plainExport), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secures, unmapped := export.Secures()
+	secure := secures["1c9a0d4e-2b3f-4a5b-8c6d-7e8f90a1b2c3"]
+	if secure.Username != "octocat" || secure.Password.Reveal() != "hunter2" || secure.Platform != "github.com" ||
+		secure.Folder != "Work" || secure.OTP.Reveal() != "JBSWY3DPEHPK3PXP" || secure.Created.Year() != 2022 {
+		t.Errorf("unexpected login %+v", secure)
+	}
+	if len(secure.Fields) != 1 || !secure.Fields[0].Protected {
+		t.Errorf("unexpected fields %+v", secure.Fields)
+	}
+
+	card := secures["2d9a0d4e-2b3f-4a5b-8c6d-7e8f90a1b2c3"]
+	if len(card.Fields) != 2 || card.Fields[1].Name != "card.number" || !card.Fields[1].Protected {
+		t.Errorf("unexpected card fields %+v", card.Fields)
+	}
+
+	if len(unmapped) != 2 {
+		t.Errorf("expected linked field and additional URI unmapped, got %v", unmapped)
+	}
+}
+
+func TestPasswordProtected(t *testing.T) {
+	informerLibrary := library.InformerLibrary{Unlocked: true, SecureStore: map[string]*library.SecureStore{
+		"1c9a0d4e-2b3f-4a5b-8c6d-7e8f90a1b2c3": {ID: "GitHub", Username: "octocat", Password: library.NewSecret("hunter2"), Folder: "Work"},
+	}}
+
+	content, err := NewExport(informerLibrary).Marshal("file password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !NeedsPassword(content) {
+		t.Fatal("export should be password protected")
+	}
+
+	_, err = Parse(content, "wrong password")
+	if err != ErrPassword {
+		t.Fatalf("expected ErrPassword, got %v", err)
+	}
+
+	export, err := Parse(content, "file password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secures, _ := export.Secures()
+	secure := secures["1c9a0d4e-2b3f-4a5b-8c6d-7e8f90a1b2c3"]
+	if secure.Password.Reveal() != "hunter2" || secure.Folder != "Work" {
+		t.Errorf("unexpected secure %+v", secure)
+	}
+}
+
+func TestKDFLimits(t *testing.T) {
+	//Crafted parameters are refused before anything is allocated or computed
+	for _, protected := range []passwordProtected{
+		{KdfType: kdfPBKDF2, KdfIterations: maxPBKDF2Iterations + 1},
+		{KdfType: kdfArgon2id, KdfIterations: 3, KdfMemory: 1 << 30, KdfParallelism: 4},
+		{KdfType: kdfArgon2id, KdfIterations: 1 << 30, KdfMemory: 64, KdfParallelism: 4},
+		{KdfType: kdfArgon2id, KdfIterations: 3, KdfMemory: 64, KdfParallelism: 255},
+	} {
+		if _, err := protected.keys("password"); err == nil {
+			t.Errorf("parameters beyond limits accepted: %+v", protected)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package bitwarden
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"junjie.pro/informer/pkg/library"
+	"junjie.pro/informer/pkg/otp"
+)
+
+// sensitive Card and identity properties kept as protected fields
+var sensitive = map[string]bool{"number": true, "code": true, "ssn": true, "passportNumber": true, "licenseNumber": true}
+
+// Secures Items as secures keyed by item id, and what of them can't be kept.
+// Cards and identities become custom fields named after their part, such as "card.number".
+func (export *Export) Secures() (map[string]library.SecureStore, []string) {
+	folders := map[string]string{}
+	for _, folder := range export.Folders {
+		folders[folder.ID] = folder.Name
+	}
+
+	secures := map[string]library.SecureStore{}
+	var unmapped []string
+	for _, item := range export.Items {
+		describe := func(format string, a ...interface{}) {
+			unmapped = append(unmapped, item.Name+": "+fmt.Sprintf(format, a...))
+		}
+
+		if item.Type < ItemLogin || item.Type > ItemIdentity {
+			describe("item type %d is not supported", item.Type)
+			continue
+		}
+
+		secure := library.SecureStore{
+			ID:           item.Name,
+			Platform:     item.Name,
+			FriendlyName: item.Name,
+			Notes:        library.NewSecret(value(item.Notes)),
+			Created:      parseTime(item.CreationDate),
+			Modified:     parseTime(item.RevisionDate),
+		}
+		if item.FolderID != nil {
+			secure.Folder = folders[*item.FolderID]
+		}
+
+		if login := item.Login; login != nil {
+			secure.Username = value(login.Username)
+			secure.Password = library.NewSecret(value(login.Password))
+			for i, uri := range login.URIs {
+				if i > 0 {
+					describe("additional URI %s", uri.URI)
+					continue
+				}
+
+				secure.URL = uri.URI
+				if u, err := url.Parse(secure.URL); err == nil && u.Hostname() != "" {
+					secure.Platform = u.Hostname()
+				}
+			}
+			if totp := value(login.TOTP); totp != "" {
+				setTOTP(&secure, totp)
+			}
+			if present(login.Fido2Credentials) {
+				describe("passkeys")
+			}
+		}
+
+		secure.Fields = append(secure.Fields, propertyFields("card", item.Card)...)
+		secure.Fields = append(secure.Fields, propertyFields("identity", item.Identity)...)
+		for _, field := range item.Fields {
+			if field.Type == FieldLinked {
+				describe("linked field %s", field.Name)
+				continue
+			}
+			secure.Fields = append(secure.Fields, library.Field{
+				Name:      field.Name,
+				Value:     library.NewSecret(value(field.Value)),
+				Protected: field.Type == FieldHidden,
+			})
+		}
+
+		if present(item.PasswordHistory) {
+			describe("password history")
+		}
+		if item.OrganizationID != nil || len(item.CollectionIDs) > 0 {
+			describe("organization and collections")
+		}
+
+		k := item.ID
+		if _, err := uuid.Parse(k); err != nil {
+			k = uuid.NewString()
+		}
+		secures[k] = secure
+	}
+
+	return secures, unmapped
+}
+
+// NewExport Export holding every secure of an unlocked library. Secures become logins, folders are kept.
+// Attachments can't be exported.
+func NewExport(informerLibrary library.InformerLibrary) *Export {
+	export := &Export{Folders: []Folder{}, Items: []Item{}}
+	folderIDs := map[string]string{}
+
+	//Sorted, so exporting the same library gives the same order
+	keys := make([]string, 0, len(informerLibrary.SecureStore))
+	for k := range informerLibrary.SecureStore {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		secure := informerLibrary.SecureStore[k]
+		name := secure.FriendlyName
+		if name == "" {
+			name = secure.ID
+		}
+
+		item := Item{
+			ID:           k,
+			Type:         ItemLogin,
+			Name:         name,
+			Notes:        pointer(secure.Notes.Reveal()),
+			Login:        &Login{Username: pointer(secure.Username), Password: pointer(secure.Password.Reveal())},
+			CreationDate: formatTime(secure.Created),
+			RevisionDate: formatTime(secure.Modified),
+		}
+		if secure.URL != "" {
+			item.Login.URIs = []URI{{URI: secure.URL}}
+		}
+		if len(secure.OTP) > 0 {
+			item.Login.TOTP = pointer(totpValue(secure))
+		}
+		for _, field := range secure.Fields {
+			fieldType := FieldText
+			if field.Protected {
+				fieldType = FieldHidden
+			}
+			item.Fields = append(item.Fields, Field{Name: field.Name, Value: pointer(field.Value.Reveal()), Type: fieldType})
+		}
+
+		if secure.Folder != "" {
+			if _, ok := folderIDs[secure.Folder]; !ok {
+				folderIDs[secure.Folder] = uuid.NewString()
+				export.Folders = append(export.Folders, Folder{ID: folderIDs[secure.Folder], Name: secure.Folder})
+			}
+			item.FolderID = pointer(folderIDs[secure.Folder])
+		}
+
+		export.Items = append(export.Items, item)
+	}
+
+	return export
+}
+
+// setTOTP Keep secret and parameters of an otpauth URI, other values such as plain secrets are kept as they are
+func setTOTP(secure *library.SecureStore, totp string) {
+	if strings.HasPrefix(totp, "otpauth://") {
+		if accounts, err := otp.ParseURI(totp); err == nil {
+			secure.SetOTPAccount(accounts[0])
+			return
+		}
+	}
+	if strings.HasPrefix(totp, "steam://") {
+		secure.OTP, secure.OTPType = library.NewSecret(strings.TrimPrefix(totp, "steam://")), "steam"
+		return
+	}
+
+	secure.OTP, secure.OTPType = library.NewSecret(strings.ToUpper(strings.ReplaceAll(totp, " ", ""))), "totp"
+}
+
+// totpValue TOTP of item, an otpauth URI if parameters aren't defaults
+func totpValue(secure *library.SecureStore) string {
+	switch {
+	case secure.OTPType == "steam":
+		return "steam://" + secure.OTP.Reveal()
+	case (secure.OTPType != "" && secure.OTPType != "totp") || (secure.OTPAlgorithm != "" && secure.OTPAlgorithm != "SHA1") ||
+		(secure.OTPDigits != 0 && secure.OTPDigits != 6) || (secure.OTPPeriod != 0 && secure.OTPPeriod != 30):
+		return secure.OTPAccount().URI()
+	}
+
+	return secure.OTP.Reveal()
+}
+
+// propertyFields Non empty properties of a card or identity, sorted by name
+func propertyFields(prefix string, properties map[string]*string) []library.Field {
+	var names []string
+	for name, v := range properties {
+		if value(v) != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var fields []library.Field
+	for _, name := range names {
+		fields = append(fields, library.Field{
+			Name:      prefix + "." + name,
+			Value:     library.NewSecret(*properties[name]),
+			Protected: sensitive[name],
+		})
+	}
+
+	return fields
+}
+
+func parseTime(text string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return parsed.UTC().Truncate(time.Second)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+func pointer(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+// present Whether a raw list holds anything
+func present(raw json.RawMessage) bool {
+	trimmed := strings.TrimSpace(string(raw))
+
+	return trimmed != "" && trimmed != "null" && trimmed != "[]"
+}
@@ -0,0 +1,200 @@
+package bitwarden
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	kdfPBKDF2   = 0
+	kdfArgon2id = 1
+
+	//Defaults of Bitwarden clients
+	defaultIterations = 600000
+
+	//Limits of Bitwarden server, exports are untrusted input and must not take unbounded memory or time
+	maxPBKDF2Iterations  = 2000000
+	maxArgon2Iterations  = 10
+	maxArgon2Memory      = 1024 //MiB
+	maxArgon2Parallelism = 16
+
+	//Type of encrypted strings using AES-256-CBC with HMAC-SHA256
+	encTypeAESHMAC = "2"
+)
+
+var ErrPassword = errors.New("invalid password or corrupted export")
+
+// keys Encryption and MAC key stretched from master key
+type keys struct {
+	encryption []byte
+	mac        []byte
+}
+
+func (protected passwordProtected) keys(password string) (keys, error) {
+	var masterKey []byte
+	switch protected.KdfType {
+	case kdfPBKDF2:
+		if protected.KdfIterations <= 0 {
+			return keys{}, errors.New("invalid KDF iterations")
+		}
+		if protected.KdfIterations > maxPBKDF2Iterations {
+			return keys{}, errors.New("KDF iterations exceed limits")
+		}
+		masterKey = pbkdf2.Key([]byte(password), []byte(protected.Salt), protected.KdfIterations, 32, sha256.New)
+	case kdfArgon2id:
+		if protected.KdfIterations <= 0 || protected.KdfMemory <= 0 || protected.KdfParallelism <= 0 {
+			return keys{}, errors.New("invalid KDF parameters")
+		}
+		if protected.KdfIterations > maxArgon2Iterations || protected.KdfMemory > maxArgon2Memory ||
+			protected.KdfParallelism > maxArgon2Parallelism {
+			return keys{}, errors.New("KDF parameters exceed limits")
+		}
+		salt := sha256.Sum256([]byte(protected.Salt))
+		masterKey = argon2.IDKey([]byte(password), salt[:], uint32(protected.KdfIterations),
+			uint32(protected.KdfMemory)*1024, uint8(protected.KdfParallelism), 32)
+	default:
+		return keys{}, errors.New("unsupported KDF")
+	}
+
+	return stretch(masterKey)
+}
+
+// stretch Expand master key to encryption and MAC keys by HKDF
+func stretch(masterKey []byte) (keys, error) {
+	stretched := keys{encryption: make([]byte, 32), mac: make([]byte, 32)}
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, masterKey, []byte("enc")), stretched.encryption); err != nil {
+		return keys{}, err
+	}
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, masterKey, []byte("mac")), stretched.mac); err != nil {
+		return keys{}, err
+	}
+
+	return stretched, nil
+}
+
+func (protected passwordProtected) decrypt(password string) ([]byte, error) {
+	stretched, err := protected.keys(password)
+	if err != nil {
+		return nil, err
+	}
+
+	//Validation fails for a wrong password before the data is touched
+	if _, err := stretched.decryptString(protected.KeyValidation); err != nil {
+		return nil, err
+	}
+
+	return stretched.decryptString(protected.Data)
+}
+
+func encryptExport(content []byte, password string) (passwordProtected, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return passwordProtected{}, err
+	}
+
+	protected := passwordProtected{
+		Encrypted:         true,
+		PasswordProtected: true,
+		Salt:              base64.StdEncoding.EncodeToString(salt),
+		KdfType:           kdfPBKDF2,
+		KdfIterations:     defaultIterations,
+	}
+	stretched, err := protected.keys(password)
+	if err != nil {
+		return passwordProtected{}, err
+	}
+
+	protected.KeyValidation, err = stretched.encryptString([]byte(uuid.NewString()))
+	if err != nil {
+		return passwordProtected{}, err
+	}
+	protected.Data, err = stretched.encryptString(content)
+	if err != nil {
+		return passwordProtected{}, err
+	}
+
+	return protected, nil
+}
+
+// decryptString Decrypt encrypted string formatted as "2.iv|data|mac"
+func (k keys) decryptString(encrypted string) ([]byte, error) {
+	if !strings.HasPrefix(encrypted, encTypeAESHMAC+".") {
+		return nil, errors.New("unsupported encrypted string")
+	}
+	parts := strings.Split(strings.TrimPrefix(encrypted, encTypeAESHMAC+"."), "|")
+	if len(parts) != 3 {
+		return nil, errors.New("unsupported encrypted string")
+	}
+
+	var decoded [3][]byte
+	for i, part := range parts {
+		var err error
+		decoded[i], err = base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			return nil, err
+		}
+	}
+	iv, data, mac := decoded[0], decoded[1], decoded[2]
+
+	if !hmac.Equal(k.computeMAC(iv, data), mac) {
+		return nil, ErrPassword
+	}
+	if len(iv) != aes.BlockSize || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, ErrPassword
+	}
+
+	block, err := aes.NewCipher(k.encryption)
+	if err != nil {
+		return nil, err
+	}
+	plainText := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plainText, data)
+
+	padding := int(plainText[len(plainText)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, ErrPassword
+	}
+
+	return plainText[:len(plainText)-padding], nil
+}
+
+func (k keys) encryptString(plainText []byte) (string, error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(k.encryption)
+	if err != nil {
+		return "", err
+	}
+	padding := aes.BlockSize - len(plainText)%aes.BlockSize
+	padded := append(append([]byte{}, plainText...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	data := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, padded)
+
+	return encTypeAESHMAC + "." + base64.StdEncoding.EncodeToString(iv) + "|" +
+		base64.StdEncoding.EncodeToString(data) + "|" +
+		base64.StdEncoding.EncodeToString(k.computeMAC(iv, data)), nil
+}
+
+func (k keys) computeMAC(iv []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, k.mac)
+	mac.Write(iv)
+	mac.Write(data)
+
+	return mac.Sum(nil)
+}
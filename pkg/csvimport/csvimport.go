@@ -0,0 +1,327 @@
+// Package csvimport reads secures from CSV files of browsers and password managers by column mapping profiles,
+// and writes secures as CSV files read back by the informer profile.
+package csvimport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"junjie.pro/informer/pkg/library"
+	"junjie.pro/informer/pkg/otp"
+)
+
+// FieldPrefix Targets starting with it send a column to a custom field, such as "field:PIN".
+const FieldPrefix = "field:"
+
+// Profile Column name to target, targets are yaml names of SecureStore fields such as "username" and "otp",
+// or FieldPrefix followed by the name of a custom field. Column names are matched case-insensitively.
+type Profile map[string]string
+
+// Profiles Built-in profiles.
+var Profiles = map[string]Profile{
+	"chrome": {
+		"name":     "friendly-name",
+		"url":      "url",
+		"username": "username",
+		"password": "password",
+		"note":     "notes",
+	},
+	"firefox": {
+		"url":                 "url",
+		"username":            "username",
+		"password":            "password",
+		"timeCreated":         "created",
+		"timePasswordChanged": "modified",
+	},
+	"lastpass": {
+		"url":      "url",
+		"username": "username",
+		"password": "password",
+		"totp":     "otp",
+		"extra":    "notes",
+		"name":     "friendly-name",
+		"grouping": "folder",
+	},
+	"1password": {
+		"Title":    "friendly-name",
+		"Url":      "url",
+		"Username": "username",
+		"Password": "password",
+		"OTPAuth":  "otp",
+		"Notes":    "notes",
+	},
+}
+
+// Columns Columns written by Write, named after their targets
+var Columns = []string{"id", "platform", "friendly-name", "username", "password", "otp", "otp-type", "otp-issuer",
+	"otp-algorithm", "otp-digits", "otp-period", "otp-counter", "otp-pin", "url", "folder", "notes", "created", "modified"}
+
+var targets = map[string]bool{}
+
+func init() {
+	informer := Profile{}
+	for _, column := range Columns {
+		targets[column] = true
+		informer[column] = column
+	}
+	Profiles["informer"] = informer
+}
+
+// Validate Check every target of profile is known.
+func (profile Profile) Validate() error {
+	for column, target := range profile {
+		if !targets[target] && !(strings.HasPrefix(target, FieldPrefix) && len(target) > len(FieldPrefix)) {
+			return fmt.Errorf("column %s: unknown target %q", column, target)
+		}
+	}
+
+	return nil
+}
+
+// Detect Name of the profile whose columns are all in header, the one with most columns wins.
+func Detect(header []string, profiles map[string]Profile) (string, bool) {
+	present := map[string]bool{}
+	for _, column := range header {
+		present[strings.ToLower(strings.TrimSpace(column))] = true
+	}
+
+	//Sorted, so ties are broken the same way every time
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	detected := ""
+	for _, name := range names {
+		matched := true
+		for column := range profiles[name] {
+			if !present[strings.ToLower(column)] {
+				matched = false
+				break
+			}
+		}
+		if matched && (detected == "" || len(profiles[name]) > len(profiles[detected])) {
+			detected = name
+		}
+	}
+
+	return detected, detected != ""
+}
+
+// Header Read header of CSV file.
+func Header(r io.Reader) ([]string, error) {
+	return csv.NewReader(r).Read()
+}
+
+// Read Secures of every row keyed by new primary keys, and columns the profile doesn't map.
+func Read(r io.Reader, profile Profile) (map[string]library.SecureStore, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lowerProfile := map[string]string{}
+	for column, target := range profile {
+		lowerProfile[strings.ToLower(column)] = target
+	}
+	columnTargets := make([]string, len(header))
+	var unmapped []string
+	for i, column := range header {
+		columnTargets[i] = lowerProfile[strings.ToLower(strings.TrimSpace(column))]
+		//Custom field columns as written by Write need no mapping
+		if columnTargets[i] == "" && strings.HasPrefix(column, FieldPrefix) && len(column) > len(FieldPrefix) {
+			columnTargets[i] = column
+		}
+		if columnTargets[i] == "" {
+			unmapped = append(unmapped, "column "+column)
+		}
+	}
+
+	secures := map[string]library.SecureStore{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		secure := library.SecureStore{}
+		for i, value := range record {
+			if i < len(columnTargets) && value != "" {
+				setTarget(&secure, columnTargets[i], value)
+			}
+		}
+		fillDefaults(&secure)
+		secures[uuid.NewString()] = secure
+	}
+
+	return secures, unmapped, nil
+}
+
+// Write Write secures sorted by primary key under Columns, custom fields follow in columns of FieldPrefix and their names.
+// Attachments and whether fields are protected aren't written.
+func Write(w io.Writer, secures map[string]library.SecureStore) error {
+	keys := make([]string, 0, len(secures))
+	fieldNames := map[string]bool{}
+	for k, secure := range secures {
+		keys = append(keys, k)
+		for _, field := range secure.Fields {
+			fieldNames[field.Name] = true
+		}
+	}
+	sort.Strings(keys)
+	var fieldColumns []string
+	for name := range fieldNames {
+		fieldColumns = append(fieldColumns, name)
+	}
+	sort.Strings(fieldColumns)
+
+	writer := csv.NewWriter(w)
+	header := append([]string{}, Columns...)
+	for _, name := range fieldColumns {
+		header = append(header, FieldPrefix+name)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		secure := secures[k]
+		record := []string{secure.ID, secure.Platform, secure.FriendlyName, secure.Username, secure.Password.Reveal(),
+			secure.OTP.Reveal(), secure.OTPType, secure.OTPIssuer, secure.OTPAlgorithm, formatNumber(uint64(secure.OTPDigits)),
+			formatNumber(uint64(secure.OTPPeriod)), formatNumber(secure.OTPCounter), secure.OTPPin.Reveal(), secure.URL, secure.Folder,
+			secure.Notes.Reveal(), formatTime(secure.Created), formatTime(secure.Modified)}
+		for _, name := range fieldColumns {
+			value := ""
+			for _, field := range secure.Fields {
+				if field.Name == name {
+					value = field.Value.Reveal()
+					break
+				}
+			}
+			record = append(record, value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func formatNumber(number uint64) string {
+	if number == 0 {
+		return ""
+	}
+
+	return strconv.FormatUint(number, 10)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func setTarget(secure *library.SecureStore, target string, value string) {
+	switch target {
+	case "id":
+		secure.ID = value
+	case "platform":
+		secure.Platform = value
+	case "friendly-name":
+		secure.FriendlyName = value
+	case "username":
+		secure.Username = value
+	case "password":
+		secure.Password = library.NewSecret(value)
+	case "otp":
+		if accounts, err := otp.ParseURI(value); err == nil && strings.HasPrefix(value, "otpauth://") {
+			secure.SetOTPAccount(accounts[0])
+		} else {
+			secure.OTP = library.NewSecret(value)
+		}
+	case "otp-type":
+		secure.OTPType = value
+	case "otp-issuer":
+		secure.OTPIssuer = value
+	case "otp-algorithm":
+		secure.OTPAlgorithm = strings.ToUpper(value)
+	case "otp-digits":
+		secure.OTPDigits, _ = strconv.Atoi(value)
+	case "otp-period":
+		secure.OTPPeriod, _ = strconv.Atoi(value)
+	case "otp-counter":
+		secure.OTPCounter, _ = strconv.ParseUint(value, 10, 64)
+	case "otp-pin":
+		secure.OTPPin = library.NewSecret(value)
+	case "url":
+		secure.URL = value
+	case "folder":
+		secure.Folder = strings.ReplaceAll(value, "\\", "/")
+	case "notes":
+		secure.Notes = library.NewSecret(value)
+	case "created":
+		secure.Created = parseTime(value)
+	case "modified":
+		secure.Modified = parseTime(value)
+	default:
+		if strings.HasPrefix(target, FieldPrefix) {
+			secure.Fields = append(secure.Fields, library.Field{Name: strings.TrimPrefix(target, FieldPrefix), Value: library.NewSecret(value)})
+		}
+	}
+}
+
+// fillDefaults Fill names missing in the file, from each other or the site
+func fillDefaults(secure *library.SecureStore) {
+	host := ""
+	if u, err := url.Parse(secure.URL); err == nil {
+		host = u.Hostname()
+	}
+
+	if secure.Platform == "" {
+		secure.Platform = host
+	}
+	if secure.FriendlyName == "" {
+		secure.FriendlyName = secure.Platform
+	}
+	if secure.ID == "" {
+		secure.ID = secure.FriendlyName
+	}
+	if secure.Platform == "" {
+		secure.Platform = secure.ID
+	}
+	if len(secure.OTP) > 0 && secure.OTPType == "" {
+		secure.OTPType = "totp"
+	}
+}
+
+// parseTime Parse milliseconds since epoch as written by Firefox, or common date formats
+func parseTime(value string) time.Time {
+	if milliseconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(milliseconds/1000, 0).UTC()
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC()
+		}
+	}
+
+	return time.Time{}
+}
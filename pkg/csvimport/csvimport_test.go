@@ -0,0 +1,81 @@
+package csvimport
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"junjie.pro/informer/pkg/library"
+)
+
+func TestRead(t *testing.T) {
+	content := "url,username,password,totp,extra,name,grouping,fav\n" +
+		"https://github.com/login,octocat,hunter2,JBSWY3DPEHPK3PXP,note,GitHub,Work\\Code,1\n"
+
+	header, err := Header(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, ok := Detect(header, Profiles)
+	if !ok || name != "lastpass" {
+		t.Fatalf("expected lastpass profile, got %q", name)
+	}
+
+	secures, unmapped, err := Read(strings.NewReader(content), Profiles[name])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secures) != 1 || len(unmapped) != 1 || unmapped[0] != "column fav" {
+		t.Fatalf("unexpected result %v %v", secures, unmapped)
+	}
+	for _, secure := range secures {
+		if secure.ID != "GitHub" || secure.Platform != "github.com" || secure.Folder != "Work/Code" ||
+			secure.Password.Reveal() != "hunter2" || secure.OTPType != "totp" || secure.Notes.Reveal() != "note" {
+			t.Errorf("unexpected secure %+v", secure)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (Profile{"PIN": "field:PIN", "site": "url"}).Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (Profile{"site": "website"}).Validate(); err == nil {
+		t.Error("unknown target should be rejected")
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	secures := map[string]library.SecureStore{
+		"a": {ID: "GitHub", Platform: "GitHub", FriendlyName: "GitHub", Username: "octocat", Password: library.NewSecret("p,\"w"),
+			OTP: library.NewSecret("JBSWY3DPEHPK3PXP"), OTPType: "totp", OTPDigits: 8, URL: "https://github.com",
+			Notes: library.NewSecret("line\nbreak"), Fields: []library.Field{{Name: "PIN", Value: library.NewSecret("1234")}},
+			Created: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	var content strings.Builder
+	if err := Write(&content, secures); err != nil {
+		t.Fatal(err)
+	}
+	header, err := Header(strings.NewReader(content.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, ok := Detect(header, Profiles)
+	if !ok || name != "informer" {
+		t.Fatalf("detected %q", name)
+	}
+
+	read, unmapped, err := Read(strings.NewReader(content.String()), Profiles[name])
+	if err != nil || len(read) != 1 || len(unmapped) != 0 {
+		t.Fatalf("read %v, unmapped %v, %v", read, unmapped, err)
+	}
+	for _, secure := range read {
+		expected := secures["a"]
+		if secure.Password.Reveal() != expected.Password.Reveal() || secure.Notes.Reveal() != expected.Notes.Reveal() ||
+			secure.OTPDigits != 8 || !secure.Created.Equal(expected.Created) || len(secure.Fields) != 1 ||
+			secure.Fields[0].Name != "PIN" || secure.Fields[0].Value.Reveal() != "1234" {
+			t.Errorf("unexpected secure %+v", secure)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package diceware generates passphrases of words picked at random from a wordlist, like rolling dice for them.
+// EFF's large wordlist and short wordlist 2.0 are embedded.
+package diceware
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"junjie.pro/informer/pkg/generator"
+)
+
+//go:embed wordlists
+var wordlists embed.FS
+
+// embedded Files of embedded wordlists by name
+var embedded = map[string]string{
+	"large": "wordlists/eff_large_wordlist.txt",
+	"short": "wordlists/eff_short_wordlist_2_0.txt",
+}
+
+// Wordlist Distinct words passphrases are made of
+type Wordlist []string
+
+// Options Options of passphrases. Number and Symbol add a random digit or symbol to the end of a random word.
+type Options struct {
+	Words      int
+	Separator  string
+	Capitalize bool
+	Number     bool
+	Symbol     bool
+}
+
+// Passphrase Generated passphrase and the entropy of how it's generated
+type Passphrase struct {
+	Passphrase string
+	//Entropy is in bits
+	Entropy float64
+}
+
+// DefaultOptions 6 words separated by hyphens, about 77 bits of the large wordlist
+func DefaultOptions() Options {
+	return Options{Words: 6, Separator: "-"}
+}
+
+// Embedded Embedded wordlist by name, large or short
+func Embedded(name string) (Wordlist, error) {
+	file, ok := embedded[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown wordlist %q, embedded ones are large and short", name)
+	}
+	content, err := wordlists.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseWordlist(content)
+}
+
+// ParseWordlist Words of a wordlist file, one per line. Dice rolls before words, as in files of EFF and Diceware,
+// are left out, so are empty lines and duplicates.
+func ParseWordlist(content []byte) (Wordlist, error) {
+	var wordlist Wordlist
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		word := fields[len(fields)-1]
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		wordlist = append(wordlist, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(wordlist) < 2 {
+		return nil, errors.New("wordlist needs 2 distinct words at least")
+	}
+
+	return wordlist, nil
+}
+
+// Generate Generate a passphrase of options from words of wordlist
+func (wordlist Wordlist) Generate(options Options) (Passphrase, error) {
+	if options.Words < 1 || options.Words > 64 {
+		return Passphrase{}, errors.New("words must be between 1 and 64")
+	}
+
+	words := make([]string, options.Words)
+	for i := range words {
+		n, err := generator.Intn(len(wordlist))
+		if err != nil {
+			return Passphrase{}, err
+		}
+		words[i] = wordlist[n]
+		if options.Capitalize {
+			runes := []rune(words[i])
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+	}
+	entropy := float64(options.Words) * math.Log2(float64(len(wordlist)))
+
+	var extras []string
+	if options.Number {
+		extras = append(extras, generator.Digits)
+	}
+	if options.Symbol {
+		extras = append(extras, generator.Symbols)
+	}
+	for _, characters := range extras {
+		i, err := generator.Intn(len(words))
+		if err != nil {
+			return Passphrase{}, err
+		}
+		n, err := generator.Intn(len(characters))
+		if err != nil {
+			return Passphrase{}, err
+		}
+		words[i] += string(characters[n])
+		entropy += math.Log2(float64(len(words))) + math.Log2(float64(len(characters)))
+	}
+
+	return Passphrase{Passphrase: strings.Join(words, options.Separator), Entropy: entropy}, nil
+}
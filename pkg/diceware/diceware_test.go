@@ -0,0 +1,69 @@
+package diceware
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEmbedded(t *testing.T) {
+	sizes := map[string]int{"large": 7776, "short": 1296}
+	for name, size := range sizes {
+		wordlist, err := Embedded(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(wordlist) != size {
+			t.Errorf("%s wordlist has %d words, want %d", name, len(wordlist), size)
+		}
+	}
+
+	if _, err := Embedded("medium"); err == nil {
+		t.Error("unknown wordlist is accepted")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	wordlist, err := ParseWordlist([]byte("11\talpha\n12\tbravo\n\ncharlie\ndelta\nalpha\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wordlist) != 4 {
+		t.Fatalf("wordlist has %d words, want 4", len(wordlist))
+	}
+
+	passphrase, err := wordlist.Generate(Options{Words: 5, Separator: " ", Capitalize: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := strings.Split(passphrase.Passphrase, " ")
+	if len(words) != 5 {
+		t.Errorf("passphrase %q has %d words, want 5", passphrase.Passphrase, len(words))
+	}
+	for _, word := range words {
+		if word[:1] != strings.ToUpper(word[:1]) {
+			t.Errorf("word %q isn't capitalized", word)
+		}
+	}
+	if passphrase.Entropy != 10 {
+		t.Errorf("entropy is %v, want 10", passphrase.Entropy)
+	}
+
+	passphrase, err = wordlist.Generate(Options{Words: 4, Separator: "-", Number: true, Symbol: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 8 + 2 + math.Log2(10) + 2 + math.Log2(15); math.Abs(passphrase.Entropy-want) > 1e-9 {
+		t.Errorf("entropy is %v, want %v", passphrase.Entropy, want)
+	}
+	if !strings.ContainsAny(passphrase.Passphrase, "0123456789") {
+		t.Errorf("passphrase %q has no number", passphrase.Passphrase)
+	}
+
+	if _, err = wordlist.Generate(Options{Words: 0}); err == nil {
+		t.Error("passphrase of no words is generated")
+	}
+	if _, err = ParseWordlist([]byte("alpha\nalpha\n")); err == nil {
+		t.Error("wordlist of one word is accepted")
+	}
+}
@@ -0,0 +1,182 @@
+// Package generator generates random passwords by crypto/rand.
+package generator
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Character classes, Symbols is the default symbol set
+const (
+	Lower   = "abcdefghijklmnopqrstuvwxyz"
+	Upper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits  = "0123456789"
+	Symbols = "$&()*+[]@#^-_!?"
+)
+
+// LookAlikes Characters mistaken for each other in many fonts
+const LookAlikes = "0OoIl1|"
+
+// MaxLength Longest password generated
+const MaxLength = 1024
+
+// Options Options of generated passwords. Classes which are false are left out, and at least the minimum count
+// of characters of every included class is in a password.
+type Options struct {
+	Length  int
+	Lower   bool
+	Upper   bool
+	Digits  bool
+	Symbols bool
+
+	MinLower   int
+	MinUpper   int
+	MinDigits  int
+	MinSymbols int
+
+	ExcludeLookAlikes bool
+	//SymbolSet replaces Symbols when it's not empty
+	SymbolSet string
+}
+
+// DefaultOptions 16 characters of every class, with one of each at least
+func DefaultOptions() Options {
+	return Options{
+		Length: 16, Lower: true, Upper: true, Digits: true, Symbols: true,
+		MinLower: 1, MinUpper: 1, MinDigits: 1, MinSymbols: 1,
+	}
+}
+
+// class Characters of a class and the minimum count of them
+type class struct {
+	name       string
+	characters string
+	min        int
+}
+
+// Generate Generate a password of options
+func Generate(options Options) (string, error) {
+	classes, err := options.classes()
+	if err != nil {
+		return "", err
+	}
+
+	all := ""
+	var password []byte
+	for _, c := range classes {
+		all += c.characters
+		for i := 0; i < c.min; i++ {
+			character, err := pick(c.characters)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, character)
+		}
+	}
+	for len(password) < options.Length {
+		character, err := pick(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, character)
+	}
+
+	//Characters of minimum counts are moved to random places
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := Intn(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+// classes Included classes without excluded characters, options are validated
+func (options Options) classes() ([]class, error) {
+	if options.Length < 1 || options.Length > MaxLength {
+		return nil, fmt.Errorf("length must be between 1 and %d", MaxLength)
+	}
+
+	symbols := Symbols
+	if options.SymbolSet != "" {
+		symbols = options.SymbolSet
+	}
+	candidates := []struct {
+		class
+		included bool
+	}{
+		{class{"lower", Lower, options.MinLower}, options.Lower},
+		{class{"upper", Upper, options.MinUpper}, options.Upper},
+		{class{"digits", Digits, options.MinDigits}, options.Digits},
+		{class{"symbols", symbols, options.MinSymbols}, options.Symbols},
+	}
+
+	var classes []class
+	seen := map[rune]bool{}
+	total := 0
+	for _, candidate := range candidates {
+		if candidate.min < 0 {
+			return nil, fmt.Errorf("minimum of %s can't be negative", candidate.name)
+		}
+		if !candidate.included {
+			if candidate.min > 0 {
+				return nil, fmt.Errorf("minimum of %s is set, but they're excluded", candidate.name)
+			}
+			continue
+		}
+
+		//Characters are kept once, the first class having them keeps them
+		var characters strings.Builder
+		for _, c := range candidate.characters {
+			if c > 0x7e || c < 0x21 {
+				return nil, fmt.Errorf("%s can only have printable ASCII characters", candidate.name)
+			}
+			if seen[c] || (options.ExcludeLookAlikes && strings.ContainsRune(LookAlikes, c)) {
+				continue
+			}
+			seen[c] = true
+			characters.WriteRune(c)
+		}
+		if characters.Len() == 0 {
+			return nil, fmt.Errorf("no character of %s is left", candidate.name)
+		}
+
+		candidate.characters = characters.String()
+		classes = append(classes, candidate.class)
+		total += candidate.min
+	}
+
+	if len(classes) == 0 {
+		return nil, errors.New("no character class is included")
+	}
+	if total > options.Length {
+		return nil, fmt.Errorf("minimums of %d characters don't fit in length %d", total, options.Length)
+	}
+
+	return classes, nil
+}
+
+// pick Random character of characters
+func pick(characters string) (byte, error) {
+	i, err := Intn(len(characters))
+	if err != nil {
+		return 0, err
+	}
+
+	return characters[i], nil
+}
+
+// Intn Uniform random int in [0, n) by crypto/rand
+func Intn(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(i.Int64()), nil
+}
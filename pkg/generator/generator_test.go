@@ -0,0 +1,52 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func count(password string, characters string) int {
+	n := 0
+	for _, c := range password {
+		if strings.ContainsRune(characters, c) {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestGenerate(t *testing.T) {
+	options := Options{Length: 12, Lower: true, Digits: true, Symbols: true, MinDigits: 4, MinSymbols: 3, ExcludeLookAlikes: true, SymbolSet: "#%"}
+	seen := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		password, err := Generate(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(password) != 12 || count(password, Digits) < 4 || count(password, "#%") < 3 {
+			t.Fatalf("%q doesn't follow options", password)
+		}
+		if count(password, Upper) > 0 || count(password, LookAlikes) > 0 || count(password, "$&()*+[]@^-_!?") > 0 {
+			t.Fatalf("%q has excluded characters", password)
+		}
+		seen[password] = true
+	}
+	if len(seen) < 195 {
+		t.Errorf("%d of 200 passwords are distinct", len(seen))
+	}
+
+	invalid := []Options{
+		{Length: 0, Lower: true},
+		{Length: 8},
+		{Length: 4, Lower: true, Digits: true, MinLower: 3, MinDigits: 2},
+		{Length: 8, Lower: true, MinUpper: 1},
+		{Length: 8, Symbols: true, SymbolSet: "|", ExcludeLookAlikes: true},
+		{Length: 8, Symbols: true, SymbolSet: "é"},
+	}
+	for _, options := range invalid {
+		if password, err := Generate(options); err == nil {
+			t.Errorf("%+v generated %q", options, password)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Character classes of password rules. Space belongs to special and ascii-printable, but it's left out of them,
+// as forms often trim it. Custom classes can still have it.
+const (
+	special        = "-~!@#$%^&*_+=`|(){}[:;\"'<>,.?]"
+	asciiPrintable = Lower + Upper + Digits + special
+)
+
+// ruleClasses Named character classes of password rules, unicode is generated as ascii-printable
+var ruleClasses = map[string]string{
+	"upper":           Upper,
+	"lower":           Lower,
+	"digit":           Digits,
+	"special":         special,
+	"ascii-printable": asciiPrintable,
+	"unicode":         asciiPrintable,
+}
+
+// maxAttempts Passwords generated before rules are taken as unsatisfiable, when max-consecutive rejects them
+const maxAttempts = 1000
+
+// Rules Password rules of a site in the syntax of passwordrules attribute,
+// such as "minlength: 8; maxlength: 12; required: lower, upper; required: digit; allowed: [-_]; max-consecutive: 2".
+// Every set of Required has a character in passwords at least, Allowed has characters of Required too.
+// Zero lengths and MaxConsecutive are no limits.
+type Rules struct {
+	Required       []string
+	Allowed        string
+	MinLength      int
+	MaxLength      int
+	MaxConsecutive int
+}
+
+// ParseRules Parse password rules. Unknown properties are left out as browsers do, but malformed ones are errors.
+// Without required and allowed properties, ascii-printable characters are allowed.
+func ParseRules(text string) (Rules, error) {
+	var rules Rules
+	allowed := ""
+	for _, property := range splitRules(text, ';') {
+		property = strings.TrimSpace(property)
+		if property == "" {
+			continue
+		}
+		i := strings.Index(property, ":")
+		if i < 0 {
+			return Rules{}, fmt.Errorf("property %q has no value", property)
+		}
+		name, value := strings.ToLower(strings.TrimSpace(property[:i])), strings.TrimSpace(property[i+1:])
+
+		switch name {
+		case "required", "allowed":
+			characters, err := parseClasses(value)
+			if err != nil {
+				return Rules{}, err
+			}
+			if name == "required" {
+				rules.Required = append(rules.Required, characters)
+			}
+			allowed += characters
+		case "minlength", "maxlength", "max-consecutive":
+			number, err := strconv.Atoi(value)
+			if err != nil || number < 1 {
+				return Rules{}, fmt.Errorf("%s must be a positive number", name)
+			}
+			switch {
+			case name == "minlength" && number > rules.MinLength:
+				rules.MinLength = number
+			case name == "maxlength" && (rules.MaxLength == 0 || number < rules.MaxLength):
+				rules.MaxLength = number
+			case name == "max-consecutive" && (rules.MaxConsecutive == 0 || number < rules.MaxConsecutive):
+				rules.MaxConsecutive = number
+			}
+		}
+	}
+
+	if allowed == "" {
+		allowed = asciiPrintable
+	}
+	rules.Allowed = unique(allowed)
+	if rules.MaxLength != 0 && rules.MinLength > rules.MaxLength {
+		return Rules{}, fmt.Errorf("minlength %d is longer than maxlength %d", rules.MinLength, rules.MaxLength)
+	}
+	if rules.MaxLength != 0 && len(rules.Required) > rules.MaxLength {
+		return Rules{}, fmt.Errorf("%d required classes don't fit in maxlength %d", len(rules.Required), rules.MaxLength)
+	}
+
+	return rules, nil
+}
+
+// splitRules Split text by separator, except separators in custom classes of brackets
+func splitRules(text string, separator byte) []string {
+	var parts []string
+	start := 0
+	inClass := false
+	for i := 0; i < len(text); i++ {
+		switch {
+		case !inClass && text[i] == '[':
+			inClass = true
+		case inClass && text[i] == ']' && (i+1 == len(text) || text[i+1] != ']'):
+			//"]" is in a custom class only as its last character, such as [-]]
+			inClass = false
+		case !inClass && text[i] == separator:
+			parts = append(parts, text[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, text[start:])
+}
+
+// parseClasses Characters of classes separated by commas, named classes or custom ones such as [-_.]
+func parseClasses(value string) (string, error) {
+	characters := ""
+	for _, class := range splitRules(value, ',') {
+		class = strings.TrimSpace(class)
+		switch {
+		case class == "":
+			continue
+		case strings.HasPrefix(class, "[") && strings.HasSuffix(class, "]") && len(class) > 2:
+			for _, c := range class[1 : len(class)-1] {
+				if c < 0x20 || c > 0x7e {
+					return "", fmt.Errorf("custom class %s can only have printable ASCII characters", class)
+				}
+			}
+			characters += class[1 : len(class)-1]
+		default:
+			named, ok := ruleClasses[strings.ToLower(class)]
+			if !ok {
+				return "", fmt.Errorf("unknown character class %q", class)
+			}
+			characters += named
+		}
+	}
+	if characters == "" {
+		return "", errors.New("character classes are empty")
+	}
+
+	return characters, nil
+}
+
+// unique Characters kept once, in order of first appearance
+func unique(characters string) string {
+	var builder strings.Builder
+	seen := map[rune]bool{}
+	for _, c := range characters {
+		if !seen[c] {
+			seen[c] = true
+			builder.WriteRune(c)
+		}
+	}
+
+	return builder.String()
+}
+
+// Length Length within rules closest to preferred length, DefaultOptions length if it's 0
+func (rules Rules) Length(length int) int {
+	if length <= 0 {
+		length = DefaultOptions().Length
+	}
+	if length < rules.MinLength {
+		length = rules.MinLength
+	}
+	if rules.MaxLength != 0 && length > rules.MaxLength {
+		length = rules.MaxLength
+	}
+	if length < len(rules.Required) {
+		length = len(rules.Required)
+	}
+
+	return length
+}
+
+// Satisfies Whether password satisfies rules
+func (rules Rules) Satisfies(password string) bool {
+	if len(password) < rules.MinLength || (rules.MaxLength != 0 && len(password) > rules.MaxLength) {
+		return false
+	}
+	for _, required := range rules.Required {
+		if !strings.ContainsAny(password, required) {
+			return false
+		}
+	}
+
+	consecutive := 0
+	for i := range password {
+		if !strings.ContainsRune(rules.Allowed, rune(password[i])) {
+			return false
+		}
+		if i > 0 && password[i] == password[i-1] {
+			consecutive++
+		} else {
+			consecutive = 1
+		}
+		if rules.MaxConsecutive != 0 && consecutive > rules.MaxConsecutive {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GenerateByRules Generate a password satisfying rules, of length within rules closest to preferred length
+func GenerateByRules(rules Rules, length int) (string, error) {
+	length = rules.Length(length)
+	if length > MaxLength {
+		return "", fmt.Errorf("length must be between 1 and %d", MaxLength)
+	}
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		//Every required class gets a random position, the others are of allowed characters
+		positions := make([]int, length)
+		for i := range positions {
+			j, err := Intn(i + 1)
+			if err != nil {
+				return "", err
+			}
+			positions[i], positions[j] = positions[j], i
+		}
+		classes := make([]string, length)
+		for i := range classes {
+			classes[i] = rules.Allowed
+		}
+		for i, characters := range rules.Required {
+			classes[positions[i]] = characters
+		}
+
+		var password []byte
+		for _, characters := range classes {
+			//A character making a run longer than max-consecutive is left out
+			if n := len(password); rules.MaxConsecutive != 0 && trailingRun(password) >= rules.MaxConsecutive {
+				characters = strings.ReplaceAll(characters, string(password[n-1]), "")
+			}
+			if characters == "" {
+				break
+			}
+			character, err := pick(characters)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, character)
+		}
+
+		if rules.Satisfies(string(password)) {
+			return string(password), nil
+		}
+	}
+
+	return "", errors.New("no password satisfying rules is found")
+}
+
+// trailingRun Count of the same character at the end of password
+func trailingRun(password []byte) int {
+	n := 0
+	for i := len(password) - 1; i >= 0 && password[i] == password[len(password)-1]; i-- {
+		n++
+	}
+
+	return n
+}
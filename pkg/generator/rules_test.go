@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("minlength: 8; maxlength: 12; required: lower, upper; required: digit; allowed: [-,;]]; max-consecutive: 2; unknown: 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.MinLength != 8 || rules.MaxLength != 12 || rules.MaxConsecutive != 2 || len(rules.Required) != 2 {
+		t.Fatalf("%+v isn't parsed as written", rules)
+	}
+	if rules.Allowed != Lower+Upper+Digits+"-,;]" {
+		t.Errorf("allowed characters are %q", rules.Allowed)
+	}
+
+	rules, err = ParseRules("")
+	if err != nil || rules.Allowed != asciiPrintable {
+		t.Errorf("empty rules allow %q, %v", rules.Allowed, err)
+	}
+
+	for _, invalid := range []string{"required: emoji", "minlength: -1", "maxlength: 4; minlength: 8", "required", "allowed: []", "required: lower; required: upper; maxlength: 1"} {
+		if _, err := ParseRules(invalid); err == nil {
+			t.Errorf("%q is accepted", invalid)
+		}
+	}
+}
+
+func TestGenerateByRules(t *testing.T) {
+	rules, err := ParseRules("required: upper; required: digit; allowed: lower; maxlength: 10; max-consecutive: 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		password, err := GenerateByRules(rules, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(password) != 10 || count(password, Upper) == 0 || count(password, Digits) == 0 || count(password, special) > 0 {
+			t.Fatalf("%q doesn't satisfy rules", password)
+		}
+		for j := 1; j < len(password); j++ {
+			if password[j] == password[j-1] {
+				t.Fatalf("%q has consecutive characters", password)
+			}
+		}
+	}
+
+	rules, err = ParseRules("allowed: [ab]; minlength: 20; max-consecutive: 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, err := GenerateByRules(rules, 0); err != nil || len(password) != 20 {
+		t.Errorf("%q, %v", password, err)
+	}
+
+	rules, err = ParseRules("allowed: [a]; minlength: 2; max-consecutive: 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GenerateByRules(rules, 0); err == nil {
+		t.Error("password of unsatisfiable rules is generated")
+	}
+}
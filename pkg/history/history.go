@@ -0,0 +1,139 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Entry Commit which changed the library file.
+type Entry struct {
+	Revision string `json:"revision"`
+	Date     string `json:"date"`
+	Message  string `json:"message"`
+}
+
+// Enabled Whether dir is the top level of a git repository.
+func Enabled(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, ".git"))
+
+	return err == nil && info.IsDir()
+}
+
+// Init Create git repository in dir, nothing is committed until Commit.
+func Init(dir string) error {
+	if Enabled(dir) {
+		return errors.New("history is already enabled")
+	}
+
+	_, err := git(dir, "init", "--quiet")
+
+	return err
+}
+
+// Commit Commit content as file if it changed. Content is committed as given, not as file is on disk,
+// so parts of file which history must not keep are left out. Message must never contain secrets.
+func Commit(dir string, file string, content []byte, message string) error {
+	blob, err := gitInput(dir, content, "hash-object", "-w", "--stdin")
+	if err != nil {
+		return err
+	}
+	_, err = git(dir, "update-index", "--add", "--cacheinfo", "100644,"+strings.TrimSpace(blob)+","+file)
+	if err != nil {
+		return err
+	}
+
+	//Nothing staged, such as writing the library without any change
+	if _, err := git(dir, "diff", "--cached", "--quiet", "--", file); err == nil {
+		return nil
+	}
+
+	_, err = git(dir, "commit", "--quiet", "--message", message)
+
+	return err
+}
+
+// Log Commits which changed file, latest first.
+func Log(dir string, file string) ([]Entry, error) {
+	output, err := git(dir, "log", "--format=%h%x09%ad%x09%s", "--date=iso", "--", file)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) == 3 {
+			entries = append(entries, Entry{Revision: fields[0], Date: fields[1], Message: fields[2]})
+		}
+	}
+
+	return entries, nil
+}
+
+// Show Content of file at revision. Revision is resolved to a commit first, so it's never taken as an option.
+func Show(dir string, revision string, file string) ([]byte, error) {
+	if strings.HasPrefix(revision, "-") {
+		return nil, fmt.Errorf("invalid revision %q", revision)
+	}
+	commit, err := git(dir, "rev-parse", "--verify", "--quiet", "--end-of-options", revision+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %q", revision)
+	}
+
+	output, err := git(dir, "show", strings.TrimSpace(commit)+":"+file)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(output), nil
+}
+
+// Push Push current branch to remote, a path of bare repository is created if it doesn't exist.
+func Push(dir string, remote string) error {
+	if _, err := os.Stat(remote); os.IsNotExist(err) && !strings.Contains(remote, ":") {
+		_, err = git(dir, "init", "--quiet", "--bare", remote)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := git(dir, "push", "--quiet", remote, "HEAD")
+
+	return err
+}
+
+// git Run git in dir. A fallback identity is used if the user never configured one.
+func git(dir string, args ...string) (string, error) {
+	return gitInput(dir, nil, args...)
+}
+
+// gitInput Run git in dir like git, with input as its standard input
+func gitInput(dir string, input []byte, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = os.Environ()
+	identityCmd := exec.Command("git", "config", "user.email")
+	identityCmd.Dir = dir
+	if identity, err := identityCmd.Output(); err != nil || len(identity) == 0 {
+		cmd.Env = append(cmd.Env,
+			"GIT_AUTHOR_NAME=Informer", "GIT_AUTHOR_EMAIL=informer@localhost",
+			"GIT_COMMITTER_NAME=Informer", "GIT_COMMITTER_EMAIL=informer@localhost")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
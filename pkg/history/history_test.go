@@ -0,0 +1,82 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir, err := ioutil.TempDir("", "informer-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if Enabled(dir) {
+		t.Fatal("history enabled before init")
+	}
+	err = Init(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Enabled(dir) || Init(dir) == nil {
+		t.Fatal("history not enabled by init")
+	}
+	err = Commit(dir, "libraries.yaml", []byte("first"), "Start history")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Writes without change aren't committed, content is committed as given, not as file is on disk
+	err = ioutil.WriteFile(filepath.Join(dir, "libraries.yaml"), []byte("on disk"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range []string{"Update a", "Update b"} {
+		err = Commit(dir, "libraries.yaml", []byte("second"), message)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := Log(dir, "libraries.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Message != "Update a" || entries[1].Message != "Start history" {
+		t.Fatalf("log is %v", entries)
+	}
+
+	content, err := Show(dir, entries[1].Revision, "libraries.yaml")
+	if err != nil || string(content) != "first" {
+		t.Errorf("content of first revision is %q, %v", content, err)
+	}
+	content, err = Show(dir, "HEAD", "libraries.yaml")
+	if err != nil || string(content) != "second" {
+		t.Errorf("content of HEAD is %q, %v", content, err)
+	}
+
+	//Revisions are never taken as options
+	output := filepath.Join(dir, "output")
+	for _, revision := range []string{"--output=" + output, "-p", "unknown"} {
+		if _, err := Show(dir, revision, "libraries.yaml"); err == nil {
+			t.Errorf("revision %q shown", revision)
+		}
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Error("revision taken as an option")
+	}
+
+	remote := filepath.Join(dir, "remote.git")
+	err = Push(dir, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git(remote, "rev-parse", "--verify", entries[0].Revision+"^{commit}"); err != nil {
+		t.Error("history not pushed", err)
+	}
+}
@@ -0,0 +1,278 @@
+package kdbx
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Argon2 as specified by RFC 9106. golang.org/x/crypto/argon2 only offers Argon2i and Argon2id,
+// while KeePass databases use Argon2d by default.
+
+const (
+	argon2d  = 0
+	argon2id = 2
+
+	argon2Version     = 0x13
+	argon2BlockWords  = 128
+	argon2SyncPoints  = 4
+	argon2AddressSize = argon2BlockWords
+)
+
+type argon2Block [argon2BlockWords]uint64
+
+// argon2Key Derive keyLength bytes. memory is in KiB.
+func argon2Key(mode int, password []byte, salt []byte, secret []byte, data []byte,
+	iterations uint32, memory uint32, parallelism uint32, keyLength uint32) []byte {
+	h0 := argon2InitialHash(mode, password, salt, secret, data, iterations, memory, parallelism, keyLength)
+
+	if memory < 2*argon2SyncPoints*parallelism {
+		memory = 2 * argon2SyncPoints * parallelism
+	}
+	memory = memory / (argon2SyncPoints * parallelism) * (argon2SyncPoints * parallelism)
+	laneLength := memory / parallelism
+	segmentLength := laneLength / argon2SyncPoints
+	blocks := make([]argon2Block, memory)
+
+	//First two blocks of every lane come from the initial hash
+	var input [72]byte
+	copy(input[:], h0)
+	for lane := uint32(0); lane < parallelism; lane++ {
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(input[64:], i)
+			binary.LittleEndian.PutUint32(input[68:], lane)
+			blockBytes := argon2Hash(input[:], 1024)
+			for j := range blocks[lane*laneLength+i] {
+				blocks[lane*laneLength+i][j] = binary.LittleEndian.Uint64(blockBytes[j*8:])
+			}
+		}
+	}
+
+	for pass := uint32(0); pass < iterations; pass++ {
+		for slice := uint32(0); slice < argon2SyncPoints; slice++ {
+			for lane := uint32(0); lane < parallelism; lane++ {
+				argon2FillSegment(blocks, mode, pass, slice, lane, iterations, memory, parallelism, laneLength, segmentLength)
+			}
+		}
+	}
+
+	var final argon2Block
+	for lane := uint32(0); lane < parallelism; lane++ {
+		last := blocks[lane*laneLength+laneLength-1]
+		for i := range final {
+			final[i] ^= last[i]
+		}
+	}
+	finalBytes := make([]byte, 1024)
+	for i, word := range final {
+		binary.LittleEndian.PutUint64(finalBytes[i*8:], word)
+	}
+
+	return argon2Hash(finalBytes, keyLength)
+}
+
+func argon2InitialHash(mode int, password []byte, salt []byte, secret []byte, data []byte,
+	iterations uint32, memory uint32, parallelism uint32, keyLength uint32) []byte {
+	h, _ := blake2b.New512(nil)
+
+	var word [4]byte
+	writeWord := func(value uint32) {
+		binary.LittleEndian.PutUint32(word[:], value)
+		h.Write(word[:])
+	}
+	writeBytes := func(value []byte) {
+		writeWord(uint32(len(value)))
+		h.Write(value)
+	}
+
+	writeWord(parallelism)
+	writeWord(keyLength)
+	writeWord(memory)
+	writeWord(iterations)
+	writeWord(argon2Version)
+	writeWord(uint32(mode))
+	writeBytes(password)
+	writeBytes(salt)
+	writeBytes(secret)
+	writeBytes(data)
+
+	return h.Sum(nil)
+}
+
+// argon2Hash Variable length hash H' of Argon2
+func argon2Hash(input []byte, length uint32) []byte {
+	var lengthBytes [4]byte
+	binary.LittleEndian.PutUint32(lengthBytes[:], length)
+
+	newHash := func(size int) hash.Hash {
+		h, _ := blake2b.New(size, nil)
+		return h
+	}
+
+	if length <= blake2b.Size {
+		h := newHash(int(length))
+		h.Write(lengthBytes[:])
+		h.Write(input)
+		return h.Sum(nil)
+	}
+
+	output := make([]byte, 0, length)
+	h := newHash(blake2b.Size)
+	h.Write(lengthBytes[:])
+	h.Write(input)
+	v := h.Sum(nil)
+
+	for uint32(len(output))+blake2b.Size < length {
+		output = append(output, v[:32]...)
+		h = newHash(blake2b.Size)
+		if length-uint32(len(output)) < blake2b.Size {
+			h = newHash(int(length - uint32(len(output))))
+		}
+		h.Write(v)
+		v = h.Sum(nil)
+	}
+
+	return append(output, v...)
+}
+
+func argon2FillSegment(blocks []argon2Block, mode int, pass uint32, slice uint32, lane uint32,
+	iterations uint32, memory uint32, parallelism uint32, laneLength uint32, segmentLength uint32) {
+	dataIndependent := mode == argon2id && pass == 0 && slice < argon2SyncPoints/2
+
+	var address, input, zero argon2Block
+	if dataIndependent {
+		input[0] = uint64(pass)
+		input[1] = uint64(lane)
+		input[2] = uint64(slice)
+		input[3] = uint64(memory)
+		input[4] = uint64(iterations)
+		input[5] = uint64(mode)
+	}
+	nextAddresses := func() {
+		input[6]++
+		argon2Compress(&address, &zero, &input, false)
+		argon2Compress(&address, &zero, &address, false)
+	}
+
+	start := uint32(0)
+	if pass == 0 && slice == 0 {
+		start = 2
+		if dataIndependent {
+			nextAddresses()
+		}
+	}
+
+	offset := lane*laneLength + slice*segmentLength + start
+	for index := start; index < segmentLength; index, offset = index+1, offset+1 {
+		previous := offset - 1
+		if offset%laneLength == 0 {
+			previous = offset + laneLength - 1
+		}
+
+		var random uint64
+		if dataIndependent {
+			if index%argon2AddressSize == 0 {
+				nextAddresses()
+			}
+			random = address[index%argon2AddressSize]
+		} else {
+			random = blocks[previous][0]
+		}
+
+		referenceLane := uint32(random>>32) % parallelism
+		if pass == 0 && slice == 0 {
+			referenceLane = lane
+		}
+		reference := argon2ReferenceIndex(uint32(random), pass, slice, index, referenceLane == lane, laneLength, segmentLength)
+
+		argon2Compress(&blocks[offset], &blocks[previous], &blocks[referenceLane*laneLength+reference], pass > 0)
+	}
+}
+
+func argon2ReferenceIndex(random uint32, pass uint32, slice uint32, index uint32, sameLane bool,
+	laneLength uint32, segmentLength uint32) uint32 {
+	var areaSize uint32
+	switch {
+	case pass == 0 && slice == 0:
+		areaSize = index - 1
+	case pass == 0 && sameLane:
+		areaSize = slice*segmentLength + index - 1
+	case pass == 0:
+		areaSize = slice * segmentLength
+		if index == 0 {
+			areaSize--
+		}
+	case sameLane:
+		areaSize = laneLength - segmentLength + index - 1
+	default:
+		areaSize = laneLength - segmentLength
+		if index == 0 {
+			areaSize--
+		}
+	}
+
+	relative := uint64(random) * uint64(random) >> 32
+	relative = uint64(areaSize) - 1 - (uint64(areaSize) * relative >> 32)
+
+	start := uint32(0)
+	if pass != 0 && slice != argon2SyncPoints-1 {
+		start = (slice + 1) * segmentLength
+	}
+
+	return uint32((uint64(start) + relative) % uint64(laneLength))
+}
+
+// argon2Compress Compression function G, the result is xor-ed into next when withXor is set
+func argon2Compress(next *argon2Block, previous *argon2Block, reference *argon2Block, withXor bool) {
+	var r, z argon2Block
+	for i := range r {
+		r[i] = previous[i] ^ reference[i]
+	}
+	z = r
+
+	for i := 0; i < 8; i++ {
+		argon2Round(&z, 16*i, 16*i+1, 16*i+2, 16*i+3, 16*i+4, 16*i+5, 16*i+6, 16*i+7,
+			16*i+8, 16*i+9, 16*i+10, 16*i+11, 16*i+12, 16*i+13, 16*i+14, 16*i+15)
+	}
+	for i := 0; i < 8; i++ {
+		argon2Round(&z, 2*i, 2*i+1, 2*i+16, 2*i+17, 2*i+32, 2*i+33, 2*i+48, 2*i+49,
+			2*i+64, 2*i+65, 2*i+80, 2*i+81, 2*i+96, 2*i+97, 2*i+112, 2*i+113)
+	}
+
+	for i := range next {
+		if withXor {
+			next[i] ^= z[i] ^ r[i]
+		} else {
+			next[i] = z[i] ^ r[i]
+		}
+	}
+}
+
+// argon2Round BLAKE2b round without message, using multiplication hardened mixing
+func argon2Round(b *argon2Block, i0, i1, i2, i3, i4, i5, i6, i7, i8, i9, i10, i11, i12, i13, i14, i15 int) {
+	argon2Mix(b, i0, i4, i8, i12)
+	argon2Mix(b, i1, i5, i9, i13)
+	argon2Mix(b, i2, i6, i10, i14)
+	argon2Mix(b, i3, i7, i11, i15)
+	argon2Mix(b, i0, i5, i10, i15)
+	argon2Mix(b, i1, i6, i11, i12)
+	argon2Mix(b, i2, i7, i8, i13)
+	argon2Mix(b, i3, i4, i9, i14)
+}
+
+func argon2Mix(block *argon2Block, a, b, c, d int) {
+	blaMka := func(x uint64, y uint64) uint64 {
+		return x + y + 2*uint64(uint32(x))*uint64(uint32(y))
+	}
+
+	block[a] = blaMka(block[a], block[b])
+	block[d] = bits.RotateLeft64(block[d]^block[a], -32)
+	block[c] = blaMka(block[c], block[d])
+	block[b] = bits.RotateLeft64(block[b]^block[c], -24)
+	block[a] = blaMka(block[a], block[b])
+	block[d] = bits.RotateLeft64(block[d]^block[a], -16)
+	block[c] = blaMka(block[c], block[d])
+	block[b] = bits.RotateLeft64(block[b]^block[c], -63)
+}
@@ -0,0 +1,30 @@
+package kdbx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestArgon2d(t *testing.T) {
+	//Test vector of RFC 9106 section 5.1
+	key := argon2Key(argon2d, bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16),
+		bytes.Repeat([]byte{3}, 8), bytes.Repeat([]byte{4}, 12), 3, 32, 4, 32)
+
+	expected := "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb"
+	if hex.EncodeToString(key) != expected {
+		t.Fatalf("expect %s, got %x", expected, key)
+	}
+}
+
+func TestArgon2id(t *testing.T) {
+	password, salt := []byte("password"), []byte("somesalt")
+
+	key := argon2Key(argon2id, password, salt, nil, nil, 2, 256, 2, 32)
+	expected := argon2.IDKey(password, salt, 2, 256, 2, 32)
+	if !bytes.Equal(key, expected) {
+		t.Fatalf("expect %x, got %x", expected, key)
+	}
+}
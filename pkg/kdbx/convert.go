@@ -0,0 +1,256 @@
+package kdbx
+
+import (
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"junjie.pro/informer/pkg/library"
+	"junjie.pro/informer/pkg/otp"
+)
+
+const (
+	keyTitle    = "Title"
+	keyUserName = "UserName"
+	keyPassword = "Password"
+	keyURL      = "URL"
+	keyNotes    = "Notes"
+	keyOTP      = "otp"
+	//Used by older KeePassXC and by KeePass 2.47 and later
+	keyTOTPSeed     = "TOTP Seed"
+	keyTOTPSettings = "TOTP Settings"
+	keyTimeOTP      = "TimeOtp-Secret-Base32"
+	keyHmacOTP      = "HmacOtp-Secret-Base32"
+)
+
+// Secures Entries of database as secures keyed by entry UUID. Groups below the root group become folders.
+func (database *Database) Secures() map[string]library.SecureStore {
+	secures := map[string]library.SecureStore{}
+	database.Root.walk("", func(folder string, entry Entry) {
+		secures[entryKey(entry.UUID)] = database.secure(folder, entry)
+	})
+
+	return secures
+}
+
+func (group Group) walk(folder string, visit func(string, Entry)) {
+	for _, entry := range group.Entries {
+		visit(folder, entry)
+	}
+	for _, subGroup := range group.Groups {
+		subFolder := subGroup.Name
+		if folder != "" {
+			subFolder = folder + "/" + subGroup.Name
+		}
+		subGroup.walk(subFolder, visit)
+	}
+}
+
+func (database *Database) secure(folder string, entry Entry) library.SecureStore {
+	secure := library.SecureStore{
+		ID:           entry.Get(keyTitle),
+		FriendlyName: entry.Get(keyTitle),
+		Platform:     entry.Get(keyTitle),
+		Username:     entry.Get(keyUserName),
+		Password:     library.NewSecret(entry.Get(keyPassword)),
+		URL:          entry.Get(keyURL),
+		Folder:       folder,
+		Notes:        library.NewSecret(entry.Get(keyNotes)),
+		Created:      entry.Times.CreationTime.Time,
+		Modified:     entry.Times.LastModificationTime.Time,
+	}
+	if u, err := url.Parse(secure.URL); err == nil && u.Hostname() != "" {
+		secure.Platform = u.Hostname()
+	}
+
+	for _, s := range entry.Strings {
+		switch s.Key {
+		case keyTitle, keyUserName, keyPassword, keyURL, keyNotes, keyTOTPSettings:
+		case keyOTP:
+			if account, ok := keyURI(s.Value.Content); ok {
+				secure.SetOTPAccount(account)
+			} else {
+				secure.OTP = library.NewSecret(s.Value.Content)
+				secure.OTPType = "totp"
+			}
+		case keyTOTPSeed, keyTimeOTP:
+			if len(secure.OTP) == 0 {
+				secure.OTP = library.NewSecret(s.Value.Content)
+				secure.OTPType = "totp"
+			}
+		case keyHmacOTP:
+			if len(secure.OTP) == 0 {
+				secure.OTP = library.NewSecret(s.Value.Content)
+				secure.OTPType = "hotp"
+			}
+		default:
+			secure.Fields = append(secure.Fields, library.Field{
+				Name:      s.Key,
+				Value:     library.NewSecret(s.Value.Content),
+				Protected: strings.EqualFold(s.Value.Protected, "True"),
+			})
+		}
+	}
+
+	for _, attachment := range entry.Binaries {
+		if attachment.Value.Ref < 0 || attachment.Value.Ref >= len(database.Binaries) {
+			continue
+		}
+		secure.Attachments = append(secure.Attachments, library.Attachment{
+			Name: attachment.Key,
+			Data: library.NewSecret(string(database.Binaries[attachment.Value.Ref])),
+		})
+	}
+
+	return secure
+}
+
+// NewDatabase Database holding every secure of an unlocked library, folders become groups.
+func NewDatabase(informerLibrary library.InformerLibrary) *Database {
+	database := &Database{
+		Meta: Meta{Generator: "Informer", DatabaseName: "Informer"},
+		Root: Group{UUID: newUUID(), Name: "Root"},
+	}
+	database.Root.Times = Times{CreationTime: Time{now()}, LastModificationTime: Time{now()}}
+
+	//Sorted, so exporting the same library gives the same order
+	keys := make([]string, 0, len(informerLibrary.SecureStore))
+	for k := range informerLibrary.SecureStore {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		secure := informerLibrary.SecureStore[k]
+		group := &database.Root
+		if secure.Folder != "" {
+			for _, name := range strings.Split(secure.Folder, "/") {
+				group = group.subGroup(name)
+			}
+		}
+		group.Entries = append(group.Entries, database.entry(k, secure))
+	}
+
+	return database
+}
+
+func (group *Group) subGroup(name string) *Group {
+	for i := range group.Groups {
+		if group.Groups[i].Name == name {
+			return &group.Groups[i]
+		}
+	}
+
+	group.Groups = append(group.Groups, Group{
+		UUID:  newUUID(),
+		Name:  name,
+		Times: Times{CreationTime: Time{now()}, LastModificationTime: Time{now()}},
+	})
+
+	return &group.Groups[len(group.Groups)-1]
+}
+
+func (database *Database) entry(k string, secure *library.SecureStore) Entry {
+	title := secure.FriendlyName
+	if title == "" {
+		title = secure.ID
+	}
+
+	entry := Entry{
+		UUID:  entryUUID(k),
+		Times: Times{CreationTime: Time{secure.Created}, LastModificationTime: Time{secure.Modified}},
+	}
+	entry.set(keyTitle, title, false)
+	entry.set(keyUserName, secure.Username, false)
+	entry.set(keyPassword, secure.Password.Reveal(), true)
+	entry.set(keyURL, secure.URL, false)
+	entry.set(keyNotes, secure.Notes.Reveal(), false)
+	if len(secure.OTP) > 0 {
+		entry.set(keyOTP, otpURI(secure, title), true)
+	}
+	for _, field := range secure.Fields {
+		entry.set(field.Name, field.Value.Reveal(), field.Protected)
+	}
+
+	for _, attachment := range secure.Attachments {
+		binary := Binary{Key: attachment.Name}
+		binary.Value.Ref = len(database.Binaries)
+		database.Binaries = append(database.Binaries, []byte(attachment.Data))
+		entry.Binaries = append(entry.Binaries, binary)
+	}
+
+	return entry
+}
+
+func (entry *Entry) set(key string, value string, protected bool) {
+	s := String{Key: key, Value: Value{Content: value}}
+	if protected {
+		s.Value.Protected = "True"
+	}
+
+	entry.Strings = append(entry.Strings, s)
+}
+
+// otpURI Key URI as used by KeePassXC for the otp field, issuer defaults to title
+func otpURI(secure *library.SecureStore, title string) string {
+	account := secure.OTPAccount()
+	if secure.OTPIssuer == "" {
+		account.Issuer = title
+	}
+	switch strings.ToLower(account.Type) {
+	case "hotp", "steam":
+	default:
+		account.Type = "totp"
+	}
+
+	return account.URI()
+}
+
+// entryKey Primary key of secure from entry UUID
+func entryKey(entryUUID string) string {
+	raw, err := base64.StdEncoding.DecodeString(entryUUID)
+	if err == nil {
+		if id, err := uuid.FromBytes(raw); err == nil {
+			return id.String()
+		}
+	}
+
+	return uuid.NewString()
+}
+
+// entryUUID Entry UUID from primary key of secure, primary keys which aren't UUIDs get a new one
+func entryUUID(k string) string {
+	id, err := uuid.Parse(k)
+	if err != nil {
+		return newUUID()
+	}
+
+	return base64.StdEncoding.EncodeToString(id[:])
+}
+
+func newUUID() string {
+	id := uuid.New()
+
+	return base64.StdEncoding.EncodeToString(id[:])
+}
+
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// keyURI Account of an otpauth key URI, other values such as plain secrets are never parsed as URIs
+func keyURI(value string) (otp.Account, bool) {
+	if !strings.HasPrefix(value, "otpauth://") {
+		return otp.Account{}, false
+	}
+
+	accounts, err := otp.ParseURI(value)
+	if err != nil {
+		return otp.Account{}, false
+	}
+
+	return accounts[0], true
+}
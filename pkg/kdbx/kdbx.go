@@ -0,0 +1,420 @@
+// Package kdbx reads and writes KeePass KDBX 4 databases.
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/twofish"
+)
+
+const (
+	signature1 = 0x9aa2d903
+	signature2 = 0xb54bfb67
+	version4   = 0x00040000
+
+	headerEnd           = 0
+	headerCipherID      = 2
+	headerCompression   = 3
+	headerMasterSeed    = 4
+	headerEncryptionIV  = 7
+	headerKdfParameters = 11
+
+	innerHeaderEnd       = 0
+	innerHeaderStreamID  = 1
+	innerHeaderStreamKey = 2
+	innerHeaderBinary    = 3
+
+	streamSalsa20  = 2
+	streamChaCha20 = 3
+
+	blockSize = 1 << 20
+)
+
+var (
+	cipherAES256   = []byte{0x31, 0xc1, 0xf2, 0xe6, 0xbf, 0x71, 0x43, 0x50, 0xbe, 0x58, 0x05, 0x21, 0x6a, 0xfc, 0x5a, 0xff}
+	cipherChaCha20 = []byte{0xd6, 0x03, 0x8a, 0x2b, 0x8b, 0x6f, 0x4c, 0xb5, 0xa5, 0x24, 0x33, 0x9a, 0x31, 0xdb, 0xb5, 0x9a}
+	cipherTwofish  = []byte{0xad, 0x68, 0xf2, 0x9f, 0x57, 0x6f, 0x4b, 0xb9, 0xa3, 0x6a, 0xd4, 0x7a, 0xf9, 0x65, 0x34, 0x6c}
+
+	ErrCredentials = errors.New("invalid credentials or corrupted database")
+)
+
+// Database Content of a KDBX database.
+type Database struct {
+	Meta     Meta
+	Root     Group
+	Binaries [][]byte
+}
+
+// Read Decrypt and parse a KDBX 4 database.
+func Read(r io.Reader, credentials Credentials) (*Database, error) {
+	var prefix [12]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(prefix[0:]) != signature1 || binary.LittleEndian.Uint32(prefix[4:]) != signature2 {
+		return nil, errors.New("not a KeePass database")
+	}
+	if binary.LittleEndian.Uint32(prefix[8:])&0xffff0000 != version4 {
+		return nil, errors.New("only KDBX 4 databases are supported")
+	}
+
+	//Outer header, kept as read for verifying
+	rawHeader := bytes.NewBuffer(append([]byte{}, prefix[:]...))
+	fields := map[byte][]byte{}
+	for {
+		var fieldHeader [5]byte
+		if _, err := io.ReadFull(r, fieldHeader[:]); err != nil {
+			return nil, err
+		}
+		value := make([]byte, binary.LittleEndian.Uint32(fieldHeader[1:]))
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, err
+		}
+		rawHeader.Write(fieldHeader[:])
+		rawHeader.Write(value)
+
+		if fieldHeader[0] == headerEnd {
+			break
+		}
+		fields[fieldHeader[0]] = value
+	}
+
+	var headerHash, headerHMAC [32]byte
+	if _, err := io.ReadFull(r, headerHash[:]); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, headerHMAC[:]); err != nil {
+		return nil, err
+	}
+	if sha256.Sum256(rawHeader.Bytes()) != headerHash {
+		return nil, errors.New("database header is corrupted")
+	}
+
+	kdfParameters, err := parseVariantDictionary(fields[headerKdfParameters])
+	if err != nil {
+		return nil, err
+	}
+	compositeKey, err := credentials.compositeKey()
+	if err != nil {
+		return nil, err
+	}
+	transformedKey, err := transformKey(compositeKey, kdfParameters)
+	if err != nil {
+		return nil, err
+	}
+	encryptionKey, hmacKey := databaseKeys(fields[headerMasterSeed], transformedKey)
+
+	if !hmac.Equal(computeHMAC(blockHMACKey(hmacKey, ^uint64(0)), rawHeader.Bytes()), headerHMAC[:]) {
+		return nil, ErrCredentials
+	}
+
+	//HMAC protected blocks, an empty block ends them
+	var encrypted bytes.Buffer
+	for index := uint64(0); ; index++ {
+		var blockHeader [36]byte
+		if _, err := io.ReadFull(r, blockHeader[:]); err != nil {
+			return nil, err
+		}
+		data := make([]byte, binary.LittleEndian.Uint32(blockHeader[32:]))
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		var indexBytes [8]byte
+		binary.LittleEndian.PutUint64(indexBytes[:], index)
+		mac := computeHMAC(blockHMACKey(hmacKey, index), indexBytes[:], blockHeader[32:], data)
+		if !hmac.Equal(mac, blockHeader[:32]) {
+			return nil, errors.New("database content is corrupted")
+		}
+
+		if len(data) == 0 {
+			break
+		}
+		encrypted.Write(data)
+	}
+
+	payload, err := decryptPayload(fields[headerCipherID], encryptionKey, fields[headerEncryptionIV], encrypted.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(fields[headerCompression]) == 4 && binary.LittleEndian.Uint32(fields[headerCompression]) == 1 {
+		gzipReader, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		payload, err = ioutil.ReadAll(gzipReader)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return parseInner(payload)
+}
+
+// parseInner Parse inner header and XML document of decrypted payload
+func parseInner(payload []byte) (*Database, error) {
+	database := &Database{}
+	var streamID uint32
+	var streamKey []byte
+
+	reader := bytes.NewReader(payload)
+	for {
+		var fieldHeader [5]byte
+		if _, err := io.ReadFull(reader, fieldHeader[:]); err != nil {
+			return nil, err
+		}
+		value := make([]byte, binary.LittleEndian.Uint32(fieldHeader[1:]))
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+
+		switch fieldHeader[0] {
+		case innerHeaderStreamID:
+			if len(value) != 4 {
+				return nil, errors.New("invalid inner random stream")
+			}
+			streamID = binary.LittleEndian.Uint32(value)
+		case innerHeaderStreamKey:
+			streamKey = value
+		case innerHeaderBinary:
+			//First byte is flags of memory protection
+			if len(value) > 0 {
+				database.Binaries = append(database.Binaries, value[1:])
+			}
+		}
+
+		if fieldHeader[0] == innerHeaderEnd {
+			break
+		}
+	}
+
+	stream, err := newInnerStream(streamID, streamKey)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	err = database.parseXML(document, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	return database, nil
+}
+
+// Write Encrypt database as KDBX 4, using AES-256, Argon2d and ChaCha20 for protected values.
+func (database *Database) Write(w io.Writer, credentials Credentials) error {
+	masterSeed := randomBytes(32)
+	encryptionIV := randomBytes(aes.BlockSize)
+	kdfParameters := variantDictionary{}
+	kdfParameters.setBytes("$UUID", kdfArgon2d)
+	kdfParameters.setBytes("S", randomBytes(32))
+	kdfParameters.setUint32("P", 2)
+	kdfParameters.setUint64("M", 64*1024*1024)
+	kdfParameters.setUint64("I", 4)
+	kdfParameters.setUint32("V", argon2Version)
+
+	//Outer header
+	var header bytes.Buffer
+	_ = binary.Write(&header, binary.LittleEndian, []uint32{signature1, signature2, version4 | 1})
+	var compression [4]byte
+	binary.LittleEndian.PutUint32(compression[:], 1)
+	writeField(&header, headerCipherID, cipherAES256)
+	writeField(&header, headerCompression, compression[:])
+	writeField(&header, headerMasterSeed, masterSeed)
+	writeField(&header, headerEncryptionIV, encryptionIV)
+	writeField(&header, headerKdfParameters, kdfParameters.serialize())
+	writeField(&header, headerEnd, []byte{'\r', '\n', '\r', '\n'})
+
+	compositeKey, err := credentials.compositeKey()
+	if err != nil {
+		return err
+	}
+	transformedKey, err := transformKey(compositeKey, kdfParameters)
+	if err != nil {
+		return err
+	}
+	encryptionKey, hmacKey := databaseKeys(masterSeed, transformedKey)
+
+	//Inner header and XML document, compressed and encrypted
+	streamKey := randomBytes(64)
+	stream, err := newInnerStream(streamChaCha20, streamKey)
+	if err != nil {
+		return err
+	}
+	document, err := database.serializeXML(stream)
+	if err != nil {
+		return err
+	}
+
+	var inner bytes.Buffer
+	var streamID [4]byte
+	binary.LittleEndian.PutUint32(streamID[:], streamChaCha20)
+	writeField(&inner, innerHeaderStreamID, streamID[:])
+	writeField(&inner, innerHeaderStreamKey, streamKey)
+	for _, binaryData := range database.Binaries {
+		writeField(&inner, innerHeaderBinary, append([]byte{1}, binaryData...))
+	}
+	writeField(&inner, innerHeaderEnd, nil)
+	inner.Write(document)
+
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	if _, err := gzipWriter.Write(inner.Bytes()); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+
+	encrypted, err := encryptPayload(cipherAES256, encryptionKey, encryptionIV, compressed.Bytes())
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	output.Write(header.Bytes())
+	headerHash := sha256.Sum256(header.Bytes())
+	output.Write(headerHash[:])
+	output.Write(computeHMAC(blockHMACKey(hmacKey, ^uint64(0)), header.Bytes()))
+
+	for index := uint64(0); ; index++ {
+		size := len(encrypted)
+		if size > blockSize {
+			size = blockSize
+		}
+
+		var indexBytes, sizeBytes [8]byte
+		binary.LittleEndian.PutUint64(indexBytes[:], index)
+		binary.LittleEndian.PutUint32(sizeBytes[:], uint32(size))
+		output.Write(computeHMAC(blockHMACKey(hmacKey, index), indexBytes[:], sizeBytes[:4], encrypted[:size]))
+		output.Write(sizeBytes[:4])
+		output.Write(encrypted[:size])
+
+		encrypted = encrypted[size:]
+		if size == 0 {
+			break
+		}
+	}
+
+	_, err = w.Write(output.Bytes())
+
+	return err
+}
+
+func decryptPayload(cipherID []byte, key []byte, iv []byte, encrypted []byte) ([]byte, error) {
+	if bytes.Equal(cipherID, cipherChaCha20) {
+		stream, err := chacha20.NewUnauthenticatedCipher(key, iv)
+		if err != nil {
+			return nil, err
+		}
+		payload := make([]byte, len(encrypted))
+		stream.XORKeyStream(payload, encrypted)
+
+		return payload, nil
+	}
+
+	block, err := newBlockCipher(cipherID, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted) == 0 || len(encrypted)%block.BlockSize() != 0 || len(iv) != block.BlockSize() {
+		return nil, errors.New("database content is corrupted")
+	}
+
+	payload := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(payload, encrypted)
+
+	//PKCS#7 padding
+	padding := int(payload[len(payload)-1])
+	if padding == 0 || padding > block.BlockSize() {
+		return nil, errors.New("database content is corrupted")
+	}
+
+	return payload[:len(payload)-padding], nil
+}
+
+func encryptPayload(cipherID []byte, key []byte, iv []byte, payload []byte) ([]byte, error) {
+	block, err := newBlockCipher(cipherID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := block.BlockSize() - len(payload)%block.BlockSize()
+	padded := append(append([]byte{}, payload...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+
+	return encrypted, nil
+}
+
+func newBlockCipher(cipherID []byte, key []byte) (cipher.Block, error) {
+	switch {
+	case bytes.Equal(cipherID, cipherAES256):
+		return aes.NewCipher(key)
+	case bytes.Equal(cipherID, cipherTwofish):
+		return twofish.NewCipher(key)
+	}
+
+	return nil, fmt.Errorf("unsupported cipher %x", cipherID)
+}
+
+func computeHMAC(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+
+	return mac.Sum(nil)
+}
+
+func writeField(buffer *bytes.Buffer, id byte, value []byte) {
+	var fieldHeader [5]byte
+	fieldHeader[0] = id
+	binary.LittleEndian.PutUint32(fieldHeader[1:], uint32(len(value)))
+	buffer.Write(fieldHeader[:])
+	buffer.Write(value)
+}
+
+func randomBytes(size int) []byte {
+	random := make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, random); err != nil {
+		panic(err)
+	}
+
+	return random
+}
+
+// innerStream Key stream protecting values in XML document, consumed in document order
+type innerStream interface {
+	XORKeyStream(dst []byte, src []byte)
+}
+
+func newInnerStream(id uint32, key []byte) (innerStream, error) {
+	switch id {
+	case streamChaCha20:
+		keyHash := sha512.Sum512(key)
+		return chacha20.NewUnauthenticatedCipher(keyHash[:32], keyHash[32:44])
+	case streamSalsa20:
+		keyHash := sha256.Sum256(key)
+		return newSalsa20Stream(keyHash), nil
+	}
+
+	return nil, fmt.Errorf("unsupported inner random stream %d", id)
+}
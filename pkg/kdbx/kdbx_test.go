@@ -0,0 +1,103 @@
+package kdbx
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"junjie.pro/informer/pkg/library"
+)
+
+func TestRoundTrip(t *testing.T) {
+	created := time.Date(2020, time.May, 4, 3, 2, 1, 0, time.UTC)
+	informerLibrary := library.InformerLibrary{Unlocked: true, SecureStore: map[string]*library.SecureStore{
+		"0b7c2e3a-6f0e-4c59-9a65-2f1d7c8b9e10": {
+			ID:           "GitHub",
+			FriendlyName: "GitHub",
+			Username:     "octocat",
+			Password:     library.NewSecret("hunter2"),
+			OTP:          library.NewSecret("JBSWY3DPEHPK3PXP"),
+			OTPType:      "totp",
+			URL:          "https://github.com/login",
+			Folder:       "Work/Code",
+			Notes:        library.NewSecret("recovery codes are in the safe"),
+			Fields:       []library.Field{{Name: "PIN", Value: library.NewSecret("1234"), Protected: true}},
+			Attachments:  []library.Attachment{{Name: "key.txt", Data: library.NewSecret("attached")}},
+			Created:      created,
+			Modified:     created.Add(time.Hour),
+		},
+	}}
+
+	credentials := Credentials{Password: "correct horse", KeyFile: []byte("key file content")}
+	var file bytes.Buffer
+	err := NewDatabase(informerLibrary).Write(&file, credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Read(bytes.NewReader(file.Bytes()), Credentials{Password: "correct horse"})
+	if err != ErrCredentials {
+		t.Fatalf("expected ErrCredentials without key file, got %v", err)
+	}
+
+	database, err := Read(bytes.NewReader(file.Bytes()), credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secures := database.Secures()
+	secure, ok := secures["0b7c2e3a-6f0e-4c59-9a65-2f1d7c8b9e10"]
+	if !ok || len(secures) != 1 {
+		t.Fatalf("unexpected secures %v", secures)
+	}
+	if secure.ID != "GitHub" || secure.Username != "octocat" || secure.Platform != "github.com" || secure.Folder != "Work/Code" {
+		t.Errorf("unexpected secure %+v", secure)
+	}
+	if secure.Password.Reveal() != "hunter2" || secure.OTP.Reveal() != "JBSWY3DPEHPK3PXP" || secure.OTPType != "totp" ||
+		secure.Notes.Reveal() != "recovery codes are in the safe" {
+		t.Error("secrets differ after round trip")
+	}
+	if len(secure.Fields) != 1 || secure.Fields[0].Value.Reveal() != "1234" || !secure.Fields[0].Protected {
+		t.Errorf("unexpected fields %+v", secure.Fields)
+	}
+	if len(secure.Attachments) != 1 || secure.Attachments[0].Data.Reveal() != "attached" {
+		t.Errorf("unexpected attachments %+v", secure.Attachments)
+	}
+	if !secure.Created.Equal(created) || !secure.Modified.Equal(created.Add(time.Hour)) {
+		t.Errorf("unexpected times %v %v", secure.Created, secure.Modified)
+	}
+}
+
+func TestTransformKeyLimits(t *testing.T) {
+	argon2Parameters := func(memory uint64, iterations uint64, parallelism uint32) variantDictionary {
+		var parameters variantDictionary
+		parameters.setBytes("$UUID", kdfArgon2d)
+		parameters.setBytes("S", make([]byte, 32))
+		parameters.setUint32("V", argon2Version)
+		parameters.setUint64("M", memory*1024)
+		parameters.setUint64("I", iterations)
+		parameters.setUint32("P", parallelism)
+
+		return parameters
+	}
+	var aesParameters variantDictionary
+	aesParameters.setBytes("$UUID", kdfAES4)
+	aesParameters.setBytes("S", make([]byte, 32))
+	aesParameters.setUint64("R", maxAESRounds+1)
+
+	//Crafted parameters are refused before anything is allocated or computed
+	for name, parameters := range map[string]variantDictionary{
+		"memory":      argon2Parameters(1<<32-1, 2, 1),
+		"iterations":  argon2Parameters(64, 1<<32-1, 1),
+		"parallelism": argon2Parameters(1024, 2, maxArgon2Parallelism+1),
+		"rounds":      aesParameters,
+	} {
+		if _, err := transformKey(make([]byte, 32), parameters); err == nil {
+			t.Errorf("%s beyond limits accepted", name)
+		}
+	}
+
+	if _, err := transformKey(make([]byte, 32), argon2Parameters(64, 2, 2)); err != nil {
+		t.Error(err)
+	}
+}
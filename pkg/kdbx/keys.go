@@ -0,0 +1,173 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+var (
+	kdfAES3     = []byte{0xc9, 0xd9, 0xf3, 0x9a, 0x62, 0x8a, 0x44, 0x60, 0xbf, 0x74, 0x0d, 0x08, 0xc1, 0x8a, 0x4f, 0xea}
+	kdfAES4     = []byte{0x7c, 0x02, 0xbb, 0x82, 0x79, 0xa7, 0x4a, 0xc0, 0x92, 0x7d, 0x11, 0x4a, 0x00, 0x64, 0x82, 0x38}
+	kdfArgon2d  = []byte{0xef, 0x63, 0x6d, 0xdf, 0x8c, 0x29, 0x44, 0x4b, 0x91, 0xf7, 0xa9, 0xa4, 0x03, 0xe3, 0x0a, 0x0c}
+	kdfArgon2id = []byte{0x9e, 0x29, 0x8b, 0x19, 0x56, 0xdb, 0x47, 0x73, 0xb2, 0x3d, 0xfc, 0x3e, 0xc6, 0xf0, 0xa1, 0xe6}
+)
+
+// Limits of key derivation parameters, databases are untrusted input and must not take unbounded memory or time.
+// They are well above what KeePass and KeePassXC let users choose.
+const (
+	maxAESRounds         = 100000000
+	maxArgon2Memory      = 1 << 20 //KiB
+	maxArgon2Iterations  = 1000
+	maxArgon2Parallelism = 64
+)
+
+// Credentials Password and content of key file of a database, either may be empty.
+type Credentials struct {
+	Password string
+	KeyFile  []byte
+}
+
+// compositeKey Hash of every given credential
+func (credentials Credentials) compositeKey() ([]byte, error) {
+	if credentials.Password == "" && len(credentials.KeyFile) == 0 {
+		return nil, errors.New("password or key file is needed")
+	}
+
+	h := sha256.New()
+	if credentials.Password != "" {
+		passwordHash := sha256.Sum256([]byte(credentials.Password))
+		h.Write(passwordHash[:])
+	}
+	if len(credentials.KeyFile) > 0 {
+		keyFileKey, err := parseKeyFile(credentials.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		h.Write(keyFileKey)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// parseKeyFile Key of XML key files version 1.0 and 2.0, 32 bytes binary or 64 hex digits files,
+// and hash of any other file.
+func parseKeyFile(content []byte) ([]byte, error) {
+	var keyFile struct {
+		Meta struct {
+			Version string `xml:"Version"`
+		} `xml:"Meta"`
+		Key struct {
+			Data struct {
+				Hash  string `xml:"Hash,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"Data"`
+		} `xml:"Key"`
+	}
+	if xml.Unmarshal(content, &keyFile) == nil && keyFile.Key.Data.Value != "" {
+		data := strings.TrimSpace(keyFile.Key.Data.Value)
+		if strings.HasPrefix(keyFile.Meta.Version, "2.") {
+			key, err := hex.DecodeString(strings.Join(strings.Fields(data), ""))
+			if err != nil {
+				return nil, err
+			}
+
+			keyHash := sha256.Sum256(key)
+			if keyFile.Key.Data.Hash != "" && !strings.EqualFold(hex.EncodeToString(keyHash[:4]), keyFile.Key.Data.Hash) {
+				return nil, errors.New("key file is corrupted")
+			}
+
+			return key, nil
+		}
+
+		return base64.StdEncoding.DecodeString(data)
+	}
+
+	if len(content) == 32 {
+		return content, nil
+	}
+	if len(content) == 64 {
+		if key, err := hex.DecodeString(string(content)); err == nil {
+			return key, nil
+		}
+	}
+
+	keyHash := sha256.Sum256(content)
+
+	return keyHash[:], nil
+}
+
+// transformKey Run key derivation function given by KDF parameters on composite key
+func transformKey(compositeKey []byte, parameters variantDictionary) ([]byte, error) {
+	kdf := parameters.bytes("$UUID")
+	salt := parameters.bytes("S")
+
+	switch {
+	case bytes.Equal(kdf, kdfAES4), bytes.Equal(kdf, kdfAES3):
+		block, err := aes.NewCipher(salt)
+		if err != nil {
+			return nil, err
+		}
+
+		rounds := parameters.uint64("R")
+		if rounds > maxAESRounds {
+			return nil, errors.New("too many AES-KDF rounds")
+		}
+
+		key := append([]byte{}, compositeKey...)
+		for i := uint64(0); i < rounds; i++ {
+			block.Encrypt(key[:16], key[:16])
+			block.Encrypt(key[16:], key[16:])
+		}
+		transformed := sha256.Sum256(key)
+
+		return transformed[:], nil
+	case bytes.Equal(kdf, kdfArgon2d), bytes.Equal(kdf, kdfArgon2id):
+		if parameters.uint32("V") != argon2Version {
+			return nil, errors.New("unsupported argon2 version")
+		}
+
+		mode := argon2d
+		if bytes.Equal(kdf, kdfArgon2id) {
+			mode = argon2id
+		}
+		memory := parameters.uint64("M") / 1024
+		iterations := parameters.uint64("I")
+		parallelism := parameters.uint32("P")
+		if memory == 0 || iterations == 0 || parallelism == 0 {
+			return nil, errors.New("invalid argon2 parameters")
+		}
+		if memory > maxArgon2Memory || iterations > maxArgon2Iterations || parallelism > maxArgon2Parallelism {
+			return nil, errors.New("argon2 parameters exceed limits")
+		}
+
+		return argon2Key(mode, compositeKey, salt, parameters.bytes("K"), parameters.bytes("A"),
+			uint32(iterations), uint32(memory), parallelism, 32), nil
+	}
+
+	return nil, errors.New("unsupported key derivation function")
+}
+
+// databaseKeys Key of payload encryption, and base key of HMAC
+func databaseKeys(masterSeed []byte, transformedKey []byte) ([]byte, []byte) {
+	encryptionKey := sha256.Sum256(append(append([]byte{}, masterSeed...), transformedKey...))
+	hmacKey := sha512.Sum512(append(append(append([]byte{}, masterSeed...), transformedKey...), 1))
+
+	return encryptionKey[:], hmacKey[:]
+}
+
+// blockHMACKey HMAC key of block index, the header uses index 2^64-1
+func blockHMACKey(hmacKey []byte, index uint64) []byte {
+	var indexBytes [8]byte
+	binary.LittleEndian.PutUint64(indexBytes[:], index)
+	key := sha512.Sum512(append(indexBytes[:], hmacKey...))
+
+	return key[:]
+}
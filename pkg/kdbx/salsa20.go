@@ -0,0 +1,39 @@
+package kdbx
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/crypto/salsa20/salsa"
+)
+
+// salsa20Nonce Fixed nonce of Salsa20 inner random stream
+var salsa20Nonce = []byte{0xe8, 0x30, 0x09, 0x4b, 0x97, 0x20, 0x5d, 0x2a}
+
+// salsa20Stream Salsa20 key stream which may be consumed in pieces of any size
+type salsa20Stream struct {
+	key       [32]byte
+	counter   uint64
+	keyStream [64]byte
+	used      int
+}
+
+func newSalsa20Stream(key [32]byte) *salsa20Stream {
+	return &salsa20Stream{key: key, used: 64}
+}
+
+func (stream *salsa20Stream) XORKeyStream(dst []byte, src []byte) {
+	for i := range src {
+		if stream.used == 64 {
+			var input [16]byte
+			copy(input[:8], salsa20Nonce)
+			binary.LittleEndian.PutUint64(input[8:], stream.counter)
+			var zero [64]byte
+			salsa.XORKeyStream(stream.keyStream[:], zero[:], &input, &stream.key)
+			stream.counter++
+			stream.used = 0
+		}
+
+		dst[i] = src[i] ^ stream.keyStream[stream.used]
+		stream.used++
+	}
+}
@@ -0,0 +1,147 @@
+package kdbx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	variantVersion = 0x0100
+
+	variantEnd    = 0x00
+	variantUint32 = 0x04
+	variantUint64 = 0x05
+	variantBool   = 0x08
+	variantInt32  = 0x0c
+	variantInt64  = 0x0d
+	variantString = 0x18
+	variantBytes  = 0x42
+)
+
+// variantItem Typed value of a variant dictionary
+type variantItem struct {
+	name      string
+	valueType byte
+	value     []byte
+}
+
+// variantDictionary Ordered key value pairs, used for KDF parameters
+type variantDictionary []variantItem
+
+func parseVariantDictionary(content []byte) (variantDictionary, error) {
+	if len(content) < 2 {
+		return nil, errors.New("invalid KDF parameters")
+	}
+	if binary.LittleEndian.Uint16(content)&0xff00 != variantVersion&0xff00 {
+		return nil, errors.New("unsupported KDF parameters version")
+	}
+
+	var dictionary variantDictionary
+	reader := bytes.NewReader(content[2:])
+	for {
+		valueType, err := reader.ReadByte()
+		if err != nil {
+			return nil, errors.New("invalid KDF parameters")
+		}
+		if valueType == variantEnd {
+			return dictionary, nil
+		}
+
+		name, err := readSized(reader)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readSized(reader)
+		if err != nil {
+			return nil, err
+		}
+		dictionary = append(dictionary, variantItem{name: string(name), valueType: valueType, value: value})
+	}
+}
+
+func readSized(reader *bytes.Reader) ([]byte, error) {
+	var size int32
+	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil || size < 0 || int(size) > reader.Len() {
+		return nil, errors.New("invalid KDF parameters")
+	}
+
+	value := make([]byte, size)
+	_, err := reader.Read(value)
+
+	return value, err
+}
+
+func (dictionary variantDictionary) serialize() []byte {
+	var buffer bytes.Buffer
+	_ = binary.Write(&buffer, binary.LittleEndian, uint16(variantVersion))
+	for _, item := range dictionary {
+		buffer.WriteByte(item.valueType)
+		_ = binary.Write(&buffer, binary.LittleEndian, int32(len(item.name)))
+		buffer.WriteString(item.name)
+		_ = binary.Write(&buffer, binary.LittleEndian, int32(len(item.value)))
+		buffer.Write(item.value)
+	}
+	buffer.WriteByte(variantEnd)
+
+	return buffer.Bytes()
+}
+
+func (dictionary variantDictionary) get(name string, valueType byte) []byte {
+	for _, item := range dictionary {
+		if item.name == name && item.valueType == valueType {
+			return item.value
+		}
+	}
+
+	return nil
+}
+
+func (dictionary *variantDictionary) set(name string, valueType byte, value []byte) {
+	for i, item := range *dictionary {
+		if item.name == name {
+			(*dictionary)[i] = variantItem{name: name, valueType: valueType, value: value}
+			return
+		}
+	}
+
+	*dictionary = append(*dictionary, variantItem{name: name, valueType: valueType, value: value})
+}
+
+func (dictionary variantDictionary) bytes(name string) []byte {
+	return dictionary.get(name, variantBytes)
+}
+
+func (dictionary variantDictionary) uint32(name string) uint32 {
+	value := dictionary.get(name, variantUint32)
+	if len(value) != 4 {
+		return 0
+	}
+
+	return binary.LittleEndian.Uint32(value)
+}
+
+func (dictionary variantDictionary) uint64(name string) uint64 {
+	value := dictionary.get(name, variantUint64)
+	if len(value) != 8 {
+		return 0
+	}
+
+	return binary.LittleEndian.Uint64(value)
+}
+
+func (dictionary *variantDictionary) setBytes(name string, value []byte) {
+	dictionary.set(name, variantBytes, value)
+}
+
+func (dictionary *variantDictionary) setUint32(name string, value uint32) {
+	encoded := make([]byte, 4)
+	binary.LittleEndian.PutUint32(encoded, value)
+	dictionary.set(name, variantUint32, encoded)
+}
+
+func (dictionary *variantDictionary) setUint64(name string, value uint64) {
+	encoded := make([]byte, 8)
+	binary.LittleEndian.PutUint64(encoded, value)
+	dictionary.set(name, variantUint64, encoded)
+}
@@ -0,0 +1,212 @@
+package kdbx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+// Meta Database properties.
+type Meta struct {
+	Generator    string `xml:"Generator"`
+	DatabaseName string `xml:"DatabaseName"`
+}
+
+// Group Folder of entries and groups.
+type Group struct {
+	UUID    string  `xml:"UUID"`
+	Name    string  `xml:"Name"`
+	Times   Times   `xml:"Times"`
+	Entries []Entry `xml:"Entry"`
+	Groups  []Group `xml:"Group"`
+}
+
+// Entry Entry with its fields, previous versions in history are not kept.
+type Entry struct {
+	UUID     string   `xml:"UUID"`
+	Times    Times    `xml:"Times"`
+	Strings  []String `xml:"String"`
+	Binaries []Binary `xml:"Binary"`
+}
+
+// Times Creation and modification time of an entry or group.
+type Times struct {
+	CreationTime         Time `xml:"CreationTime"`
+	LastModificationTime Time `xml:"LastModificationTime"`
+}
+
+// String Named field of entry, such as Title, UserName, Password, URL, Notes or a custom one.
+type String struct {
+	Key   string `xml:"Key"`
+	Value Value  `xml:"Value"`
+}
+
+// Value Field value, Protected is "True" for values which were encrypted by inner random stream.
+type Value struct {
+	Protected string `xml:"Protected,attr,omitempty"`
+	Content   string `xml:",chardata"`
+}
+
+// Binary Attachment of entry, Ref is index of Database.Binaries.
+type Binary struct {
+	Key   string `xml:"Key"`
+	Value struct {
+		Ref int `xml:"Ref,attr"`
+	} `xml:"Value"`
+}
+
+// Time Time stored as seconds since 0001-01-01 UTC in base64, or as ISO 8601 in older files.
+type Time struct {
+	time.Time
+}
+
+type keePassFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    Meta     `xml:"Meta"`
+	Root    struct {
+		Group Group `xml:"Group"`
+	} `xml:"Root"`
+}
+
+// timeEpoch Time of zero seconds in KDBX 4
+var timeEpoch = time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func (t Time) MarshalText() ([]byte, error) {
+	var seconds [8]byte
+	binary.LittleEndian.PutUint64(seconds[:], uint64(t.UTC().Unix()-timeEpoch.Unix()))
+
+	return []byte(base64.StdEncoding.EncodeToString(seconds[:])), nil
+}
+
+func (t *Time) UnmarshalText(text []byte) error {
+	if parsed, err := time.Parse(time.RFC3339, string(text)); err == nil {
+		t.Time = parsed
+		return nil
+	}
+
+	seconds, err := base64.StdEncoding.DecodeString(string(text))
+	if err != nil || len(seconds) != 8 {
+		//Broken times are ignored instead of refusing the whole database
+		t.Time = time.Time{}
+		return nil
+	}
+	t.Time = time.Unix(int64(binary.LittleEndian.Uint64(seconds))+timeEpoch.Unix(), 0).UTC()
+
+	return nil
+}
+
+// Get Value of field key, empty if entry doesn't have it.
+func (entry Entry) Get(key string) string {
+	for _, s := range entry.Strings {
+		if s.Key == key {
+			return s.Value.Content
+		}
+	}
+
+	return ""
+}
+
+func (database *Database) parseXML(document []byte, stream innerStream) error {
+	document, err := transformProtected(document, func(content string) (string, error) {
+		encrypted, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return "", err
+		}
+		stream.XORKeyStream(encrypted, encrypted)
+
+		return string(encrypted), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	file := keePassFile{}
+	err = xml.Unmarshal(document, &file)
+	if err != nil {
+		return err
+	}
+	database.Meta = file.Meta
+	database.Root = file.Root.Group
+
+	return nil
+}
+
+func (database *Database) serializeXML(stream innerStream) ([]byte, error) {
+	file := keePassFile{Meta: database.Meta}
+	file.Root.Group = database.Root
+
+	document, err := xml.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err = transformProtected(document, func(content string) (string, error) {
+		encrypted := []byte(content)
+		stream.XORKeyStream(encrypted, encrypted)
+
+		return base64.StdEncoding.EncodeToString(encrypted), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), document...), nil
+}
+
+// transformProtected Replace content of every protected value in document order,
+// which is the order the inner random stream has to be consumed in.
+func transformProtected(document []byte, transform func(string) (string, error)) ([]byte, error) {
+	var output bytes.Buffer
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	encoder := xml.NewEncoder(&output)
+
+	protected := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			protected = false
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "Protected" && strings.EqualFold(attr.Value, "True") {
+					protected = true
+				}
+			}
+		case xml.EndElement:
+			protected = false
+		case xml.CharData:
+			if protected {
+				content, err := transform(string(t))
+				if err != nil {
+					return nil, err
+				}
+				token = xml.CharData(content)
+			}
+		case xml.ProcInst:
+			//Header is written again by caller
+			continue
+		}
+
+		err = encoder.EncodeToken(xml.CopyToken(token))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := encoder.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Bytes(), nil
+}
@@ -0,0 +1,50 @@
+package library
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Difference A secure added, removed, or a field of it changed. Field is empty for added and removed secures.
+type Difference struct {
+	PrimaryKey string `json:"primaryKey"`
+	ID         string `json:"id"`
+	Field      string `json:"field"`
+	Old        string `json:"old"`
+	New        string `json:"new"`
+}
+
+// Diff Differences between two versions of a library, secrets are redacted.
+// Encrypted secrets differ after every Lock, so libraries should be unlocked to compare them.
+func Diff(before InformerLibrary, after InformerLibrary) []Difference {
+	var differences []Difference
+
+	for _, k := range primaryKeys(before.SecureStore, after.SecureStore) {
+		oldSecure, newSecure := before.SecureStore[k], after.SecureStore[k]
+
+		switch {
+		case oldSecure == nil:
+			differences = append(differences, Difference{PrimaryKey: k, ID: newSecure.ID, Old: "absent", New: "added"})
+		case newSecure == nil:
+			differences = append(differences, Difference{PrimaryKey: k, ID: oldSecure.ID, Old: "present", New: "removed"})
+		default:
+			oldValue, newValue := reflect.ValueOf(oldSecure).Elem(), reflect.ValueOf(newSecure).Elem()
+			for i := 0; i < oldValue.NumField(); i++ {
+				field := fieldName(oldValue.Type().Field(i))
+				if bookkeepingField(oldValue.Type().Field(i)) || fieldEqual(oldValue.Field(i), newValue.Field(i)) {
+					continue
+				}
+
+				differences = append(differences, Difference{
+					PrimaryKey: k,
+					ID:         newSecure.ID,
+					Field:      field,
+					Old:        fmt.Sprint(oldValue.Field(i).Interface()),
+					New:        fmt.Sprint(newValue.Field(i).Interface()),
+				})
+			}
+		}
+	}
+
+	return differences
+}
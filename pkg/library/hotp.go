@@ -0,0 +1,85 @@
+package library
+
+import (
+	"errors"
+	"time"
+
+	"junjie.pro/informer/pkg/otp"
+)
+
+// IssueHOTPCode Generate HOTP code of secure k of informerLibrary unlocked by key, and persist the following counter.
+// The counter is read from library file, not from informerLibrary, as another code may have been issued since
+// the library was read, such as by the server while the CLI runs.
+func IssueHOTPCode(informerLibrary *InformerLibrary, key []byte, k string) (string, error) {
+	var code string
+	err := informerLibrary.updateOTPCounter(key, k, func(secure *SecureStore) (uint64, error) {
+		var err error
+		code, err = otp.GenerateCode(secure.OTPAccount(), time.Now())
+
+		return secure.OTPCounter + 1, err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// SetOTPCounter Persist HOTP counter of secure k of informerLibrary unlocked by key, such as one found by resynchronization
+func SetOTPCounter(informerLibrary *InformerLibrary, key []byte, k string, counter uint64) error {
+	return informerLibrary.updateOTPCounter(key, k, func(*SecureStore) (uint64, error) {
+		return counter, nil
+	})
+}
+
+// updateOTPCounter Set HOTP counter of secure k to the one given by next, in library file and in informerLibrary.
+// Library file stays locked while it's read again, unlocked and locked by key, and written, so the set key opens
+// is the one updated, whether it's the visible or the sealed set.
+func (informerLibrary *InformerLibrary) updateOTPCounter(key []byte, k string, next func(*SecureStore) (uint64, error)) error {
+	unlock, err := lockLibraryFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stored, err := ReadLibrary()
+	if err != nil {
+		return err
+	}
+	readDigest := stored.readDigest
+	err = stored.Unlock(key)
+	if err != nil {
+		return err
+	}
+	defer stored.Wipe()
+	secure, ok := stored.SecureStore[k]
+	if !ok {
+		return errors.New("secure not found")
+	}
+
+	//Counters are no changes to sync, they only move forward, so syncing never takes an older one
+	counter, err := next(secure)
+	if err != nil {
+		return err
+	}
+	secure.OTPCounter = counter
+
+	err = stored.Lock(key)
+	if err != nil {
+		return err
+	}
+	err = stored.writeLibrary()
+	if err != nil {
+		return err
+	}
+
+	if current, ok := informerLibrary.SecureStore[k]; ok {
+		current.OTPCounter = counter
+	}
+	//informerLibrary read the file as it was, with the new counter it's still as written
+	if informerLibrary.readFrom == stored.readFrom && informerLibrary.readDigest == readDigest {
+		informerLibrary.readDigest = stored.readDigest
+	}
+
+	return nil
+}
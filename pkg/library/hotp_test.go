@@ -0,0 +1,177 @@
+package library
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const testHOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// testDataHome Point library file to a temporary directory, the returned function restores it
+func testDataHome(t *testing.T) func() {
+	dataHome, err := ioutil.TempDir("", "informer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataHomeBefore := os.Getenv("XDG_DATA_HOME")
+	os.Setenv("XDG_DATA_HOME", dataHome)
+	err = os.MkdirAll(filepath.Join(dataHome, "informer"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		os.Setenv("XDG_DATA_HOME", dataHomeBefore)
+		os.RemoveAll(dataHome)
+	}
+}
+
+func TestIssueHOTPCode(t *testing.T) {
+	defer testDataHome(t)()
+
+	informerLibrary := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	informerLibrary.Add(SecureStore{ID: "bank", OTP: NewSecret(testHOTPSecret), OTPType: "hotp"})
+	k := ""
+	for primaryKey := range informerLibrary.SecureStore {
+		k = primaryKey
+	}
+	err := informerLibrary.Lock(testKey)
+	if err == nil {
+		err = informerLibrary.WriteLibrary()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unlocked, err := ReadLibrary()
+	if err == nil {
+		err = unlocked.Unlock(testKey)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Codes issued at once never share a counter
+	codes := map[string]bool{}
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			code, err := IssueHOTPCode(&unlocked, testKey, k)
+			if err != nil {
+				t.Error(err)
+			}
+			mutex.Lock()
+			codes[code] = true
+			mutex.Unlock()
+		}()
+	}
+	wait.Wait()
+
+	//RFC 4226 codes of counters 0 to 4
+	for _, code := range []string{"755224", "287082", "359152", "969429", "338314"} {
+		if !codes[code] {
+			t.Errorf("code %s wasn't issued, issued %v", code, codes)
+		}
+	}
+	stored, err := ReadLibrary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.SecureStore[k].OTPCounter != 5 || unlocked.SecureStore[k].OTPCounter != 5 {
+		t.Errorf("counter is %d, expected 5", stored.SecureStore[k].OTPCounter)
+	}
+	if stored.Revision != informerLibrary.Revision || len(stored.Changes) != len(informerLibrary.Changes) {
+		t.Errorf("issuing codes recorded changes, revision %d", stored.Revision)
+	}
+
+	//Editing keeps the counter, resynchronizing sets it
+	err = unlocked.Edit(k, SecureStore{Username: "alice", OTPCounter: 1})
+	if err != nil || unlocked.SecureStore[k].OTPCounter != 5 {
+		t.Errorf("counter is %d after editing, %v", unlocked.SecureStore[k].OTPCounter, err)
+	}
+	err = SetOTPCounter(&unlocked, testKey, k, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err = ReadLibrary()
+	if err != nil || stored.SecureStore[k].OTPCounter != 42 {
+		t.Errorf("counter isn't resynchronized, %v", err)
+	}
+
+	//The library codes were issued by is still as written, a library read before is outdated
+	err = unlocked.Lock(testKey)
+	if err == nil {
+		err = unlocked.WriteLibrary()
+	}
+	if err != nil {
+		t.Errorf("write after issuing codes: %v", err)
+	}
+	err = informerLibrary.WriteLibrary()
+	if err != ErrLibraryChanged {
+		t.Errorf("outdated library written, %v", err)
+	}
+}
+
+func TestIssueConcealedHOTPCode(t *testing.T) {
+	defer testDataHome(t)()
+
+	informerLibrary := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	informerLibrary.Add(SecureStore{ID: "bank", OTP: NewSecret(testHOTPSecret), OTPType: "hotp"})
+	k := ""
+	for primaryKey := range informerLibrary.SecureStore {
+		k = primaryKey
+	}
+	err := informerLibrary.Conceal(testKey, testDuressKey)
+	if err == nil {
+		err = informerLibrary.WriteLibrary()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Secures of the sealed set issue codes like visible ones
+	unlocked, err := ReadLibrary()
+	if err == nil {
+		err = unlocked.Unlock(testKey)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := IssueHOTPCode(&unlocked, testKey, k)
+	if err != nil || code != "755224" {
+		t.Fatalf("code %q, %v", code, err)
+	}
+
+	stored, err := ReadLibrary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.SecureStore) != 0 {
+		t.Error("sealed secure written to the visible set")
+	}
+	err = stored.Unlock(testKey)
+	if err != nil || stored.SecureStore[k].OTPCounter != 1 {
+		t.Errorf("counter of sealed secure isn't persisted, %v", err)
+	}
+}
+
+func TestNormalizeHOTP(t *testing.T) {
+	//A test code of a counter based type would use up the counter
+	secure := SecureStore{ID: "bank", OTP: NewSecret(testHOTPSecret), OTPType: "HOTP"}
+	code, err := secure.NormalizeOTP()
+	if err != nil || code != "" || secure.OTPType != "hotp" {
+		t.Errorf("test code %q of %s, %v", code, secure.OTPType, err)
+	}
+
+	secure = SecureStore{ID: "mail", OTP: NewSecret(testHOTPSecret)}
+	code, err = secure.NormalizeOTP()
+	if err != nil || len(code) != 6 {
+		t.Errorf("test code %q of totp, %v", code, err)
+	}
+}
@@ -4,26 +4,81 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"io"
 	"io/ioutil"
+	"junjie.pro/informer/pkg/history"
+	"junjie.pro/informer/pkg/otp"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
 var (
 	dataDefault = InformerLibrary{Version: "0.1", Unlocked: true}
+
+	// ErrWrongKey Key opens neither the sealed set nor the secures of library
+	ErrWrongKey = errors.New("key doesn't unlock library")
+	// ErrLibraryChanged Library file was written by someone else since it was read, writing would undo that
+	ErrLibraryChanged = errors.New("library changed since it was read, try again")
+)
+
+// absentDigest Digest of a library file which didn't exist when library was read
+const absentDigest = "absent"
+
+// keyCheckText Plain text of KeyCheck
+const keyCheckText = "informer key check"
+
+const (
+	//Plain text size of the sealed set is rounded up to a power of two of sealedBlockSize at least,
+	//so small hidden sets and random filler can't be told apart, and larger ones only by their bucket
+	sealedBlockSize = 16 * 1024
+	gcmNonceSize    = 12
+	gcmTagSize      = 16
 )
 
 type InformerLibrary struct {
 	Version     string                  `json:"version" yaml:"version"`
 	Unlocked    bool                    `json:"unlocked" yaml:"unlocked"`
+	Revision    uint64                  `json:"revision" yaml:"revision"`
+	Changes     []Change                `json:"changes" yaml:"changes"`
 	SecureStore map[string]*SecureStore `json:"libraries" yaml:"libraries"`
+	//Sealed holds a second set of secures encrypted as a whole, or random filler of the same size
+	Sealed string `json:"-" yaml:"sealed"`
+	//KeyCheck is a known text encrypted by the key of the visible set, so any other key is refused even if it has no secures
+	KeyCheck string    `json:"-" yaml:"key-check,omitempty"`
+	Upstream *Upstream `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+	//PlatformRules are password rules by platform, for secures without rules of their own.
+	//They belong to the set of secures, so the sealed set keeps its own.
+	PlatformRules map[string]string `json:"platformRules,omitempty" yaml:"platform-rules,omitempty"`
+
+	//concealed is true while SecureStore holds the sealed set, decoy keeps the visible set meanwhile
+	concealed bool
+	decoy     secureSet
+	//notes describe changes since reading, used as history message
+	notes []string
+	//readFrom and readDigest are the file library was read from and its digest, a changed file is never overwritten
+	readFrom   string
+	readDigest string
+}
+
+// secureSet Secures with their revisions, the visible and the sealed set each have one
+type secureSet struct {
+	Revision      uint64                  `yaml:"revision"`
+	Changes       []Change                `yaml:"changes"`
+	SecureStore   map[string]*SecureStore `yaml:"libraries"`
+	PlatformRules map[string]string       `yaml:"platform-rules,omitempty"`
 }
 
 type SecureStore struct {
@@ -31,13 +86,188 @@ type SecureStore struct {
 	Platform     string `json:"platform" yaml:"platform"`
 	FriendlyName string `json:"friendlyName" yaml:"friendly-name"`
 	Username     string `json:"username" yaml:"username"`
-	Password     string `json:"password" yaml:"password"`
-	OTP          string `json:"otp" yaml:"otp"`
+	Password     Secret `json:"password" yaml:"password"`
+	OTP          Secret `json:"otp" yaml:"otp"`
 	OTPType      string `json:"otpType" yaml:"otp-type"`
+	Revision     uint64 `json:"revision" yaml:"revision"`
+
+	//OTP parameters, zero values are defaults of SHA1, 6 digits and period of 30 seconds
+	OTPIssuer    string `json:"otpIssuer,omitempty" yaml:"otp-issuer,omitempty"`
+	OTPAlgorithm string `json:"otpAlgorithm,omitempty" yaml:"otp-algorithm,omitempty"`
+	OTPDigits    int    `json:"otpDigits,omitempty" yaml:"otp-digits,omitempty"`
+	OTPPeriod    int    `json:"otpPeriod,omitempty" yaml:"otp-period,omitempty"`
+	OTPCounter   uint64 `json:"otpCounter,omitempty" yaml:"otp-counter,omitempty"`
+	//OTPPin is the PIN of mOTP, encrypted like the password
+	OTPPin Secret `json:"otpPin,omitempty" yaml:"otp-pin,omitempty"`
+
+	//PasswordRules are rules of generated passwords in passwordrules syntax, they override rules of platform
+	PasswordRules string `json:"passwordRules,omitempty" yaml:"password-rules,omitempty"`
+
+	URL         string       `json:"url,omitempty" yaml:"url,omitempty"`
+	Folder      string       `json:"folder,omitempty" yaml:"folder,omitempty"`
+	Notes       Secret       `json:"notes,omitempty" yaml:"notes,omitempty"`
+	Fields      []Field      `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty" yaml:"attachments,omitempty"`
+	Created     time.Time    `json:"created" yaml:"created,omitempty"`
+	Modified    time.Time    `json:"modified" yaml:"modified,omitempty"`
+}
+
+// Field Custom field of a secure, its value is encrypted like the password.
+// Protected fields should be hidden when shown.
+type Field struct {
+	Name      string `json:"name" yaml:"name"`
+	Value     Secret `json:"value" yaml:"value"`
+	Protected bool   `json:"protected,omitempty" yaml:"protected,omitempty"`
+}
+
+// Attachment File attached to a secure, its data is encrypted like the password.
+type Attachment struct {
+	Name string `json:"name" yaml:"name"`
+	Data Secret `json:"data" yaml:"data"`
+}
+
+// OTPAccount OTP configuration of secure. Issuer defaults to platform, the account name is the username.
+func (secure SecureStore) OTPAccount() otp.Account {
+	issuer := secure.OTPIssuer
+	if issuer == "" {
+		issuer = secure.Platform
+	}
+
+	return otp.Account{
+		Issuer:    issuer,
+		Name:      secure.Username,
+		Secret:    secure.OTP.Reveal(),
+		Type:      secure.OTPType,
+		Algorithm: secure.OTPAlgorithm,
+		Digits:    secure.OTPDigits,
+		Period:    secure.OTPPeriod,
+		Counter:   secure.OTPCounter,
+		Pin:       secure.OTPPin.Reveal(),
+	}
+}
+
+// SetOTPAccount Keep secret and parameters of OTP account, such as one parsed from an otpauth URI
+func (secure *SecureStore) SetOTPAccount(account otp.Account) {
+	secure.OTP = NewSecret(account.Secret)
+	secure.OTPType = account.Type
+	secure.OTPIssuer = account.Issuer
+	secure.OTPAlgorithm = account.Algorithm
+	secure.OTPDigits = account.Digits
+	secure.OTPPeriod = account.Period
+	secure.OTPCounter = account.Counter
+	secure.OTPPin = NewSecret(account.Pin)
+}
+
+// NormalizeOTP Normalize OTP secret of secure, an otpauth URI sets the parameters too, and return its current code
+// for confirming against the provider. Secures without OTP are left as they are. Counter based types return no code,
+// as showing one would use up its counter without persisting it.
+func (secure *SecureStore) NormalizeOTP() (string, error) {
+	if len(secure.OTP) == 0 {
+		return "", nil
+	}
+
+	account, err := otp.ParseSecret(secure.OTP.Reveal(), secure.OTPType)
+	if err != nil {
+		return "", err
+	}
+	secure.OTP.Wipe()
+	if account.Type != "" {
+		account.Pin = secure.OTPPin.Reveal()
+		secure.SetOTPAccount(account)
+	} else {
+		secure.OTP = NewSecret(account.Secret)
+	}
+	secure.OTPType = strings.ToLower(secure.OTPType)
+	if secure.OTPType == "" {
+		secure.OTPType = "totp"
+	}
+	if otp.CounterBased(secure.OTPType) {
+		return "", nil
+	}
+
+	return otp.GenerateCode(secure.OTPAccount(), time.Now())
+}
+
+// secrets Every encrypted value of secure
+func (secure *SecureStore) secrets() []*Secret {
+	secrets := []*Secret{&secure.Password, &secure.OTP, &secure.OTPPin, &secure.Notes}
+	for i := range secure.Fields {
+		secrets = append(secrets, &secure.Fields[i].Value)
+	}
+	for i := range secure.Attachments {
+		secrets = append(secrets, &secure.Attachments[i].Data)
+	}
+
+	return secrets
+}
+
+// Wipe Overwrite decrypted secrets of secure in memory, such as a copy returned by Query or List.
+func (secure *SecureStore) Wipe() {
+	for _, secret := range secure.secrets() {
+		secret.Wipe()
+	}
+}
+
+// WipeCopies Wipe secures returned by Query or List.
+func WipeCopies(secures map[string]SecureStore) {
+	for _, secure := range secures {
+		secure.Wipe()
+	}
+}
+
+// copySecures Copies of secures made by copySecure
+func copySecures(secures map[string]*SecureStore) map[string]*SecureStore {
+	if secures == nil {
+		return nil
+	}
+
+	copied := make(map[string]*SecureStore, len(secures))
+	for k, secure := range secures {
+		copied[k] = copySecure(secure)
+	}
+
+	return copied
+}
+
+// Copy Copy of library sharing no secrets with it, wiping or locking either leaves the other as it is.
+func (informerLibrary InformerLibrary) Copy() InformerLibrary {
+	copied := informerLibrary
+	copied.Changes = append([]Change(nil), informerLibrary.Changes...)
+	copied.SecureStore = copySecures(informerLibrary.SecureStore)
+	copied.decoy.Changes = append([]Change(nil), informerLibrary.decoy.Changes...)
+	copied.decoy.SecureStore = copySecures(informerLibrary.decoy.SecureStore)
+	copied.notes = append([]string(nil), informerLibrary.notes...)
+	if informerLibrary.Upstream != nil {
+		upstream := *informerLibrary.Upstream
+		if upstream.Revisions != nil {
+			upstream.Revisions = map[string]uint64{}
+			for k, revision := range informerLibrary.Upstream.Revisions {
+				upstream.Revisions[k] = revision
+			}
+		}
+		copied.Upstream = &upstream
+	}
+	copied.PlatformRules = copyRules(informerLibrary.PlatformRules)
+	copied.decoy.PlatformRules = copyRules(informerLibrary.decoy.PlatformRules)
+
+	return copied
+}
+
+func copyRules(platformRules map[string]string) map[string]string {
+	if platformRules == nil {
+		return nil
+	}
+
+	copied := map[string]string{}
+	for platform, rules := range platformRules {
+		copied[platform] = rules
+	}
+
+	return copied
 }
 
 func ReadLibrary() (InformerLibrary, error) {
-	dataLocation, err := dataPath()
+	dataLocation, err := DataPath()
 	if err != nil {
 		return InformerLibrary{}, err
 	}
@@ -54,16 +284,35 @@ func ReadLibrary() (InformerLibrary, error) {
 	//If data file doesn't not exists, return default data
 	if _, err := os.Stat(dataLocation); os.IsNotExist(err) {
 		log.Println("Data file not exists, using default data")
-		return dataDefault, nil
+		informerLibrary := dataDefault
+		informerLibrary.SecureStore = map[string]*SecureStore{}
+		informerLibrary.readFrom, informerLibrary.readDigest = dataLocation, absentDigest
+		return informerLibrary, nil
+	}
+
+	return ReadLibraryFile(dataLocation)
+}
+
+// ReadLibraryFile Read library from given location, such as a copy made by file synchronization.
+func ReadLibraryFile(location string) (InformerLibrary, error) {
+	libraryFile, err := ioutil.ReadFile(location)
+	if err != nil {
+		return InformerLibrary{}, err
 	}
 
-	libraryFile, err := ioutil.ReadFile(dataLocation)
+	informerLibrary, err := ParseLibrary(libraryFile)
 	if err != nil {
 		return InformerLibrary{}, err
 	}
+	informerLibrary.readFrom, informerLibrary.readDigest = location, digest(libraryFile)
 
+	return informerLibrary, nil
+}
+
+// ParseLibrary Parse content of library file.
+func ParseLibrary(libraryFile []byte) (InformerLibrary, error) {
 	informerLibrary := InformerLibrary{}
-	err = yaml.Unmarshal(libraryFile, &informerLibrary)
+	err := yaml.Unmarshal(libraryFile, &informerLibrary)
 	if err != nil {
 		return InformerLibrary{}, err
 	}
@@ -75,26 +324,198 @@ func ReadLibrary() (InformerLibrary, error) {
 	return informerLibrary, nil
 }
 
-func (informerLibrary InformerLibrary) WriteLibrary() error {
-	dataLocation, err := dataPath()
+// WriteLibrary Write library to data file, and commit it if history is enabled. Library file stays locked meanwhile,
+// and if the file changed since library was read, ErrLibraryChanged is returned instead.
+func (informerLibrary *InformerLibrary) WriteLibrary() error {
+	unlock, err := lockLibraryFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return informerLibrary.writeLibrary()
+}
+
+// writeLibrary Write library like WriteLibrary, lockLibraryFile must be held
+func (informerLibrary *InformerLibrary) writeLibrary() error {
+	dataLocation, err := DataPath()
+	if err != nil {
+		return err
+	}
+
+	err = informerLibrary.writeLibraryFile(dataLocation)
+	if err != nil {
+		return err
+	}
+
+	//If data directory is a git repository, commit every write
+	message := "Update library"
+	if len(informerLibrary.notes) > 0 {
+		message = strings.Join(informerLibrary.notes, "\n")
+	}
+
+	return informerLibrary.commitHistory(dataLocation, message)
+}
+
+// CommitHistory Commit library file if history is enabled, such as after history is started
+func CommitHistory(message string) error {
+	unlock, err := lockLibraryFile()
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
+	dataLocation, err := DataPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dataLocation); os.IsNotExist(err) {
+		return nil
+	}
+	informerLibrary, err := ReadLibraryFile(dataLocation)
+	if err != nil {
+		return err
+	}
+
+	return informerLibrary.commitHistory(dataLocation, message)
+}
+
+// commitHistory Commit library written to dataLocation if history is enabled. The sealed part is left out,
+// it would tell when the sealed set is written, and it's never restored from history either.
+func (informerLibrary InformerLibrary) commitHistory(dataLocation string, message string) error {
+	dataDir, dataFile := filepath.Split(dataLocation)
+	if !history.Enabled(dataDir) {
+		return nil
+	}
+
+	informerLibrary.Sealed = ""
 	data, err := yaml.Marshal(informerLibrary)
 	if err != nil {
 		return err
 	}
 
-	err = ioutil.WriteFile(dataLocation, data, os.FileMode(0600))
+	return history.Commit(dataDir, dataFile, data, message)
+}
+
+// Restore Take secures of restored, such as a library from history, in place of secures of library.
+// The sealed part is kept, history never holds it.
+func (informerLibrary *InformerLibrary) Restore(restored InformerLibrary, note string) {
+	restored.Sealed = informerLibrary.Sealed
+	restored.readFrom, restored.readDigest = informerLibrary.readFrom, informerLibrary.readDigest
+	restored.notes = []string{note}
+	*informerLibrary = restored
+}
+
+// WriteLibraryFile Write library to given location. The file is replaced at once, readers see either the old or the new library.
+// Like WriteLibrary, library file stays locked and a file changed since library was read from it isn't overwritten.
+func (informerLibrary *InformerLibrary) WriteLibraryFile(location string) error {
+	unlock, err := lockLibraryFile()
 	if err != nil {
 		return err
 	}
+	defer unlock()
+
+	return informerLibrary.writeLibraryFile(location)
+}
+
+// writeLibraryFile Write library like WriteLibraryFile, lockLibraryFile must be held
+func (informerLibrary *InformerLibrary) writeLibraryFile(location string) error {
+	//Never write the sealed set in place of the visible one
+	if informerLibrary.concealed {
+		return errors.New("library must be locked before writing")
+	}
+
+	//Written by someone else since it was read, such as a code issued by the server while the CLI runs
+	if informerLibrary.readFrom == location {
+		current := absentDigest
+		if libraryFile, err := ioutil.ReadFile(location); err == nil {
+			current = digest(libraryFile)
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		if current != informerLibrary.readDigest {
+			return ErrLibraryChanged
+		}
+	}
+
+	//Every library carries a sealed part, whether a second set exists or not
+	if informerLibrary.Sealed == "" {
+		filler, err := sealedFiller()
+		if err != nil {
+			return err
+		}
+		informerLibrary.Sealed = filler
+	}
+
+	data, err := yaml.Marshal(informerLibrary)
+	if err != nil {
+		return err
+	}
+
+	//Write a temporary file beside library, then rename it over library
+	file, err := ioutil.TempFile(filepath.Dir(location), ".libraries-*.yaml")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), os.FileMode(0600))
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), location)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	informerLibrary.readFrom, informerLibrary.readDigest = location, digest(data)
 
 	return nil
 }
 
-func dataPath() (string, error) {
+// lockLibraryFile Lock a file beside library file, so processes never update library at once.
+// The returned function releases the lock.
+func lockLibraryFile() (func(), error) {
+	dataLocation, err := DataPath()
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(dataLocation), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(dataLocation+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = lockFile(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return func() {
+		_ = unlockFile(file)
+		_ = file.Close()
+	}, nil
+}
+
+// digest Digest of content of library file
+func digest(libraryFile []byte) string {
+	sum := sha256.Sum256(libraryFile)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// DataPath Location of library file, its directory may be a git repository keeping history.
+func DataPath() (string, error) {
 	dataPath := os.Getenv("XDG_DATA_HOME")
 	if dataPath == "" {
 		homeDir, err := os.UserHomeDir()
@@ -110,19 +531,42 @@ func dataPath() (string, error) {
 }
 
 func (informerLibrary *InformerLibrary) Lock(key []byte) error {
+	//Seal the hidden set again and bring back the visible one
+	if informerLibrary.Unlocked && informerLibrary.concealed {
+		sealed, err := seal(key, informerLibrary.secureSet())
+		if err != nil {
+			return err
+		}
+
+		wipeSecures(informerLibrary.SecureStore)
+		informerLibrary.Sealed = sealed
+		informerLibrary.setSecureSet(informerLibrary.decoy)
+		informerLibrary.decoy = secureSet{}
+		informerLibrary.concealed = false
+		informerLibrary.Unlocked = false
+		return nil
+	}
+
 	if informerLibrary.Unlocked {
-		for k, v := range informerLibrary.SecureStore {
-			encryptedPassword, err := encrypt(key, v.Password)
-			if err != nil {
-				return err
+		for _, v := range informerLibrary.SecureStore {
+			for _, secret := range v.secrets() {
+				encrypted, err := encrypt(key, *secret)
+				if err != nil {
+					return err
+				}
+
+				secret.Wipe()
+				*secret = Secret(encrypted)
 			}
-			encryptedOTP, err := encrypt(key, v.OTP)
+		}
+
+		//Key check is only renewed for a new key, so writes by the same key leave it as it is
+		if _, err := decrypt(key, informerLibrary.KeyCheck); informerLibrary.KeyCheck == "" || err != nil {
+			keyCheck, err := encrypt(key, []byte(keyCheckText))
 			if err != nil {
 				return err
 			}
-
-			informerLibrary.SecureStore[k].Password = encryptedPassword
-			informerLibrary.SecureStore[k].OTP = encryptedOTP
+			informerLibrary.KeyCheck = keyCheck
 		}
 		informerLibrary.Unlocked = false
 		return nil
@@ -136,18 +580,32 @@ func (informerLibrary *InformerLibrary) Unlock(key []byte) error {
 		return nil
 	}
 
-	for k, v := range informerLibrary.SecureStore {
-		decryptedPassword, err := decrypt(key, v.Password)
-		if err != nil {
-			return err
-		}
-		decryptedOTP, err := decrypt(key, v.OTP)
-		if err != nil {
-			return err
+	//If key opens the sealed set, it takes the place of the visible set until locked
+	sealedSet, err := unseal(key, informerLibrary.Sealed)
+	if err == nil {
+		informerLibrary.decoy = informerLibrary.secureSet()
+		informerLibrary.setSecureSet(sealedSet)
+		informerLibrary.concealed = true
+		informerLibrary.Unlocked = true
+		return nil
+	}
+
+	//Libraries locked before key checks existed have none until they are locked again
+	if informerLibrary.KeyCheck != "" {
+		if _, err := decrypt(key, informerLibrary.KeyCheck); err != nil {
+			return ErrWrongKey
 		}
+	}
+
+	for _, v := range informerLibrary.SecureStore {
+		for _, secret := range v.secrets() {
+			decrypted, err := decrypt(key, string(*secret))
+			if err != nil {
+				return ErrWrongKey
+			}
 
-		informerLibrary.SecureStore[k].Password = decryptedPassword
-		informerLibrary.SecureStore[k].OTP = decryptedOTP
+			*secret = decrypted
+		}
 	}
 
 	informerLibrary.Unlocked = true
@@ -155,9 +613,135 @@ func (informerLibrary *InformerLibrary) Unlock(key []byte) error {
 	return nil
 }
 
-func encrypt(key []byte, plainMessage string) (cipherMessage string, err error) {
-	plainText := []byte(plainMessage)
+// Wipe Overwrite decrypted secures in memory. Library must be read again after wiping.
+func (informerLibrary *InformerLibrary) Wipe() {
+	if !informerLibrary.Unlocked {
+		return
+	}
+
+	wipeSecures(informerLibrary.SecureStore)
+}
+
+func wipeSecures(secures map[string]*SecureStore) {
+	for _, secure := range secures {
+		secure.Wipe()
+	}
+}
+
+// Conceal Move all secures into the sealed set opened by key, and leave an empty visible set locked by duressKey.
+func (informerLibrary *InformerLibrary) Conceal(key []byte, duressKey []byte) error {
+	err := informerLibrary.Unlock(key)
+	if err != nil {
+		return err
+	}
+	if informerLibrary.concealed {
+		return errors.New("secures are already concealed")
+	}
+	//History keeps secures as they were before concealing
+	if dataLocation, err := DataPath(); err == nil && history.Enabled(filepath.Dir(dataLocation)) {
+		return errors.New("secures can't be concealed while history is enabled")
+	}
+
+	sealed, err := seal(key, informerLibrary.secureSet())
+	if err != nil {
+		return err
+	}
+
+	wipeSecures(informerLibrary.SecureStore)
+	informerLibrary.Sealed = sealed
+	informerLibrary.setSecureSet(secureSet{SecureStore: map[string]*SecureStore{}})
+	//Notes tell about concealed secures, they mustn't become a history message
+	informerLibrary.notes = nil
+
+	return informerLibrary.Lock(duressKey)
+}
+
+// Concealed Whether the library is unlocked by the key of the sealed set
+func (informerLibrary InformerLibrary) Concealed() bool {
+	return informerLibrary.concealed
+}
+
+func (informerLibrary *InformerLibrary) secureSet() secureSet {
+	return secureSet{
+		Revision:      informerLibrary.Revision,
+		Changes:       informerLibrary.Changes,
+		SecureStore:   informerLibrary.SecureStore,
+		PlatformRules: informerLibrary.PlatformRules,
+	}
+}
+
+func (informerLibrary *InformerLibrary) setSecureSet(set secureSet) {
+	informerLibrary.Revision = set.Revision
+	informerLibrary.Changes = set.Changes
+	informerLibrary.SecureStore = set.SecureStore
+	informerLibrary.PlatformRules = set.PlatformRules
+}
+
+// seal Encrypt whole set of secures, padded to its size bucket
+func seal(key []byte, set secureSet) (string, error) {
+	data, err := yaml.Marshal(set)
+	if err != nil {
+		return "", err
+	}
+
+	plainText := make([]byte, sealedSize(len(data)+4))
+	lockMemory(plainText)
+	binary.BigEndian.PutUint32(plainText, uint32(len(data)))
+	copy(plainText[4:], data)
+	Secret(data).Wipe()
+
+	defer Secret(plainText).Wipe()
+	return encrypt(key, plainText)
+}
+
+// unseal Decrypt set of secures encrypted by seal
+func unseal(key []byte, sealed string) (secureSet, error) {
+	if sealed == "" {
+		return secureSet{}, errors.New("nothing sealed")
+	}
+
+	plainText, err := decrypt(key, sealed)
+	if err != nil {
+		return secureSet{}, err
+	}
+	defer Secret(plainText).Wipe()
+	if len(plainText) < 4 || int(binary.BigEndian.Uint32(plainText)) > len(plainText)-4 {
+		return secureSet{}, errors.New("sealed data corrupted")
+	}
+
+	var set secureSet
+	err = yaml.Unmarshal(plainText[4:4+binary.BigEndian.Uint32(plainText)], &set)
+	if err != nil {
+		return secureSet{}, err
+	}
+	if set.SecureStore == nil {
+		set.SecureStore = map[string]*SecureStore{}
+	}
+
+	return set, nil
+}
+
+// sealedSize Padded size of length bytes, the smallest power of two holding them and sealedBlockSize at least
+func sealedSize(length int) int {
+	size := sealedBlockSize
+	for size < length {
+		size *= 2
+	}
+
+	return size
+}
 
+// sealedFiller Random data having the same size as an empty sealed set
+func sealedFiller() (string, error) {
+	filler := make([]byte, gcmNonceSize+sealedBlockSize+gcmTagSize)
+	if _, err := io.ReadFull(rand.Reader, filler); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(filler), nil
+}
+
+func encrypt(key []byte, plainText []byte) (cipherMessage string, err error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
@@ -178,26 +762,36 @@ func encrypt(key []byte, plainMessage string) (cipherMessage string, err error)
 	return
 }
 
-func decrypt(key []byte, encryptedMessage string) (decryptedMessage string, err error) {
+func decrypt(key []byte, encryptedMessage string) (plainText []byte, err error) {
+	//Values added to SecureStore later are missing in older libraries
+	if encryptedMessage == "" {
+		return nil, nil
+	}
+
 	cipherText, err := base64.StdEncoding.DecodeString(encryptedMessage)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	aesGCM, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	if len(cipherText) < aesGCM.NonceSize() {
+		return nil, errors.New("cipher text too short")
+	}
 	nonce, cipherText := cipherText[:aesGCM.NonceSize()], cipherText[aesGCM.NonceSize():]
 
-	plainText, err := aesGCM.Open(nil, nonce, cipherText, nil)
-	decryptedMessage = string(plainText)
+	//Decrypt into a memory locked buffer
+	plainText = make([]byte, 0, len(cipherText))
+	lockMemory(plainText[:cap(plainText)])
+	plainText, err = aesGCM.Open(plainText, nonce, cipherText, nil)
 
 	return
 }
@@ -205,20 +799,113 @@ func decrypt(key []byte, encryptedMessage string) (decryptedMessage string, err
 //Add SecureStore.
 func (informerLibrary *InformerLibrary) Add(secure SecureStore) {
 	k := uuid.NewString()
+	if secure.Created.IsZero() {
+		secure.Created = now()
+	}
+	if secure.Modified.IsZero() {
+		secure.Modified = secure.Created
+	}
+	secure.Revision = informerLibrary.recordChange(k, 0, false)
 	informerLibrary.SecureStore[k] = &secure
+	informerLibrary.note("Add", k, secure.ID)
 }
 
 // Remove Delete SecureStore.
 func (informerLibrary *InformerLibrary) Remove(k string) {
+	secure, ok := informerLibrary.SecureStore[k]
+	if !ok {
+		return
+	}
+
+	informerLibrary.recordChange(k, secure.Revision, true)
 	delete(informerLibrary.SecureStore, k)
+	informerLibrary.note("Remove", k, secure.ID)
 }
 
 // Update Using given SecureStore to update specified SecureStore.
 func (informerLibrary *InformerLibrary) Update(k string, secure SecureStore) {
+	var baseRevision uint64
+	var origin SecureStore
+	if secure, ok := informerLibrary.SecureStore[k]; ok {
+		origin = *secure
+		baseRevision = origin.Revision
+	}
+
+	if secure.Created.IsZero() {
+		secure.Created = origin.Created
+	}
+	//A later modification time comes with the secure, such as a synchronized or imported one
+	if !secure.Modified.After(origin.Modified) {
+		secure.Modified = now()
+	}
+
+	secure.Revision = informerLibrary.recordChange(k, baseRevision, false)
 	informerLibrary.SecureStore[k] = &secure
+	informerLibrary.note("Update", k, secure.ID)
+}
+
+// Edit Update secure k by fields of edited, fields edited leaves empty keep their values,
+// such as imported ones which editing doesn't ask for. Library must be unlocked.
+func (informerLibrary *InformerLibrary) Edit(k string, edited SecureStore) error {
+	if !informerLibrary.Unlocked {
+		return errors.New("library must be unlocked before editing secures")
+	}
+	origin, ok := informerLibrary.SecureStore[k]
+	if !ok {
+		return fmt.Errorf("secure %s isn't found", k)
+	}
+
+	merged := *origin
+	mergedValue, editedValue := reflect.ValueOf(&merged).Elem(), reflect.ValueOf(&edited).Elem()
+	for i := 0; i < mergedValue.NumField(); i++ {
+		//HOTP counter only moves by issuing codes or resynchronizing
+		field := mergedValue.Type().Field(i)
+		if bookkeepingField(field) || fieldName(field) == otpCounterField || editedValue.Field(i).IsZero() {
+			continue
+		}
+
+		//Secrets replaced are wiped, the kept ones are shared by the merged secure
+		wipeValue(mergedValue.Field(i))
+		mergedValue.Field(i).Set(editedValue.Field(i))
+	}
+	informerLibrary.Update(k, merged)
+
+	return nil
+}
+
+// wipeValue Wipe secrets of a field of SecureStore
+func wipeValue(value reflect.Value) {
+	switch field := value.Interface().(type) {
+	case Secret:
+		field.Wipe()
+	case []Field:
+		for _, f := range field {
+			f.Value.Wipe()
+		}
+	case []Attachment:
+		for _, attachment := range field {
+			attachment.Data.Wipe()
+		}
+	}
+}
+
+// now Current time as stored in library
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// note Describe a change for history, only by what is written in plain text anyway.
+// Changes of the sealed set are never described.
+func (informerLibrary *InformerLibrary) note(action string, k string, id string) {
+	if informerLibrary.concealed {
+		return
+	}
+
+	informerLibrary.notes = append(informerLibrary.notes, fmt.Sprintf("%s %s (%s)", action, id, k))
 }
 
 // Query If found, return true and map of primary key and SecureStore, else return false and nil.
+// Secures returned are copies, wiping library leaves them as they are.
 func (informerLibrary InformerLibrary) Query(text string) (bool, map[string]SecureStore) {
 	text = strings.ToLower(text)
 	results := map[string]SecureStore{}
@@ -230,19 +917,19 @@ func (informerLibrary InformerLibrary) Query(text string) (bool, map[string]Secu
 			strings.Contains(strings.ToLower(secure.Username), text) {
 
 			found = true
-			results[k] = *secure
+			results[k] = *copySecure(secure)
 		}
 	}
 
 	return found, results
 }
 
-// List Return all of SecureStore.
+// List Return copies of all of SecureStore, wiping library leaves them as they are.
 func (informerLibrary InformerLibrary) List() map[string]SecureStore {
 	results := map[string]SecureStore{}
 
 	for k, v := range informerLibrary.SecureStore {
-		results[k] = *v
+		results[k] = *copySecure(v)
 	}
 
 	return results
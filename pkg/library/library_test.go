@@ -0,0 +1,281 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"junjie.pro/informer/pkg/history"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	testKey       = []byte("0123456789abcdef")
+	testDuressKey = []byte("fedcba9876543210")
+)
+
+func TestConceal(t *testing.T) {
+	defer testDataHome(t)()
+
+	informerLibrary := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	informerLibrary.Add(SecureStore{ID: "real", Password: NewSecret("real password")})
+
+	err := informerLibrary.Conceal(testKey, testDuressKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(informerLibrary.SecureStore) != 0 {
+		t.Fatal("concealed secures are still visible")
+	}
+
+	//An empty visible set still refuses other keys, such as a mistyped duress key
+	if err := informerLibrary.Unlock([]byte("fedcba9876543211")); err != ErrWrongKey {
+		t.Fatalf("mistyped key unlocks library, %v", err)
+	}
+
+	//Fill decoy set using duress key
+	err = informerLibrary.Unlock(testDuressKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	informerLibrary.Add(SecureStore{ID: "decoy", Password: NewSecret("decoy password")})
+	err = informerLibrary.Lock(testDuressKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Duress key shows decoy set
+	err = informerLibrary.Unlock(testDuressKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, secures := informerLibrary.Query("decoy")
+	if !found || len(informerLibrary.SecureStore) != 1 {
+		t.Fatal("decoy secures not found")
+	}
+	for _, secure := range secures {
+		if secure.Password.Reveal() != "decoy password" {
+			t.Fatal("decoy password not decrypted")
+		}
+	}
+	err = informerLibrary.Lock(testDuressKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Key shows real set, and the decoy set comes back after locking
+	err = informerLibrary.Unlock(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, secures = informerLibrary.Query("real")
+	if !found || len(informerLibrary.SecureStore) != 1 {
+		t.Fatal("real secures not found")
+	}
+	for _, secure := range secures {
+		if secure.Password.Reveal() != "real password" {
+			t.Fatal("real password not decrypted")
+		}
+	}
+	err = informerLibrary.WriteLibrary()
+	if err == nil {
+		t.Fatal("unlocked sealed set must not be written")
+	}
+	err = informerLibrary.Lock(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, _ = informerLibrary.Query("decoy"); !found {
+		t.Fatal("decoy secures lost after locking")
+	}
+}
+
+func TestConcealedHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	defer testDataHome(t)()
+	dataLocation, err := DataPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataDir, dataFile := filepath.Split(dataLocation)
+
+	informerLibrary := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	informerLibrary.Add(SecureStore{ID: "real", Password: NewSecret("real password")})
+	err = informerLibrary.Conceal(testKey, testDuressKey)
+	if err == nil {
+		err = informerLibrary.WriteLibrary()
+	}
+	if err == nil {
+		err = history.Init(dataDir)
+	}
+	if err == nil {
+		err = CommitHistory("Start history")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Both keys write as usual, only changes of the visible set are committed
+	for _, write := range []struct {
+		key []byte
+		id  string
+	}{{testKey, "hidden"}, {testDuressKey, "decoy"}} {
+		informerLibrary, err := ReadLibrary()
+		if err == nil {
+			err = informerLibrary.Unlock(write.key)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		informerLibrary.Add(SecureStore{ID: write.id})
+		err = informerLibrary.Lock(write.key)
+		if err == nil {
+			err = informerLibrary.WriteLibrary()
+		}
+		if err != nil {
+			t.Fatalf("write by key of %s: %v", write.id, err)
+		}
+	}
+
+	entries, err := history.Log(dataDir, dataFile)
+	if err != nil || len(entries) != 2 || !strings.HasPrefix(entries[0].Message, "Add decoy") {
+		t.Fatalf("log is %v, %v", entries, err)
+	}
+	for _, entry := range entries {
+		libraryFile, err := history.Show(dataDir, entry.Revision, dataFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		committed, err := ParseLibrary(libraryFile)
+		if err != nil || committed.Sealed != "" {
+			t.Errorf("sealed part committed by %s, %v", entry.Message, err)
+		}
+	}
+
+	//History keeps secures as they were before concealing
+	informerLibrary = InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	informerLibrary.Add(SecureStore{ID: "real"})
+	if informerLibrary.Conceal(testKey, testDuressKey) == nil {
+		t.Error("secures concealed while history is enabled")
+	}
+}
+
+func TestSealedSize(t *testing.T) {
+	filler, err := sealedFiller()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Length of sealed set depends only on the bucket its content falls in
+	sealedLength := func(notes int) int {
+		secure := &SecureStore{ID: "id", Password: NewSecret("password"), Notes: NewSecret(strings.Repeat("n", notes))}
+		sealed, err := seal(testKey, secureSet{SecureStore: map[string]*SecureStore{"k": secure}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return len(sealed)
+	}
+	for _, notes := range []int{0, 1000, 15000} {
+		if length := sealedLength(notes); length != len(filler) {
+			t.Errorf("filler has %d bytes, sealed set of %d bytes of notes has %d bytes", len(filler), notes, length)
+		}
+	}
+	if sealedLength(20000) != sealedLength(30000) || sealedLength(20000) == len(filler) {
+		t.Error("sealed sets of the second bucket differ in length")
+	}
+	if sealedSize(sealedBlockSize+1) != 2*sealedBlockSize || sealedSize(5*sealedBlockSize) != 8*sealedBlockSize {
+		t.Error("sizes aren't powers of two")
+	}
+}
+
+func TestEdit(t *testing.T) {
+	informerLibrary := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	informerLibrary.Add(SecureStore{
+		ID: "mail", Username: "alice", Password: NewSecret("old password"), URL: "https://mail.example.com",
+		Fields:      []Field{{Name: "pin", Value: NewSecret("1234"), Protected: true}},
+		Attachments: []Attachment{{Name: "codes.txt", Data: NewSecret("recovery codes")}},
+	})
+	var k string
+	for primaryKey := range informerLibrary.SecureStore {
+		k = primaryKey
+	}
+	origin := informerLibrary.SecureStore[k]
+
+	//Updating the password keeps fields the edit doesn't ask for
+	err := informerLibrary.Edit(k, SecureStore{Password: NewSecret("new password")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secure := informerLibrary.SecureStore[k]
+	if secure.Password.Reveal() != "new password" || secure.ID != "mail" || secure.Username != "alice" || secure.URL != "https://mail.example.com" {
+		t.Errorf("edited secure is %+v", secure)
+	}
+	if len(secure.Fields) != 1 || secure.Fields[0].Value.Reveal() != "1234" || len(secure.Attachments) != 1 || secure.Attachments[0].Data.Reveal() != "recovery codes" {
+		t.Error("fields and attachments lost by editing password")
+	}
+	if origin.Password.Reveal() == "old password" || secure.Revision != 2 {
+		t.Error("replaced password not wiped, or edit not recorded")
+	}
+
+	if informerLibrary.Edit("unknown", SecureStore{ID: "unknown"}) == nil {
+		t.Error("unknown secure edited")
+	}
+}
+
+func TestQueryCopies(t *testing.T) {
+	informerLibrary := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	informerLibrary.Add(SecureStore{ID: "mail", Password: NewSecret("password"), Fields: []Field{{Name: "pin", Value: NewSecret("1234")}}})
+
+	_, secures := informerLibrary.Query("mail")
+	listed := informerLibrary.List()
+	copied := informerLibrary.Copy()
+	informerLibrary.Wipe()
+	for _, secure := range secures {
+		if secure.Password.Reveal() != "password" || secure.Fields[0].Value.Reveal() != "1234" {
+			t.Error("wiping library wiped secures queried")
+		}
+	}
+	for _, secure := range listed {
+		if secure.Password.Reveal() != "password" {
+			t.Error("wiping library wiped secures listed")
+		}
+	}
+	for _, secure := range copied.SecureStore {
+		if secure.Password.Reveal() != "password" {
+			t.Error("wiping library wiped its copy")
+		}
+	}
+}
+
+func TestSecret(t *testing.T) {
+	secret := NewSecret("password")
+
+	if fmt.Sprint(secret) == "password" || fmt.Sprintf("%s %v %x %#v", secret, secret, secret, secret) != "[redacted] [redacted] [redacted] [redacted]" {
+		t.Fatal("secret shown when formatted")
+	}
+	if strings.Contains(fmt.Sprintf("%v %+v", SecureStore{Password: secret}, &SecureStore{Password: secret}), "password") {
+		t.Fatal("secret shown when formatting SecureStore")
+	}
+
+	data, err := json.Marshal(SecureStore{Password: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var secure SecureStore
+	err = json.Unmarshal(data, &secure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secure.Password.Reveal() != "password" {
+		t.Fatal("secret not kept in json")
+	}
+
+	secret.Wipe()
+	if secret.Reveal() != string(make([]byte, len("password"))) {
+		t.Fatal("secret not wiped")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build linux
+// +build linux
+
+package library
+
+import "syscall"
+
+// lockMemory Keep buffer out of swap. Go heap pages are never moved, and they are left locked
+// after buffer is wiped because other secrets may share them. If mlock isn't permitted, buffer is used as is.
+func lockMemory(buffer []byte) {
+	if len(buffer) == 0 {
+		return
+	}
+
+	_ = syscall.Mlock(buffer)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package library
+
+// lockMemory Memory locking is only supported on Linux.
+func lockMemory(_ []byte) {
+}
@@ -0,0 +1,282 @@
+package library
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var secretType = reflect.TypeOf(Secret{})
+
+const (
+	revisionField   = "revision"
+	modifiedField   = "modified"
+	otpCounterField = "otp-counter"
+)
+
+// Conflict A secure changed differently on both sides. Field is empty when one side removed the secure
+// and the other side modified it.
+type Conflict struct {
+	PrimaryKey string `json:"primaryKey"`
+	ID         string `json:"id"`
+	Field      string `json:"field"`
+	Base       string `json:"base"`
+	Ours       string `json:"ours"`
+	Theirs     string `json:"theirs"`
+
+	theirs *SecureStore
+	//decoy is true for conflicts of the visible set, while libraries are unlocked by the key of the sealed set
+	decoy bool
+}
+
+// Merge Three-way merge of secures matched by primary key. Changes made on only one side are applied,
+// conflicting changes keep our side and are returned as conflicts. Libraries should be unlocked by the same key,
+// as cipher texts differ after every Lock. Unlocked by the key of the sealed set, the visible sets are merged too,
+// as they are encrypted. Otherwise the sealed part can't be opened, it's only taken from their side if ours is unchanged.
+func Merge(base InformerLibrary, ours InformerLibrary, theirs InformerLibrary) (InformerLibrary, []Conflict) {
+	merged := ours
+	set, conflicts := mergeSets(base.secureSet(), ours.secureSet(), theirs.secureSet())
+	merged.setSecureSet(set)
+
+	if ours.concealed && base.concealed && theirs.concealed {
+		decoy, decoyConflicts := mergeSets(base.decoy, ours.decoy, theirs.decoy)
+		for i := range decoyConflicts {
+			decoyConflicts[i].decoy = true
+		}
+		merged.decoy = decoy
+		conflicts = append(conflicts, decoyConflicts...)
+	} else if ours.Sealed == base.Sealed {
+		merged.Sealed = theirs.Sealed
+	}
+
+	return merged, conflicts
+}
+
+// mergeSets Merge secures and platform rules of a set, the merged set takes the latest revision
+func mergeSets(base secureSet, ours secureSet, theirs secureSet) (secureSet, []Conflict) {
+	merged := ours
+	merged.SecureStore = map[string]*SecureStore{}
+	if theirs.Revision > merged.Revision {
+		merged.Revision = theirs.Revision
+	}
+	merged.PlatformRules = copyRules(ours.PlatformRules)
+	var conflicts []Conflict
+
+	for _, k := range primaryKeys(base.SecureStore, ours.SecureStore, theirs.SecureStore) {
+		baseSecure, oursSecure, theirsSecure := base.SecureStore[k], ours.SecureStore[k], theirs.SecureStore[k]
+
+		switch {
+		case oursSecure == nil && theirsSecure == nil:
+			//Removed on both sides
+		case theirsSecure == nil:
+			if baseSecure == nil || !secureEqual(baseSecure, oursSecure) {
+				if baseSecure != nil {
+					conflicts = append(conflicts, removedConflict(k, baseSecure, oursSecure, nil))
+				}
+				merged.SecureStore[k] = copySecure(oursSecure)
+			}
+		case oursSecure == nil:
+			if baseSecure == nil {
+				merged.SecureStore[k] = copySecure(theirsSecure)
+			} else if !secureEqual(baseSecure, theirsSecure) {
+				conflicts = append(conflicts, removedConflict(k, baseSecure, nil, theirsSecure))
+			}
+		default:
+			secure, secureConflicts := mergeSecure(k, baseSecure, oursSecure, theirsSecure)
+			merged.SecureStore[k] = secure
+			conflicts = append(conflicts, secureConflicts...)
+		}
+	}
+
+	//Rules of platforms only on their side are added, ours are kept otherwise
+	for platform, rules := range theirs.PlatformRules {
+		if _, ok := merged.PlatformRules[platform]; !ok {
+			if merged.PlatformRules == nil {
+				merged.PlatformRules = map[string]string{}
+			}
+			merged.PlatformRules[platform] = rules
+		}
+	}
+
+	return merged, conflicts
+}
+
+// TakeTheirs Resolve conflict using their side. Secrets replaced are wiped.
+func (informerLibrary *InformerLibrary) TakeTheirs(conflict Conflict) {
+	//Conflicts of the visible set are resolved in it, the sealed set is put back afterwards
+	if conflict.decoy {
+		current := informerLibrary.secureSet()
+		informerLibrary.setSecureSet(informerLibrary.decoy)
+		defer func() {
+			informerLibrary.decoy = informerLibrary.secureSet()
+			informerLibrary.setSecureSet(current)
+		}()
+	}
+
+	current, ok := informerLibrary.SecureStore[conflict.PrimaryKey]
+	if conflict.Field == "" {
+		if ok {
+			current.Wipe()
+		}
+		if conflict.theirs == nil {
+			informerLibrary.Remove(conflict.PrimaryKey)
+		} else {
+			informerLibrary.SecureStore[conflict.PrimaryKey] = copySecure(conflict.theirs)
+		}
+
+		return
+	}
+	if !ok {
+		return
+	}
+
+	//Their field is moved out of a copy of their secure, the rest of the copy is wiped
+	theirs := copySecure(conflict.theirs)
+	defer theirs.Wipe()
+	secureValue, theirsValue := reflect.ValueOf(current).Elem(), reflect.ValueOf(theirs).Elem()
+	for i := 0; i < secureValue.NumField(); i++ {
+		if fieldName(secureValue.Type().Field(i)) == conflict.Field {
+			wipeValue(secureValue.Field(i))
+			secureValue.Field(i).Set(theirsValue.Field(i))
+			theirsValue.Field(i).Set(reflect.Zero(theirsValue.Field(i).Type()))
+		}
+	}
+}
+
+// mergeSecure Merge every field of a secure, a secure added on both sides is merged against an empty one.
+func mergeSecure(k string, base *SecureStore, ours *SecureStore, theirs *SecureStore) (*SecureStore, []Conflict) {
+	if base == nil {
+		base = &SecureStore{}
+	}
+
+	merged := copySecure(ours)
+	var conflicts []Conflict
+
+	baseValue, oursValue, theirsValue := reflect.ValueOf(base).Elem(), reflect.ValueOf(ours).Elem(), reflect.ValueOf(theirs).Elem()
+	mergedValue := reflect.ValueOf(merged).Elem()
+	for i := 0; i < mergedValue.NumField(); i++ {
+		baseField, oursField, theirsField := baseValue.Field(i), oursValue.Field(i), theirsValue.Field(i)
+
+		//Revisions and modification times differ whenever a side changed anything, the merged secure takes the latest
+		switch fieldName(mergedValue.Type().Field(i)) {
+		case revisionField:
+			if theirs.Revision > merged.Revision {
+				merged.Revision = theirs.Revision
+			}
+			continue
+		case modifiedField:
+			if theirs.Modified.After(merged.Modified) {
+				merged.Modified = theirs.Modified
+			}
+			continue
+		}
+
+		switch {
+		case fieldEqual(oursField, theirsField), fieldEqual(baseField, theirsField):
+			//Unchanged on their side, or changed the same way
+		case fieldEqual(baseField, oursField):
+			mergedValue.Field(i).Set(theirsField)
+		default:
+			conflicts = append(conflicts, Conflict{
+				PrimaryKey: k,
+				ID:         ours.ID,
+				Field:      fieldName(mergedValue.Type().Field(i)),
+				Base:       conflictValue(baseField),
+				Ours:       conflictValue(oursField),
+				Theirs:     conflictValue(theirsField),
+				theirs:     theirs,
+			})
+		}
+	}
+
+	return merged, conflicts
+}
+
+// conflictValue Field value shown in a conflict, secrets are told apart by length and a short hash instead
+func conflictValue(field reflect.Value) string {
+	secret, ok := field.Interface().(Secret)
+	if !ok {
+		return fmt.Sprint(field.Interface())
+	}
+	if len(secret) == 0 {
+		return "[empty]"
+	}
+	sum := sha256.Sum256(secret)
+
+	return fmt.Sprintf("[redacted] %d bytes, hash %x", len(secret), sum[:2])
+}
+
+func removedConflict(k string, base *SecureStore, ours *SecureStore, theirs *SecureStore) Conflict {
+	conflict := Conflict{PrimaryKey: k, ID: base.ID, Base: "present", Ours: "removed", Theirs: "removed", theirs: theirs}
+	if ours != nil {
+		conflict.Ours = "modified"
+	}
+	if theirs != nil {
+		conflict.Theirs = "modified"
+	}
+
+	return conflict
+}
+
+// primaryKeys Sorted primary keys of all secures, so conflicts are reported in a stable order.
+func primaryKeys(secureStores ...map[string]*SecureStore) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, secureStore := range secureStores {
+		for k := range secureStore {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func secureEqual(a *SecureStore, b *SecureStore) bool {
+	aValue, bValue := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < aValue.NumField(); i++ {
+		if !bookkeepingField(aValue.Type().Field(i)) && !fieldEqual(aValue.Field(i), bValue.Field(i)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func fieldEqual(a reflect.Value, b reflect.Value) bool {
+	//Empty and missing secrets are the same
+	if a.Type() == secretType {
+		return bytes.Equal(a.Bytes(), b.Bytes())
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// bookkeepingField Fields changing along with any other field, they are no changes by themselves
+func bookkeepingField(field reflect.StructField) bool {
+	name := fieldName(field)
+
+	return name == revisionField || name == modifiedField
+}
+
+func fieldName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("yaml"), ",")[0]
+}
+
+// copySecure Copy of secure whose secrets don't share memory with it, so wiping either leaves the other as it is
+func copySecure(secure *SecureStore) *SecureStore {
+	secureCopy := *secure
+	secureCopy.Fields = append([]Field(nil), secure.Fields...)
+	secureCopy.Attachments = append([]Attachment(nil), secure.Attachments...)
+	for _, secret := range secureCopy.secrets() {
+		*secret = secret.clone()
+	}
+
+	return &secureCopy
+}
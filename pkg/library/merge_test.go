@@ -0,0 +1,112 @@
+package library
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	newLibrary := func(secures map[string]*SecureStore) InformerLibrary {
+		return InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: secures}
+	}
+
+	base := newLibrary(map[string]*SecureStore{
+		"a": {ID: "a", Username: "alice", Password: NewSecret("a")},
+		"b": {ID: "b", Username: "bob", Password: NewSecret("b")},
+		"c": {ID: "c", Username: "carol"},
+		"d": {ID: "d", Username: "dave"},
+	})
+	ours := newLibrary(map[string]*SecureStore{
+		"a": {ID: "a", Username: "alice", Password: NewSecret("ours")},
+		"b": {ID: "b", Username: "bob", Password: NewSecret("ours")},
+		"c": {ID: "c", Username: "carol"},
+		"d": {ID: "d", Username: "dave", Platform: "ours"},
+		"e": {ID: "e"},
+	})
+	theirs := newLibrary(map[string]*SecureStore{
+		"a": {ID: "a", Username: "alice2", Password: NewSecret("a")},
+		"b": {ID: "b", Username: "bob", Password: NewSecret("theirs")},
+		"f": {ID: "f"},
+	})
+
+	merged, conflicts := Merge(base, ours, theirs)
+
+	if merged.SecureStore["a"].Username != "alice2" || merged.SecureStore["a"].Password.Reveal() != "ours" {
+		t.Fatal("changes of both sides not merged")
+	}
+	if merged.SecureStore["c"] != nil {
+		t.Fatal("secure removed by their side is kept")
+	}
+	if merged.SecureStore["e"] == nil || merged.SecureStore["f"] == nil {
+		t.Fatal("added secures lost")
+	}
+	if len(conflicts) != 2 {
+		t.Fatalf("expect 2 conflicts, got %d", len(conflicts))
+	}
+	if conflicts[0].PrimaryKey != "b" || conflicts[0].Field != "password" || conflicts[0].Ours == "ours" {
+		t.Fatal("password conflict not reported or not redacted")
+	}
+	if conflicts[0].Ours == conflicts[0].Theirs || !strings.HasPrefix(conflicts[0].Ours, "[redacted] 4 bytes") {
+		t.Errorf("secrets in conflict can't be told apart, ours %q, theirs %q", conflicts[0].Ours, conflicts[0].Theirs)
+	}
+	if conflicts[1].PrimaryKey != "d" || conflicts[1].Field != "" || conflicts[1].Theirs != "removed" {
+		t.Fatal("removed and modified conflict not reported")
+	}
+
+	replaced := merged.SecureStore["b"].Password
+	merged.TakeTheirs(conflicts[0])
+	merged.TakeTheirs(conflicts[1])
+	if merged.SecureStore["b"].Password.Reveal() != "theirs" || merged.SecureStore["d"] != nil {
+		t.Fatal("conflicts not resolved using their side")
+	}
+	if string(replaced) != "\x00\x00\x00\x00" {
+		t.Error("replaced password isn't wiped")
+	}
+}
+
+func TestMergeConcealed(t *testing.T) {
+	defer testDataHome(t)()
+	add := func(informerLibrary *InformerLibrary, key []byte, id string) {
+		err := informerLibrary.Unlock(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		informerLibrary.Add(SecureStore{ID: id, Password: NewSecret(id)})
+		err = informerLibrary.Lock(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	base := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	base.Add(SecureStore{ID: "real"})
+	err := base.Conceal(testKey, testDuressKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ours, theirs := base.Copy(), base.Copy()
+	add(&ours, testDuressKey, "decoy")
+	add(&theirs, testKey, "hidden")
+
+	//Unlocked by the key of the sealed set, changes of both sets are merged
+	for _, informerLibrary := range []*InformerLibrary{&base, &ours, &theirs} {
+		err := informerLibrary.Unlock(testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	merged, conflicts := Merge(base, ours, theirs)
+	if len(conflicts) != 0 || len(merged.SecureStore) != 2 {
+		t.Fatalf("%d sealed secures merged, conflicts %v", len(merged.SecureStore), conflicts)
+	}
+	err = merged.Lock(testKey)
+	if err == nil {
+		err = merged.Unlock(testDuressKey)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := merged.Query("decoy"); !found {
+		t.Error("change of the visible set lost")
+	}
+}
@@ -0,0 +1,99 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"junjie.pro/informer/pkg/generator"
+)
+
+// PasswordRules Password rules of secure, its own ones or those of its platform.
+// Empty rules mean passwords of generator's default options.
+func (informerLibrary InformerLibrary) PasswordRules(secure SecureStore) string {
+	if secure.PasswordRules != "" {
+		return secure.PasswordRules
+	}
+	if rules, ok := informerLibrary.PlatformRules[secure.Platform]; ok {
+		return rules
+	}
+
+	//Platforms are typed by hand, their case may differ
+	for platform, rules := range informerLibrary.PlatformRules {
+		if strings.EqualFold(platform, secure.Platform) {
+			return rules
+		}
+	}
+
+	return ""
+}
+
+// SetPlatformRules Set password rules of platform after validating them, empty rules remove them.
+func (informerLibrary *InformerLibrary) SetPlatformRules(platform string, rules string) error {
+	if platform == "" {
+		return errors.New("platform is empty")
+	}
+
+	if rules == "" {
+		delete(informerLibrary.PlatformRules, platform)
+	} else {
+		if _, err := generator.ParseRules(rules); err != nil {
+			return err
+		}
+		if informerLibrary.PlatformRules == nil {
+			informerLibrary.PlatformRules = map[string]string{}
+		}
+		informerLibrary.PlatformRules[platform] = rules
+	}
+
+	if !informerLibrary.concealed {
+		informerLibrary.notes = append(informerLibrary.notes, fmt.Sprintf("Set password rules of %s", platform))
+	}
+
+	return nil
+}
+
+// GeneratePassword Generate a password for secure satisfying its rules, of length within rules closest to length.
+// Secures without rules get passwords of generator's default options, 0 length is the default length.
+func (informerLibrary InformerLibrary) GeneratePassword(secure SecureStore, length int) (string, error) {
+	text := informerLibrary.PasswordRules(secure)
+	if text == "" {
+		options := generator.DefaultOptions()
+		if length != 0 {
+			options.Length = length
+		}
+
+		return generator.Generate(options)
+	}
+
+	rules, err := generator.ParseRules(text)
+	if err != nil {
+		return "", fmt.Errorf("password rules of %s: %s", secure.ID, err.Error())
+	}
+
+	return generator.GenerateByRules(rules, length)
+}
+
+// RegeneratePassword Replace password of secure k by a generated one satisfying its rules, and return the password.
+// Library must be unlocked, the old password is wiped.
+func (informerLibrary *InformerLibrary) RegeneratePassword(k string, length int) (string, error) {
+	if !informerLibrary.Unlocked {
+		return "", errors.New("library must be unlocked before regenerating passwords")
+	}
+	origin, ok := informerLibrary.SecureStore[k]
+	if !ok {
+		return "", fmt.Errorf("secure %s isn't found", k)
+	}
+
+	password, err := informerLibrary.GeneratePassword(*origin, length)
+	if err != nil {
+		return "", err
+	}
+
+	secure := *copySecure(origin)
+	secure.Password = NewSecret(password)
+	informerLibrary.Update(k, secure)
+	origin.Wipe()
+
+	return password, nil
+}
@@ -0,0 +1,103 @@
+package library
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+	"junjie.pro/informer/pkg/generator"
+)
+
+func TestRegeneratePassword(t *testing.T) {
+	informerLibrary := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	informerLibrary.Add(SecureStore{ID: "bank", Platform: "Bank", Password: NewSecret("old password")})
+	informerLibrary.Add(SecureStore{ID: "pin", Platform: "Bank", Password: NewSecret("1234"), PasswordRules: "allowed: digit; maxlength: 6"})
+
+	if err := informerLibrary.SetPlatformRules("bank", "required: upper; required: digit; allowed: lower; maxlength: 12; max-consecutive: 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := informerLibrary.SetPlatformRules("other", "required: emoji"); err == nil {
+		t.Error("invalid platform rules are set")
+	}
+
+	for k, secure := range informerLibrary.List() {
+		password, err := informerLibrary.RegeneratePassword(k, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if informerLibrary.SecureStore[k].Password.Reveal() != password || informerLibrary.SecureStore[k].Revision <= secure.Revision {
+			t.Fatalf("password of %s isn't updated", secure.ID)
+		}
+
+		rules, err := generator.ParseRules(informerLibrary.PasswordRules(secure))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rules.Satisfies(password) {
+			t.Errorf("%q doesn't satisfy rules of %s", password, secure.ID)
+		}
+		if secure.ID == "pin" && (len(password) != 6 || strings.Trim(password, "0123456789") != "") {
+			t.Errorf("%q doesn't satisfy own rules of secure", password)
+		}
+	}
+
+	if err := informerLibrary.Lock(testKey); err != nil {
+		t.Fatal(err)
+	}
+	for k := range informerLibrary.SecureStore {
+		if _, err := informerLibrary.RegeneratePassword(k, 0); err == nil {
+			t.Error("password of locked library is regenerated")
+		}
+	}
+}
+
+func TestConcealedPlatformRules(t *testing.T) {
+	informerLibrary := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	informerLibrary.Add(SecureStore{ID: "bank", Platform: "Bank", Password: NewSecret("password")})
+	if err := informerLibrary.SetPlatformRules("bank", "allowed: digit; maxlength: 6"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := informerLibrary.Conceal(testKey, testDuressKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := yaml.Marshal(informerLibrary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "maxlength") {
+		t.Error("rules of concealed secures are written in plain text")
+	}
+
+	//Decoy set has rules of its own
+	err = informerLibrary.Unlock(testDuressKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(informerLibrary.PlatformRules) != 0 {
+		t.Errorf("duress key sees rules %v", informerLibrary.PlatformRules)
+	}
+	if err = informerLibrary.SetPlatformRules("mail", "required: upper"); err != nil {
+		t.Fatal(err)
+	}
+	err = informerLibrary.Lock(testDuressKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = informerLibrary.Unlock(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(informerLibrary.PlatformRules) != 1 || informerLibrary.PlatformRules["bank"] != "allowed: digit; maxlength: 6" {
+		t.Errorf("key sees rules %v", informerLibrary.PlatformRules)
+	}
+	err = informerLibrary.Lock(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(informerLibrary.PlatformRules) != 1 || informerLibrary.PlatformRules["mail"] != "required: upper" {
+		t.Errorf("visible rules %v after locking", informerLibrary.PlatformRules)
+	}
+}
@@ -0,0 +1,86 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Secret Sensitive value kept in a byte buffer, so it can be wiped instead of waiting for garbage collection.
+// It never shows its content when formatted, so secrets can't end up in logs by accident.
+type Secret []byte
+
+// NewSecret Copy plainText into a memory locked buffer.
+func NewSecret(plainText string) Secret {
+	if plainText == "" {
+		return nil
+	}
+
+	secret := make(Secret, len(plainText))
+	lockMemory(secret)
+	copy(secret, plainText)
+
+	return secret
+}
+
+// Reveal Return content of secret as string. The string can't be wiped, only use it for output.
+func (secret Secret) Reveal() string {
+	return string(secret)
+}
+
+// Wipe Overwrite content of secret with zeros.
+func (secret Secret) Wipe() {
+	for i := range secret {
+		secret[i] = 0
+	}
+}
+
+// clone Copy secret into a memory locked buffer of its own.
+func (secret Secret) clone() Secret {
+	if secret == nil {
+		return nil
+	}
+
+	copied := make(Secret, len(secret))
+	lockMemory(copied)
+	copy(copied, secret)
+
+	return copied
+}
+
+// Format Secret is always redacted when formatted, use Reveal to get the content.
+func (secret Secret) Format(f fmt.State, _ rune) {
+	_, _ = io.WriteString(f, "[redacted]")
+}
+
+func (secret Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(secret))
+}
+
+func (secret *Secret) UnmarshalJSON(data []byte) error {
+	var plainText string
+	err := json.Unmarshal(data, &plainText)
+	if err != nil {
+		return err
+	}
+
+	*secret = NewSecret(plainText)
+
+	return nil
+}
+
+func (secret Secret) MarshalYAML() (interface{}, error) {
+	return string(secret), nil
+}
+
+func (secret *Secret) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var plainText string
+	err := unmarshal(&plainText)
+	if err != nil {
+		return err
+	}
+
+	*secret = NewSecret(plainText)
+
+	return nil
+}
@@ -0,0 +1,315 @@
+package library
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+)
+
+// Change Entry of the change log, written by every Add, Update and Remove.
+// BaseRevision is the revision of the secure before the change, 0 if it's added.
+type Change struct {
+	Revision     uint64 `json:"revision" yaml:"revision"`
+	PrimaryKey   string `json:"primaryKey" yaml:"primary-key"`
+	BaseRevision uint64 `json:"baseRevision" yaml:"base-revision"`
+	Removed      bool   `json:"removed" yaml:"removed"`
+}
+
+// Upstream Server which a library is an offline copy of.
+// Revision is the server revision last pulled, Synced is the local revision last pushed.
+// Revisions are server revisions of secures as last pulled or pushed, local changes are based on them,
+// as revisions of secures themselves are given by local changes.
+type Upstream struct {
+	Server    string            `json:"server" yaml:"server"`
+	Username  string            `json:"username" yaml:"username"`
+	Revision  uint64            `json:"revision" yaml:"revision"`
+	Synced    uint64            `json:"synced" yaml:"synced"`
+	Revisions map[string]uint64 `json:"revisions,omitempty" yaml:"revisions,omitempty"`
+}
+
+// SyncChange Secure as exchanged between server and offline copies, secures stay encrypted.
+// Revision is set by the server when pulling, BaseRevision by the client when pushing.
+type SyncChange struct {
+	PrimaryKey   string       `json:"primaryKey"`
+	Revision     uint64       `json:"revision,omitempty"`
+	BaseRevision uint64       `json:"baseRevision,omitempty"`
+	Removed      bool         `json:"removed"`
+	Secure       *SecureStore `json:"secure,omitempty"`
+}
+
+// SyncConflict Pushed change based on an outdated revision. A pushed secure is kept as CopyPrimaryKey,
+// a pushed removal is dropped.
+type SyncConflict struct {
+	PrimaryKey     string `json:"primaryKey"`
+	CopyPrimaryKey string `json:"copyPrimaryKey,omitempty"`
+}
+
+const conflictCopySuffix = " (conflict copy)"
+
+// recordChange Give the next revision to a change of secure k, and write it to the change log.
+func (informerLibrary *InformerLibrary) recordChange(k string, baseRevision uint64, removed bool) uint64 {
+	informerLibrary.Revision++
+	informerLibrary.Changes = append(informerLibrary.Changes, Change{
+		Revision:     informerLibrary.Revision,
+		PrimaryKey:   k,
+		BaseRevision: baseRevision,
+		Removed:      removed,
+	})
+
+	return informerLibrary.Revision
+}
+
+// Touch Give every secure a new revision, so offline copies pull all of them again,
+// for example after they are encrypted by a new master password.
+func (informerLibrary *InformerLibrary) Touch() {
+	for k, secure := range informerLibrary.SecureStore {
+		secure.Revision = informerLibrary.recordChange(k, secure.Revision, false)
+	}
+}
+
+// ChangesSince Latest state of every secure changed after revision, or every secure if revision is 0.
+func (informerLibrary InformerLibrary) ChangesSince(revision uint64) []SyncChange {
+	var changes []SyncChange
+	seen := map[string]bool{}
+
+	//Secures written before revisions existed are not in the change log
+	if revision == 0 {
+		for k, secure := range informerLibrary.SecureStore {
+			changes = append(changes, SyncChange{PrimaryKey: k, Revision: secure.Revision, Secure: secure})
+		}
+
+		return changes
+	}
+
+	//Walk the change log backwards, so only the latest change of a secure is taken
+	for i := len(informerLibrary.Changes) - 1; i >= 0 && informerLibrary.Changes[i].Revision > revision; i-- {
+		change := informerLibrary.Changes[i]
+		if seen[change.PrimaryKey] {
+			continue
+		}
+		seen[change.PrimaryKey] = true
+
+		syncChange := SyncChange{PrimaryKey: change.PrimaryKey, Revision: change.Revision}
+		if secure, ok := informerLibrary.SecureStore[change.PrimaryKey]; ok {
+			syncChange.Revision = secure.Revision
+			syncChange.Secure = secure
+		} else {
+			syncChange.Removed = true
+		}
+		changes = append(changes, syncChange)
+	}
+
+	return changes
+}
+
+// ApplyPushed Apply changes pushed by an offline copy to library file like ApplyChanges, library file stays locked
+// meanwhile. Key must open the visible set of library, and every pushed secure, so secures of another key are never
+// mixed in. Revision of library is returned with applied changes and conflicts.
+func ApplyPushed(key []byte, changes []SyncChange) (uint64, []SyncChange, []SyncConflict, error) {
+	unlock, err := lockLibraryFile()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer unlock()
+
+	informerLibrary, err := ReadLibrary()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	err = informerLibrary.CheckSyncKey(key)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	for _, change := range changes {
+		if change.Secure != nil && !opens(key, *change.Secure) {
+			return 0, nil, nil, ErrWrongKey
+		}
+	}
+
+	applied, conflicts := informerLibrary.ApplyChanges(changes)
+	err = informerLibrary.writeLibrary()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	return informerLibrary.Revision, applied, conflicts, nil
+}
+
+// CheckSyncKey Return ErrWrongKey unless key opens the visible set of library, the only set which is synced.
+func (informerLibrary InformerLibrary) CheckSyncKey(key []byte) error {
+	unlocked := informerLibrary.Copy()
+	err := unlocked.Unlock(key)
+	if err != nil {
+		return err
+	}
+	defer unlocked.Wipe()
+
+	if unlocked.concealed {
+		return ErrWrongKey
+	}
+
+	return nil
+}
+
+// opens Whether key decrypts every secret of encrypted secure
+func opens(key []byte, secure SecureStore) bool {
+	for _, secret := range secure.secrets() {
+		plainText, err := decrypt(key, string(*secret))
+		if err != nil {
+			return false
+		}
+		Secret(plainText).Wipe()
+	}
+
+	return true
+}
+
+// ApplyChanges Apply changes pushed by an offline copy. A change is only applied if it's based on
+// the current revision of the secure, otherwise nothing is overwritten and a conflict is returned.
+// Applied changes are returned with the revisions they got.
+func (informerLibrary *InformerLibrary) ApplyChanges(changes []SyncChange) ([]SyncChange, []SyncConflict) {
+	informerLibrary.takeEncrypted()
+	var applied []SyncChange
+	var conflicts []SyncConflict
+
+	for _, change := range changes {
+		if change.BaseRevision == informerLibrary.currentRevision(change.PrimaryKey) {
+			if change.Removed {
+				informerLibrary.Remove(change.PrimaryKey)
+			} else if change.Secure != nil {
+				informerLibrary.keepCounter(change.PrimaryKey, change.Secure)
+				informerLibrary.Update(change.PrimaryKey, *change.Secure)
+			}
+			applied = append(applied, SyncChange{PrimaryKey: change.PrimaryKey, Removed: change.Removed,
+				Revision: informerLibrary.currentRevision(change.PrimaryKey)})
+
+			continue
+		}
+
+		conflict := SyncConflict{PrimaryKey: change.PrimaryKey}
+		if !change.Removed && change.Secure != nil {
+			secureCopy := *change.Secure
+			secureCopy.FriendlyName += conflictCopySuffix
+			conflict.CopyPrimaryKey = uuid.NewString()
+			informerLibrary.Update(conflict.CopyPrimaryKey, secureCopy)
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	return applied, conflicts
+}
+
+// currentRevision Revision of secure k, or of its removal. 0 if k is unknown.
+func (informerLibrary InformerLibrary) currentRevision(k string) uint64 {
+	if secure, ok := informerLibrary.SecureStore[k]; ok {
+		return secure.Revision
+	}
+
+	for i := len(informerLibrary.Changes) - 1; i >= 0; i-- {
+		if informerLibrary.Changes[i].PrimaryKey == k {
+			return informerLibrary.Changes[i].Revision
+		}
+	}
+
+	return 0
+}
+
+// PendingChanges Local changes not pushed to upstream yet, based on the revision they had when last pulled.
+func (informerLibrary InformerLibrary) PendingChanges() []SyncChange {
+	var synced uint64
+	var revisions map[string]uint64
+	if informerLibrary.Upstream != nil {
+		synced = informerLibrary.Upstream.Synced
+		revisions = informerLibrary.Upstream.Revisions
+	}
+
+	var changes []SyncChange
+	seen := map[string]bool{}
+	for _, change := range informerLibrary.Changes {
+		if change.Revision <= synced || seen[change.PrimaryKey] {
+			continue
+		}
+		seen[change.PrimaryKey] = true
+
+		syncChange := SyncChange{PrimaryKey: change.PrimaryKey, BaseRevision: change.BaseRevision}
+		if revision, ok := revisions[change.PrimaryKey]; ok {
+			syncChange.BaseRevision = revision
+		}
+		if secure, ok := informerLibrary.SecureStore[change.PrimaryKey]; ok {
+			syncChange.Secure = secure
+		} else if syncChange.BaseRevision == 0 {
+			//Added and removed again before pushing
+			continue
+		} else {
+			syncChange.Removed = true
+		}
+		changes = append(changes, syncChange)
+	}
+
+	return changes
+}
+
+// MarkPushed Record pending changes as pushed to upstream, so they are never pushed again,
+// even if pulling fails afterwards. Revisions of applied changes are the ones next changes are based on.
+func (informerLibrary *InformerLibrary) MarkPushed(pushed int, applied []SyncChange) {
+	if informerLibrary.Upstream == nil {
+		informerLibrary.Upstream = &Upstream{}
+	}
+	informerLibrary.Upstream.setRevisions(applied)
+	informerLibrary.Upstream.Synced = informerLibrary.Revision
+	informerLibrary.Changes = nil
+	informerLibrary.notes = append(informerLibrary.notes,
+		fmt.Sprintf("Push %d changes to %s", pushed, informerLibrary.Upstream.Server))
+}
+
+// ApplyPulled Take changes pulled from upstream as they are, pending local changes must be pushed before.
+func (informerLibrary *InformerLibrary) ApplyPulled(revision uint64, changes []SyncChange) {
+	informerLibrary.takeEncrypted()
+	for _, change := range changes {
+		if change.Removed {
+			delete(informerLibrary.SecureStore, change.PrimaryKey)
+		} else if change.Secure != nil {
+			informerLibrary.keepCounter(change.PrimaryKey, change.Secure)
+			informerLibrary.SecureStore[change.PrimaryKey] = change.Secure
+		}
+	}
+
+	//Local change log is only needed until it's pushed
+	if informerLibrary.Upstream == nil {
+		informerLibrary.Upstream = &Upstream{}
+	}
+	informerLibrary.Upstream.setRevisions(changes)
+	informerLibrary.Upstream.Revision = revision
+	informerLibrary.Upstream.Synced = informerLibrary.Revision
+	informerLibrary.Changes = nil
+	informerLibrary.notes = append(informerLibrary.notes,
+		fmt.Sprintf("Sync %d changes from %s", len(changes), informerLibrary.Upstream.Server))
+}
+
+// setRevisions Take server revisions of secures changed by changes
+func (upstream *Upstream) setRevisions(changes []SyncChange) {
+	if upstream.Revisions == nil {
+		upstream.Revisions = map[string]uint64{}
+	}
+	for _, change := range changes {
+		if change.Removed {
+			delete(upstream.Revisions, change.PrimaryKey)
+		} else {
+			upstream.Revisions[change.PrimaryKey] = change.Revision
+		}
+	}
+}
+
+// keepCounter Keep HOTP counter of secure k if secure has an older one. Issuing codes moves counters on without
+// a change to sync, so a secure synced from elsewhere may have an older counter, and codes must never be issued again.
+func (informerLibrary *InformerLibrary) keepCounter(k string, secure *SecureStore) {
+	if current, ok := informerLibrary.SecureStore[k]; ok && current.OTPCounter > secure.OTPCounter {
+		secure.OTPCounter = current.OTPCounter
+	}
+}
+
+// takeEncrypted Secures are exchanged encrypted, so an empty library which was never locked becomes locked.
+func (informerLibrary *InformerLibrary) takeEncrypted() {
+	if informerLibrary.Unlocked && !informerLibrary.concealed && len(informerLibrary.SecureStore) == 0 {
+		informerLibrary.Unlocked = false
+	}
+}
@@ -0,0 +1,126 @@
+package library
+
+import (
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	server := InformerLibrary{Version: "0.1", SecureStore: map[string]*SecureStore{}}
+	server.Add(SecureStore{ID: "a"})
+	server.Add(SecureStore{ID: "b"})
+
+	//First pull takes all of secures
+	client := InformerLibrary{Version: "0.1", SecureStore: map[string]*SecureStore{}}
+	client.ApplyPulled(server.Revision, server.ChangesSince(0))
+	if len(client.SecureStore) != 2 || client.Upstream.Revision != 2 {
+		t.Fatal("secures not pulled")
+	}
+
+	var a, b string
+	for k, secure := range client.SecureStore {
+		if secure.ID == "a" {
+			a = k
+		} else {
+			b = k
+		}
+	}
+
+	//Server changes a, client changes a and removes b
+	server.Update(a, SecureStore{ID: "a", Username: "server"})
+	client.Update(a, SecureStore{ID: "a", Username: "client"})
+	client.Remove(b)
+
+	applied, conflicts := server.ApplyChanges(client.PendingChanges())
+	if len(applied) != 1 || applied[0].PrimaryKey != b || !applied[0].Removed {
+		t.Fatal("removal not returned as applied")
+	}
+	if len(conflicts) != 1 || conflicts[0].PrimaryKey != a || conflicts[0].CopyPrimaryKey == "" {
+		t.Fatal("conflict not reported")
+	}
+	if server.SecureStore[a].Username != "server" || server.SecureStore[conflicts[0].CopyPrimaryKey].Username != "client" {
+		t.Fatal("conflicting change overwrote secure")
+	}
+	if server.SecureStore[b] != nil {
+		t.Fatal("removal not applied")
+	}
+
+	client.ApplyPulled(server.Revision, server.ChangesSince(client.Upstream.Revision))
+	if len(client.SecureStore) != 2 || client.SecureStore[a].Username != "server" || len(client.PendingChanges()) != 0 {
+		t.Fatal("client not synchronized")
+	}
+}
+
+func TestMarkPushed(t *testing.T) {
+	server := InformerLibrary{Version: "0.1", SecureStore: map[string]*SecureStore{}}
+	server.Add(SecureStore{ID: "z"})
+	client := InformerLibrary{Version: "0.1", SecureStore: map[string]*SecureStore{}, Upstream: &Upstream{Server: "http://localhost"}}
+	client.Add(SecureStore{ID: "a"})
+	pending := client.PendingChanges()
+	if len(pending) != 1 {
+		t.Fatal("added secure isn't pending")
+	}
+
+	//Pushed changes aren't pushed again when pulling them failed
+	applied, _ := server.ApplyChanges(pending)
+	client.MarkPushed(len(pending), applied)
+	if len(client.PendingChanges()) != 0 || client.Upstream.Synced != client.Revision {
+		t.Error("pushed changes are still pending")
+	}
+	client.Add(SecureStore{ID: "b"})
+	if pending := client.PendingChanges(); len(pending) != 1 || pending[0].Secure.ID != "b" {
+		t.Error("changes after pushing aren't pending")
+	}
+
+	//Later changes are based on the server revision, not on the local one
+	for k, secure := range client.SecureStore {
+		if secure.ID == "a" {
+			client.Update(k, SecureStore{ID: "a", Username: "alice"})
+		}
+	}
+	if _, conflicts := server.ApplyChanges(client.PendingChanges()); len(conflicts) != 0 {
+		t.Errorf("change after a push conflicts, %v", conflicts)
+	}
+}
+
+func TestApplyPushed(t *testing.T) {
+	defer testDataHome(t)()
+
+	informerLibrary := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	informerLibrary.Add(SecureStore{ID: "mail", Password: NewSecret("password")})
+	err := informerLibrary.Conceal(testKey, testDuressKey)
+	if err == nil {
+		err = informerLibrary.WriteLibrary()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushed := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	pushed.Add(SecureStore{ID: "bank", Password: NewSecret("password")})
+	err = pushed.Lock(testDuressKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Only the visible set is synced, and only with secures of its key
+	if _, _, _, err := ApplyPushed(testKey, pushed.PendingChanges()); err != ErrWrongKey {
+		t.Errorf("sealed set synced, %v", err)
+	}
+	if _, _, _, err := ApplyPushed(testDuressKey, pushed.PendingChanges()); err != nil {
+		t.Fatal(err)
+	}
+	other := InformerLibrary{Version: "0.1", Unlocked: true, SecureStore: map[string]*SecureStore{}}
+	other.Add(SecureStore{ID: "shop", Password: NewSecret("password")})
+	err = other.Lock(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := ApplyPushed(testDuressKey, other.PendingChanges()); err != ErrWrongKey {
+		t.Errorf("secure of another key synced, %v", err)
+	}
+
+	stored, err := ReadLibrary()
+	if err != nil || len(stored.SecureStore) != 1 {
+		t.Errorf("%d secures stored, %v", len(stored.SecureStore), err)
+	}
+}
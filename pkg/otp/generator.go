@@ -0,0 +1,116 @@
+package otp
+
+import (
+	"encoding/base32"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// generator Generates codes of an OTP type. Codes of counter based types are of the counter of account,
+// which has to move on whenever one is issued, the others are of time.
+// Types of a fixed period set it, the others use period of account.
+// Types authenticators don't take by otpauth URI set noURI.
+type generator struct {
+	generate func(account Account, t time.Time) (string, error)
+	counter  bool
+	period   int
+	noURI    bool
+}
+
+// generators Generators keyed by OTP type, every type registers itself in init of its file
+var generators = map[string]generator{}
+
+// GenerateCode Generate code of account at time t by generator of its type, honouring its algorithm, digits and period.
+// Zero parameters are defaults of TOTP, SHA1, 6 digits and period of 30 seconds.
+func GenerateCode(account Account, t time.Time) (string, error) {
+	account = account.withDefaults()
+	g, ok := generators[account.Type]
+	if !ok {
+		return "", fmt.Errorf("unsupported OTP type %q", account.Type)
+	}
+
+	return g.generate(account, t)
+}
+
+// Supported Whether codes of OTP type can be generated
+func Supported(otpType string) bool {
+	_, ok := generators[strings.ToLower(otpType)]
+
+	return ok
+}
+
+// CounterBased Whether codes of OTP type are of a counter instead of time
+func CounterBased(otpType string) bool {
+	return generators[strings.ToLower(otpType)].counter
+}
+
+// HasURI Whether accounts of OTP type can be given to authenticators by otpauth URI, such as by QR code
+func HasURI(otpType string) bool {
+	g, ok := generators[strings.ToLower(otpType)]
+
+	return otpType == "" || ok && !g.noURI
+}
+
+// Period Seconds every code of account is valid for, 0 for counter based and unsupported types
+func Period(account Account) int {
+	account = account.withDefaults()
+	g, ok := generators[account.Type]
+	switch {
+	case !ok || g.counter:
+		return 0
+	case g.period > 0:
+		return g.period
+	}
+
+	return account.Period
+}
+
+// Remaining Seconds code of account at time t stays valid for, 0 for counter based and unsupported types
+func Remaining(account Account, t time.Time) int {
+	period := Period(account)
+	if period < 1 {
+		return 0
+	}
+
+	return period - int(t.Unix()%int64(period))
+}
+
+// Types Supported OTP types, sorted
+func Types() []string {
+	var types []string
+	for otpType := range generators {
+		types = append(types, otpType)
+	}
+	sort.Strings(types)
+
+	return types
+}
+
+// withDefaults Account with defaults in place of zero parameters
+func (account Account) withDefaults() Account {
+	account.Type = strings.ToLower(account.Type)
+	if account.Type == "" {
+		account.Type = "totp"
+	}
+	account.Algorithm = strings.ToUpper(account.Algorithm)
+	if account.Algorithm == "" {
+		account.Algorithm = "SHA1"
+	}
+	if account.Digits == 0 {
+		account.Digits = 6
+	}
+	if account.Period == 0 {
+		account.Period = 30
+	}
+
+	return account
+}
+
+// decodeSecret Bytes of base32 secret, spaces and padding are ignored
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+}
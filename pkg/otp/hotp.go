@@ -0,0 +1,43 @@
+package otp
+
+import (
+	"time"
+
+	"github.com/pquerna/otp/hotp"
+)
+
+func init() {
+	generators["hotp"] = generator{generate: generateHOTP, counter: true}
+}
+
+// generateHOTP RFC 4226 code of the counter of account, regardless of t
+func generateHOTP(account Account, _ time.Time) (string, error) {
+	algorithm, digits, err := rfcParameters(account)
+	if err != nil {
+		return "", err
+	}
+
+	return hotp.GenerateCodeCustom(account.Secret, account.Counter, hotp.ValidateOpts{
+		Digits:    digits,
+		Algorithm: algorithm,
+	})
+}
+
+// Resync Counter following two consecutive HOTP codes, searched from counter of account up to window counters ahead
+func Resync(account Account, first string, second string, window uint64) (uint64, bool) {
+	account.Type = "hotp"
+	start := account.Counter
+	for counter := start; counter <= start+window; counter++ {
+		account.Counter = counter
+		if code, err := GenerateCode(account, time.Time{}); err != nil || code != first {
+			continue
+		}
+
+		account.Counter = counter + 1
+		if code, err := GenerateCode(account, time.Time{}); err == nil && code == second {
+			return counter + 2, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,29 @@
+package otp
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+)
+
+func init() {
+	generators["motp"] = generator{generate: generateMOTP, period: 10, noURI: true}
+}
+
+// generateMOTP Mobile-OTP code of account at time t, the first 6 hex digits of MD5 of time in 10 second steps,
+// the secret in lowercase hex and the PIN. Secrets are kept in base32 like the others, as Aegis backups hold them.
+func generateMOTP(account Account, t time.Time) (string, error) {
+	if account.Pin == "" {
+		return "", errors.New("mOTP needs a PIN")
+	}
+	secret, err := decodeSecret(account.Secret)
+	if err != nil {
+		return "", err
+	}
+
+	sum := md5.Sum([]byte(strconv.FormatInt(t.Unix()/10, 10) + hex.EncodeToString(secret) + account.Pin))
+
+	return hex.EncodeToString(sum[:])[:6], nil
+}
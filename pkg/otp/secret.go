@@ -0,0 +1,84 @@
+package otp
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseSecret Account of a secret of otpType as it's typed or pasted, an otpauth URI or a base32 secret.
+// Spaces, hyphens and padding of secrets are left out and letters are uppercased.
+// mOTP secrets are normally hex, they're given as hex: followed by the hex secret and kept in base32 like the others,
+// so a base32 secret is never taken as hex when it's normalized again.
+// Only Secret is set for plain secrets, URIs set the parameters too.
+func ParseSecret(value string, otpType string) (Account, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(strings.ToLower(value), "otpauth-migration:") {
+		return Account{}, errors.New("migration URIs hold several accounts, import them by otp import")
+	}
+
+	if strings.HasPrefix(strings.ToLower(value), "otpauth:") {
+		accounts, err := ParseURI(value)
+		if err != nil {
+			return Account{}, err
+		}
+		account := accounts[0]
+		account.Secret, err = normalizeTypedSecret(account.Secret, account.Type)
+
+		return account, err
+	}
+
+	secret, err := normalizeTypedSecret(value, otpType)
+
+	return Account{Secret: secret}, err
+}
+
+// NormalizeSecret Base32 secret without spaces, hyphens and padding, in uppercase
+func NormalizeSecret(secret string) (string, error) {
+	secret = strings.NewReplacer(" ", "", "\t", "", "-", "").Replace(secret)
+	secret = strings.ToUpper(strings.TrimRight(secret, "="))
+	if secret == "" {
+		return "", errors.New("secret is empty")
+	}
+
+	for i, c := range secret {
+		if (c < 'A' || c > 'Z') && (c < '2' || c > '7') {
+			return "", fmt.Errorf("secret isn't base32, %q at position %d isn't one of A-Z and 2-7", c, i+1)
+		}
+	}
+	//1, 3 or 6 characters past whole blocks of 8 are no whole bytes
+	if remainder := len(secret) % 8; remainder == 1 || remainder == 3 || remainder == 6 {
+		return "", fmt.Errorf("secret isn't base32, %d characters are no whole bytes", len(secret))
+	}
+
+	return secret, nil
+}
+
+// hexSecretPrefix Prefix of mOTP secrets given as hex
+const hexSecretPrefix = "hex:"
+
+// normalizeTypedSecret Normalized secret of otpType, mOTP secrets given as hex are converted to base32
+func normalizeTypedSecret(secret string, otpType string) (string, error) {
+	if !strings.EqualFold(otpType, "motp") {
+		return NormalizeSecret(secret)
+	}
+
+	hexSecret := strings.ToLower(strings.NewReplacer(" ", "", "\t", "", "-", "").Replace(secret))
+	if strings.HasPrefix(hexSecret, hexSecretPrefix) {
+		decoded, err := hex.DecodeString(strings.TrimPrefix(hexSecret, hexSecretPrefix))
+		if err != nil || len(decoded) == 0 {
+			return "", errors.New("secret isn't hex")
+		}
+
+		return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(decoded), nil
+	}
+
+	normalized, err := NormalizeSecret(secret)
+	if _, hexErr := hex.DecodeString(hexSecret); err != nil && hexErr == nil {
+		return "", fmt.Errorf("%w, give a hex secret as %s%s", err, hexSecretPrefix, hexSecret)
+	}
+
+	return normalized, err
+}
@@ -0,0 +1,46 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// steamAlphabet Characters of Steam Guard codes, which leave out look-alikes and vowels
+const steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+
+func init() {
+	generators["steam"] = generator{generate: generateSteam}
+}
+
+// generateSteam Steam Guard code of account at time t, a TOTP of SHA1 written as 5 characters of steamAlphabet.
+// Algorithm and digits of account are ignored.
+func generateSteam(account Account, t time.Time) (string, error) {
+	if account.Period < 1 {
+		return "", errors.New("invalid period")
+	}
+	secret, err := decodeSecret(account.Secret)
+	if err != nil {
+		return "", err
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(t.Unix()/int64(account.Period)))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	//Dynamic truncation of RFC 4226
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	code := make([]byte, 5)
+	for i := range code {
+		code[i] = steamAlphabet[value%uint32(len(steamAlphabet))]
+		value /= uint32(len(steamAlphabet))
+	}
+
+	return string(code), nil
+}
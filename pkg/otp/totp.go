@@ -1,17 +1,59 @@
 package otp
 
 import (
-	"github.com/pquerna/otp/totp"
+	"errors"
+	"fmt"
 	"log"
 	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
 )
 
-// GenerateTotpPassCode Generate Time-based One-Time Password by otpSecret
+var algorithms = map[string]otp.Algorithm{
+	"SHA1":   otp.AlgorithmSHA1,
+	"SHA256": otp.AlgorithmSHA256,
+	"SHA512": otp.AlgorithmSHA512,
+	"MD5":    otp.AlgorithmMD5,
+}
+
+func init() {
+	generators["totp"] = generator{generate: generateTOTP}
+}
+
+// GenerateTotpPassCode Generate Time-based One-Time Password by otpSecret, using SHA1, 6 digits and period of 30 seconds
 func GenerateTotpPassCode(otpSecret string) string {
-	passCode, err := totp.GenerateCode(otpSecret, time.Now())
+	passCode, err := GenerateCode(Account{Secret: otpSecret}, time.Now())
 	if err != nil {
 		log.Println(err.Error())
 	}
 
 	return passCode
 }
+
+// generateTOTP RFC 6238 code of account at time t
+func generateTOTP(account Account, t time.Time) (string, error) {
+	algorithm, digits, err := rfcParameters(account)
+	if err != nil {
+		return "", err
+	}
+
+	return totp.GenerateCodeCustom(account.Secret, t, totp.ValidateOpts{
+		Period:    uint(account.Period),
+		Digits:    digits,
+		Algorithm: algorithm,
+	})
+}
+
+// rfcParameters Algorithm and digits of account for codes of RFC 4226 and RFC 6238
+func rfcParameters(account Account) (otp.Algorithm, otp.Digits, error) {
+	algorithm, ok := algorithms[account.Algorithm]
+	if !ok {
+		return 0, 0, fmt.Errorf("unsupported algorithm %q", account.Algorithm)
+	}
+	if account.Digits < 1 || account.Digits > 10 || account.Period < 1 {
+		return 0, 0, errors.New("invalid digits or period")
+	}
+
+	return algorithm, otp.Digits(account.Digits), nil
+}
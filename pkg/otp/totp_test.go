@@ -0,0 +1,193 @@
+package otp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateCode(t *testing.T) {
+	//Test vectors of RFC 6238 appendix B
+	accounts := []struct {
+		account  Account
+		expected map[int64]string
+	}{
+		{Account{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Digits: 8},
+			map[int64]string{59: "94287082", 1111111109: "07081804", 20000000000: "65353130"}},
+		{Account{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA====", Algorithm: "SHA256", Digits: 8},
+			map[int64]string{59: "46119246", 1111111109: "68084774", 20000000000: "77737706"}},
+		{Account{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA=",
+			Algorithm: "SHA512", Digits: 8}, map[int64]string{59: "90693936", 1111111109: "25091201", 20000000000: "47863826"}},
+	}
+	for _, test := range accounts {
+		for seconds, expected := range test.expected {
+			code, err := GenerateCode(test.account, time.Unix(seconds, 0))
+			if err != nil || code != expected {
+				t.Errorf("%s at %d: generated %q, %v, expected %q", test.account.Algorithm, seconds, code, err, expected)
+			}
+		}
+	}
+
+	//A longer period gives the code of the time step it covers
+	account := Account{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Digits: 8, Period: 60}
+	if code, _ := GenerateCode(account, time.Unix(118, 0)); code != "94287082" {
+		t.Errorf("period 60: generated %q", code)
+	}
+}
+
+func TestURIRoundTrip(t *testing.T) {
+	account := Account{Issuer: "ACME Co", Name: "john@example.com", Secret: "JBSWY3DPEHPK3PXP", Type: "totp", Algorithm: "SHA256", Digits: 8, Period: 60}
+	accounts, err := ParseURI(account.URI())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0] != account {
+		t.Errorf("parsed %+v from %s, expected %+v", accounts, account.URI(), account)
+	}
+
+	//Steam Guard accounts are TOTP of steam encoder, mOTP ones have no URI authenticators take
+	steam := Account{Name: "bob", Secret: "JBSWY3DPEHPK3PXP", Type: "steam", Algorithm: "SHA1", Digits: 6, Period: 30}
+	if uri := steam.URI(); !strings.HasPrefix(uri, "otpauth://totp/") || !strings.Contains(uri, "encoder=steam") {
+		t.Errorf("URI of steam is %s", uri)
+	}
+	if accounts, err := ParseURI(steam.URI()); err != nil || accounts[0] != steam {
+		t.Errorf("parsed %+v from %s, %v", accounts, steam.URI(), err)
+	}
+	if HasURI("motp") || !HasURI("steam") || !HasURI("") {
+		t.Error("types with URI are wrong")
+	}
+
+	for _, uri := range []string{"otpauth://totp/bob?secret=JBSW&digits=six", "otpauth://totp/bob?secret=JBSW&period=-30",
+		"otpauth://hotp/bob?secret=JBSW&counter=x"} {
+		if accounts, err := ParseURI(uri); err == nil {
+			t.Errorf("parsed %+v from %s", accounts, uri)
+		}
+	}
+}
+
+func TestHOTP(t *testing.T) {
+	//Test vectors of RFC 4226 appendix D
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	account := Account{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Type: "hotp"}
+	for counter, code := range expected {
+		account.Counter = uint64(counter)
+		if generated, err := GenerateCode(account, time.Now()); err != nil || generated != code {
+			t.Errorf("counter %d: generated %q, %v, expected %q", counter, generated, err, code)
+		}
+	}
+
+	account.Counter = 2
+	if counter, ok := Resync(account, "287922", "162583", 10); !ok || counter != 8 {
+		t.Errorf("resynchronized to %d, %v", counter, ok)
+	}
+	if _, ok := Resync(account, "287922", "399871", 10); ok {
+		t.Error("codes which aren't consecutive resynchronized")
+	}
+}
+
+func TestNonRFCTypes(t *testing.T) {
+	steam := Account{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Type: "steam"}
+	motp := Account{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Type: "motp", Pin: "1234"}
+	tests := []struct {
+		account  Account
+		expected map[int64]string
+	}{
+		{steam, map[int64]string{59: "PV9M4", 1111111109: "PY4YB", 20000000000: "R5DMB"}},
+		{motp, map[int64]string{59: "707bcd", 1111111109: "acfb4a"}},
+	}
+	for _, test := range tests {
+		for seconds, expected := range test.expected {
+			code, err := GenerateCode(test.account, time.Unix(seconds, 0))
+			if err != nil || code != expected {
+				t.Errorf("%s at %d: generated %q, %v, expected %q", test.account.Type, seconds, code, err, expected)
+			}
+		}
+	}
+
+	motp.Pin = ""
+	if _, err := GenerateCode(motp, time.Now()); err == nil {
+		t.Error("mOTP code generated without PIN")
+	}
+	if _, err := GenerateCode(Account{Secret: steam.Secret, Type: "yandex"}, time.Now()); err == nil {
+		t.Error("code of unregistered type generated")
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	at := time.Unix(59, 0)
+	accounts := []struct {
+		account   Account
+		remaining int
+	}{
+		{Account{}, 1},
+		{Account{Period: 60}, 1},
+		{Account{Period: 45}, 31},
+		{Account{Type: "motp"}, 1},
+		{Account{Type: "hotp"}, 0},
+	}
+	for _, test := range accounts {
+		if remaining := Remaining(test.account, at); remaining != test.remaining {
+			t.Errorf("%+v: %d seconds remaining, expected %d", test.account, remaining, test.remaining)
+		}
+	}
+}
+
+func TestParseSecret(t *testing.T) {
+	valid := map[string]Account{
+		"jbsw y3dp ehpk 3pxp":                      {Secret: "JBSWY3DPEHPK3PXP"},
+		" JBSW-Y3DP-EHPK-3PXP ":                    {Secret: "JBSWY3DPEHPK3PXP"},
+		"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA====": {Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA"},
+		"otpauth://totp/ACME:bob?secret=jbswy3dpehpk3pxp&issuer=ACME&digits=8": {
+			Issuer: "ACME", Name: "bob", Secret: "JBSWY3DPEHPK3PXP", Type: "totp", Algorithm: "SHA1", Digits: 8, Period: 30},
+	}
+	for value, expected := range valid {
+		if account, err := ParseSecret(value, "totp"); err != nil || account != expected {
+			t.Errorf("%q: parsed %+v, %v", value, account, err)
+		}
+	}
+
+	//mOTP secrets are given as hex by prefix, base32 ones are never taken as hex, even if they are valid hex
+	mOTP := map[string]string{
+		"hex:0123 4567 89ab cdef": "AERUKZ4JVPG66",
+		"HEX:0123456789ABCDEF":    "AERUKZ4JVPG66",
+		"JBSWY3DPEHPK3PXP":        "JBSWY3DPEHPK3PXP",
+		"AAAAAAAAAAAAAAAA":        "AAAAAAAAAAAAAAAA",
+	}
+	for value, expected := range mOTP {
+		if account, err := ParseSecret(value, "motp"); err != nil || account.Secret != expected {
+			t.Errorf("%q: parsed %+v, %v", value, account, err)
+		}
+	}
+
+	for _, value := range []string{"0123456789abcdef", "hex:JBSW"} {
+		if account, err := ParseSecret(value, "motp"); err == nil {
+			t.Errorf("%q: parsed %+v", value, account)
+		}
+	}
+	for _, value := range []string{"", "JBSWY3DPEHPK3PX1", "JBSWY3DPE", "otpauth://totp/bob", "otpauth-migration://offline?data=AA"} {
+		if account, err := ParseSecret(value, "totp"); err == nil {
+			t.Errorf("%q: parsed %+v", value, account)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	account := Account{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Digits: 8}
+	at := time.Unix(1111111109, 0)
+	step, ok := Verify(account, "0708 1804", at, 1, 0)
+	if !ok || step != 1111111109/30 {
+		t.Fatalf("verified step %d, %v", step, ok)
+	}
+	if _, ok := Verify(account, "07081804", at.Add(30*time.Second), 1, 0); !ok {
+		t.Error("code of the previous step rejected")
+	}
+	if _, ok := Verify(account, "07081804", at.Add(90*time.Second), 1, 0); ok {
+		t.Error("code out of skew accepted")
+	}
+	if _, ok := Verify(account, "07081804", at, 1, step); ok {
+		t.Error("code replayed")
+	}
+	if _, ok := Verify(Account{Secret: account.Secret, Type: "hotp"}, "755224", at, 1, 0); ok {
+		t.Error("HOTP code verified")
+	}
+}
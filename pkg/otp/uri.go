@@ -0,0 +1,294 @@
+package otp
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Account OTP account of an otpauth or otpauth-migration URI.
+type Account struct {
+	Issuer string
+	//Name is the account name, usually the username
+	Name      string
+	Secret    string
+	Type      string
+	Algorithm string
+	Digits    int
+	Period    int
+	Counter   uint64
+	//Pin is kept along with the secret by mOTP, it's never part of URIs
+	Pin string
+}
+
+var migrationAlgorithms = map[uint64]string{1: "SHA1", 2: "SHA256", 3: "SHA512", 4: "MD5"}
+var migrationDigits = map[uint64]int{1: 6, 2: 8}
+var migrationTypes = map[uint64]string{1: "hotp", 2: "totp"}
+
+// ParseURI Accounts of an otpauth URI, or of an otpauth-migration URI exported by Google Authenticator.
+func ParseURI(uri string) ([]Account, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "otpauth":
+		account, err := parseKeyURI(u)
+		if err != nil {
+			return nil, err
+		}
+		return []Account{account}, nil
+	case "otpauth-migration":
+		return parseMigrationURI(u)
+	}
+
+	return nil, fmt.Errorf("unsupported URI scheme %q", u.Scheme)
+}
+
+func parseKeyURI(u *url.URL) (Account, error) {
+	var err error
+	query := u.Query()
+	account := Account{
+		Type:      strings.ToLower(u.Host),
+		Issuer:    query.Get("issuer"),
+		Secret:    strings.ToUpper(query.Get("secret")),
+		Algorithm: strings.ToUpper(query.Get("algorithm")),
+		Digits:    6,
+		Period:    30,
+	}
+	//KeePassXC keeps Steam Guard accounts as TOTP of steam encoder
+	if account.Type == "totp" && strings.EqualFold(query.Get("encoder"), "steam") {
+		account.Type = "steam"
+	}
+	if !Supported(account.Type) {
+		return Account{}, fmt.Errorf("unsupported OTP type %q", u.Host)
+	}
+	if account.Secret == "" {
+		return Account{}, errors.New("secret is missing")
+	}
+	if account.Algorithm == "" {
+		account.Algorithm = "SHA1"
+	}
+
+	//Label is "issuer:name" or just "name"
+	label := strings.TrimPrefix(u.Path, "/")
+	if i := strings.Index(label, ":"); i >= 0 {
+		if account.Issuer == "" {
+			account.Issuer = strings.TrimSpace(label[:i])
+		}
+		label = label[i+1:]
+	}
+	account.Name = strings.TrimSpace(label)
+
+	if digits := query.Get("digits"); digits != "" {
+		account.Digits, err = strconv.Atoi(digits)
+		if err != nil || account.Digits < 1 {
+			return Account{}, fmt.Errorf("digits %q isn't a number of digits", digits)
+		}
+	}
+	if period := query.Get("period"); period != "" {
+		account.Period, err = strconv.Atoi(period)
+		if err != nil || account.Period < 1 {
+			return Account{}, fmt.Errorf("period %q isn't a number of seconds", period)
+		}
+	}
+	if counter := query.Get("counter"); counter != "" {
+		account.Counter, err = strconv.ParseUint(counter, 10, 64)
+		if err != nil {
+			return Account{}, fmt.Errorf("counter %q isn't a number", counter)
+		}
+	}
+
+	return account, nil
+}
+
+// parseMigrationURI Decode protobuf MigrationPayload in data parameter
+func parseMigrationURI(u *url.URL) ([]Account, error) {
+	//Unescaped "+" of base64 is read as space by query parsing
+	data := strings.ReplaceAll(u.Query().Get("data"), " ", "+")
+	payload, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		payload, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var accounts []Account
+	err = readMessage(payload, func(field uint64, value []byte, _ uint64) error {
+		//Field 1 is repeated OtpParameters, version and batch information are ignored
+		if field != 1 {
+			return nil
+		}
+
+		account, err := parseMigrationParameters(value)
+		if err != nil {
+			return err
+		}
+		accounts = append(accounts, account)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, errors.New("migration payload has no accounts")
+	}
+
+	return accounts, nil
+}
+
+func parseMigrationParameters(message []byte) (Account, error) {
+	account := Account{Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30}
+	err := readMessage(message, func(field uint64, value []byte, number uint64) error {
+		switch field {
+		case 1:
+			account.Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(value)
+		case 2:
+			account.Name = string(value)
+		case 3:
+			account.Issuer = string(value)
+		case 4:
+			if algorithm, ok := migrationAlgorithms[number]; ok {
+				account.Algorithm = algorithm
+			}
+		case 5:
+			if digits, ok := migrationDigits[number]; ok {
+				account.Digits = digits
+			}
+		case 6:
+			if otpType, ok := migrationTypes[number]; ok {
+				account.Type = otpType
+			}
+		case 7:
+			account.Counter = number
+		}
+
+		return nil
+	})
+	if err != nil {
+		return Account{}, err
+	}
+
+	//Name is "issuer:name" when the issuer is in the label
+	if i := strings.Index(account.Name, ":"); i >= 0 {
+		if account.Issuer == "" || strings.EqualFold(strings.TrimSpace(account.Name[:i]), account.Issuer) {
+			if account.Issuer == "" {
+				account.Issuer = strings.TrimSpace(account.Name[:i])
+			}
+			account.Name = strings.TrimSpace(account.Name[i+1:])
+		}
+	}
+	if account.Secret == "" {
+		return Account{}, errors.New("migration account has no secret")
+	}
+
+	return account, nil
+}
+
+// readMessage Call visit for every field of protobuf message, with bytes of length delimited fields
+// and the number of varint fields
+func readMessage(message []byte, visit func(field uint64, value []byte, number uint64) error) error {
+	errInvalid := errors.New("invalid migration payload")
+
+	for len(message) > 0 {
+		key, n := readVarint(message)
+		if n == 0 {
+			return errInvalid
+		}
+		message = message[n:]
+
+		var value []byte
+		var number uint64
+		switch key & 7 {
+		case 0:
+			number, n = readVarint(message)
+			if n == 0 {
+				return errInvalid
+			}
+			message = message[n:]
+		case 1:
+			if len(message) < 8 {
+				return errInvalid
+			}
+			message = message[8:]
+		case 2:
+			length, n := readVarint(message)
+			if n == 0 || uint64(len(message)-n) < length {
+				return errInvalid
+			}
+			value = message[n : n+int(length)]
+			message = message[n+int(length):]
+		case 5:
+			if len(message) < 4 {
+				return errInvalid
+			}
+			message = message[4:]
+		default:
+			return errInvalid
+		}
+
+		if err := visit(key>>3, value, number); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readVarint Value and length of varint at start of data, length is 0 if it's invalid
+func readVarint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+
+	return 0, 0
+}
+
+// URI otpauth URI of account, parameters of default values are left out. Steam Guard accounts are TOTP of steam
+// encoder, as KeePassXC keeps them. Types without URI, as told by HasURI, get one of their own type no authenticator takes.
+func (account Account) URI() string {
+	label := account.Name
+	if account.Issuer != "" {
+		label = account.Issuer + ":" + account.Name
+	}
+
+	query := url.Values{}
+	query.Set("secret", account.Secret)
+	if account.Issuer != "" {
+		query.Set("issuer", account.Issuer)
+	}
+	if account.Algorithm != "" && account.Algorithm != "SHA1" {
+		query.Set("algorithm", account.Algorithm)
+	}
+	if account.Digits != 0 && account.Digits != 6 {
+		query.Set("digits", strconv.Itoa(account.Digits))
+	}
+	if CounterBased(account.Type) {
+		query.Set("counter", strconv.FormatUint(account.Counter, 10))
+	} else if account.Period != 0 && account.Period != 30 {
+		query.Set("period", strconv.Itoa(account.Period))
+	}
+
+	otpType := strings.ToLower(account.Type)
+	switch otpType {
+	case "":
+		otpType = "totp"
+	case "steam":
+		otpType = "totp"
+		query.Set("encoder", "steam")
+	}
+	u := url.URL{Scheme: "otpauth", Host: otpType, Path: "/" + label, RawQuery: query.Encode()}
+
+	return u.String()
+}
@@ -0,0 +1,38 @@
+package otp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMigrationURI(t *testing.T) {
+	uri := "otpauth-migration://offline?data=CjUKCkhlbGxvId6tvu8SGEV4YW1wbGU6YWxpY2VAZ29vZ2xlLmNvbRoHRXhhbXBsZSABKAEwAgorCgpIZWxsbyHerb7vEg9ib2JAZXhhbXBsZS5jb20aBEFDTUUgAigCMAE4KhABGAEgACiVmu86"
+	accounts, err := ParseURI(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Account{
+		{Issuer: "Example", Name: "alice@google.com", Secret: "JBSWY3DPEHPK3PXP", Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30},
+		{Issuer: "ACME", Name: "bob@example.com", Secret: "JBSWY3DPEHPK3PXP", Type: "hotp", Algorithm: "SHA256", Digits: 8, Period: 30, Counter: 42},
+	}
+	if !reflect.DeepEqual(accounts, expected) {
+		t.Errorf("parsed %+v, expected %+v", accounts, expected)
+	}
+}
+
+func TestParseKeyURI(t *testing.T) {
+	accounts, err := ParseURI("otpauth://hotp/ACME%20Co:john@example.com?secret=jbswy3dpehpk3pxp&digits=8&counter=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Account{Issuer: "ACME Co", Name: "john@example.com", Secret: "JBSWY3DPEHPK3PXP", Type: "hotp", Algorithm: "SHA1", Digits: 8, Period: 30, Counter: 5}
+	if len(accounts) != 1 || accounts[0] != expected {
+		t.Errorf("parsed %+v, expected %+v", accounts, expected)
+	}
+
+	if _, err := ParseURI("https://example.com"); err == nil {
+		t.Error("expected error of unsupported scheme")
+	}
+}
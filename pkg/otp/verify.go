@@ -0,0 +1,31 @@
+package otp
+
+import (
+	"crypto/subtle"
+	"strings"
+	"time"
+)
+
+// Verify Whether code is of account at time t, or of skew periods before or after it, and the time step it's of.
+// Steps up to last are rejected, so a code once accepted can't be replayed. Counter based types aren't verified.
+func Verify(account Account, code string, t time.Time, skew int, last int64) (int64, bool) {
+	period := int64(Period(account))
+	if period < 1 {
+		return 0, false
+	}
+	code = strings.ReplaceAll(code, " ", "")
+
+	current := t.Unix() / period
+	for step := current - int64(skew); step <= current+int64(skew); step++ {
+		if step <= last {
+			continue
+		}
+
+		generated, err := GenerateCode(account, time.Unix(step*period, 0))
+		if err == nil && subtle.ConstantTimeCompare([]byte(generated), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
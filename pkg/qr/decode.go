@@ -0,0 +1,327 @@
+// Package qr decodes QR codes from images, such as screenshots of codes shown by websites or authenticator apps.
+// Codes are located by their finder patterns and sampled without perspective correction,
+// so photos taken at an angle may not be readable.
+package qr
+
+import (
+	"errors"
+	"image"
+	"math"
+	"sort"
+)
+
+// ErrNotFound No QR code could be read from image.
+var ErrNotFound = errors.New("no QR code found")
+
+// Decode Text of the QR code in img.
+func Decode(img image.Image) (string, error) {
+	m := binarize(img)
+	finders := m.findFinders()
+
+	err := ErrNotFound
+	for _, candidate := range triples(finders) {
+		topLeft, topRight, bottomLeft := order(candidate)
+		module := (topLeft.module + topRight.module + bottomLeft.module) / 3
+		modulesBetween := (distance(topLeft, topRight) + distance(topLeft, bottomLeft)) / 2 / module
+		estimate := int(math.Round((modulesBetween + 7 - 17) / 4))
+
+		for _, version := range []int{estimate, estimate - 1, estimate + 1} {
+			if version < 1 || version > 40 {
+				continue
+			}
+
+			modules, ok := m.sample(version, topLeft, topRight, bottomLeft)
+			if !ok {
+				continue
+			}
+			var text string
+			text, err = decodeMatrix(modules, version)
+			if err == nil {
+				return text, nil
+			}
+		}
+	}
+
+	return "", err
+}
+
+// bitmap Image reduced to dark and light pixels
+type bitmap struct {
+	width  int
+	height int
+	dark   []bool
+}
+
+// binarize Separate dark from light pixels by Otsu's threshold, transparent pixels are light
+func binarize(img image.Image) bitmap {
+	bounds := img.Bounds()
+	m := bitmap{width: bounds.Dx(), height: bounds.Dy(), dark: make([]bool, bounds.Dx()*bounds.Dy())}
+	luminance := make([]uint8, len(m.dark))
+	var histogram [256]int
+
+	for y := 0; y < m.height; y++ {
+		for x := 0; x < m.width; x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			light := 0xffff - a
+			l := uint8(((299*(r+light) + 587*(g+light) + 114*(b+light)) / 1000) >> 8)
+			luminance[y*m.width+x] = l
+			histogram[l]++
+		}
+	}
+
+	total := float64(len(luminance))
+	var sum float64
+	for i, count := range histogram {
+		sum += float64(i * count)
+	}
+	var sumBackground, weightBackground, bestVariance float64
+	threshold := 128
+	for i, count := range histogram {
+		weightBackground += float64(count)
+		if weightBackground == 0 {
+			continue
+		}
+		weightForeground := total - weightBackground
+		if weightForeground == 0 {
+			break
+		}
+		sumBackground += float64(i * count)
+		meanBackground := sumBackground / weightBackground
+		meanForeground := (sum - sumBackground) / weightForeground
+		variance := weightBackground * weightForeground * (meanBackground - meanForeground) * (meanBackground - meanForeground)
+		if variance > bestVariance {
+			bestVariance = variance
+			threshold = i + 1
+		}
+	}
+
+	for i, l := range luminance {
+		m.dark[i] = int(l) < threshold
+	}
+
+	return m
+}
+
+func (m bitmap) inside(x int, y int) bool {
+	return x >= 0 && y >= 0 && x < m.width && y < m.height
+}
+
+func (m bitmap) at(x int, y int) bool {
+	return m.dark[y*m.width+x]
+}
+
+// finder Center of a finder pattern, module is its estimated module size in pixels
+type finder struct {
+	x      float64
+	y      float64
+	module float64
+	count  int
+}
+
+// findFinders Scan rows for dark-light-dark-light-dark runs of ratio 1:1:3:1:1, confirmed across the column
+func (m bitmap) findFinders() []finder {
+	var finders []finder
+	for y := 0; y < m.height; y++ {
+		var starts, lengths []int
+		var darks []bool
+		for x := 0; x < m.width; x++ {
+			if x == 0 || m.at(x, y) != m.at(x-1, y) {
+				starts = append(starts, x)
+				lengths = append(lengths, 0)
+				darks = append(darks, m.at(x, y))
+			}
+			lengths[len(lengths)-1]++
+		}
+
+		for i := 0; i+5 <= len(lengths); i++ {
+			if !darks[i] {
+				continue
+			}
+			var counts [5]int
+			copy(counts[:], lengths[i:i+5])
+			if !finderRatio(counts) {
+				continue
+			}
+
+			centerX := float64(starts[i+2]) + float64(lengths[i+2])/2
+			centerY, verticalTotal, ok := m.crossCheck(int(centerX), y, 0, 1)
+			if !ok {
+				continue
+			}
+			centerX, horizontalTotal, ok := m.crossCheck(int(centerX), int(centerY), 1, 0)
+			if !ok {
+				continue
+			}
+
+			finders = addFinder(finders, finder{
+				x:      centerX,
+				y:      centerY,
+				module: float64(verticalTotal+horizontalTotal) / 14,
+				count:  1,
+			})
+		}
+	}
+
+	return finders
+}
+
+func finderRatio(counts [5]int) bool {
+	total := 0
+	for _, count := range counts {
+		if count == 0 {
+			return false
+		}
+		total += count
+	}
+	if total < 7 {
+		return false
+	}
+
+	module := float64(total) / 7
+	variance := module / 2
+	return math.Abs(module-float64(counts[0])) < variance &&
+		math.Abs(module-float64(counts[1])) < variance &&
+		math.Abs(3*module-float64(counts[2])) < 3*variance &&
+		math.Abs(module-float64(counts[3])) < variance &&
+		math.Abs(module-float64(counts[4])) < variance
+}
+
+// crossCheck Measure runs of a finder pattern through dark pixel (x, y) along direction (dx, dy).
+// Returns the center coordinate along the direction and the total length.
+func (m bitmap) crossCheck(x int, y int, dx int, dy int) (float64, int, bool) {
+	if !m.inside(x, y) || !m.at(x, y) {
+		return 0, 0, false
+	}
+
+	var counts [5]int
+	walk := func(px int, py int, step int, index int, dark bool) (int, int) {
+		for m.inside(px, py) && m.at(px, py) == dark {
+			counts[index]++
+			px += step * dx
+			py += step * dy
+		}
+
+		return px, py
+	}
+
+	px, py := walk(x, y, -1, 2, true)
+	px, py = walk(px, py, -1, 1, false)
+	px, py = walk(px, py, -1, 0, true)
+	start := px*dx + py*dy + 1
+
+	px, py = walk(x+dx, y+dy, 1, 2, true)
+	px, py = walk(px, py, 1, 3, false)
+	walk(px, py, 1, 4, true)
+
+	if !finderRatio(counts) {
+		return 0, 0, false
+	}
+
+	total := counts[0] + counts[1] + counts[2] + counts[3] + counts[4]
+
+	return float64(start+counts[0]+counts[1]) + float64(counts[2])/2, total, true
+}
+
+// addFinder Merge a finder found again on another row, or add a new one
+func addFinder(finders []finder, found finder) []finder {
+	for i, known := range finders {
+		if math.Abs(known.x-found.x) <= known.module && math.Abs(known.y-found.y) <= known.module &&
+			math.Abs(known.module-found.module) <= known.module/2 {
+			count := float64(known.count)
+			finders[i] = finder{
+				x:      (known.x*count + found.x) / (count + 1),
+				y:      (known.y*count + found.y) / (count + 1),
+				module: (known.module*count + found.module) / (count + 1),
+				count:  known.count + 1,
+			}
+			return finders
+		}
+	}
+
+	return append(finders, found)
+}
+
+// triples Combinations of three finders, the most likely to belong to the same code first
+func triples(finders []finder) [][3]finder {
+	sort.Slice(finders, func(i, j int) bool {
+		return finders[i].count > finders[j].count
+	})
+	if len(finders) > 8 {
+		finders = finders[:8]
+	}
+
+	type scored struct {
+		triple [3]finder
+		score  float64
+	}
+	var candidates []scored
+	for i := 0; i < len(finders); i++ {
+		for j := i + 1; j < len(finders); j++ {
+			for k := j + 1; k < len(finders); k++ {
+				topLeft, topRight, bottomLeft := order([3]finder{finders[i], finders[j], finders[k]})
+				top, left := distance(topLeft, topRight), distance(topLeft, bottomLeft)
+				minModule := math.Min(finders[i].module, math.Min(finders[j].module, finders[k].module))
+				maxModule := math.Max(finders[i].module, math.Max(finders[j].module, finders[k].module))
+				//Sides of a code are equally long, and its finders equally large
+				score := math.Abs(top-left)/math.Max(top, left) + (maxModule-minModule)/maxModule
+				candidates = append(candidates, scored{triple: [3]finder{finders[i], finders[j], finders[k]}, score: score})
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].score < candidates[j].score
+	})
+
+	result := make([][3]finder, len(candidates))
+	for i, candidate := range candidates {
+		result[i] = candidate.triple
+	}
+
+	return result
+}
+
+// order Top left finder is opposite of the longest side, top right follows it clockwise
+func order(finders [3]finder) (finder, finder, finder) {
+	a, b, c := finders[0], finders[1], finders[2]
+	ab, bc, ac := distance(a, b), distance(b, c), distance(a, c)
+
+	topLeft, p, q := a, b, c
+	if ac > ab && ac > bc {
+		topLeft, p, q = b, a, c
+	} else if ab > bc && ab > ac {
+		topLeft, p, q = c, a, b
+	}
+
+	if (p.x-topLeft.x)*(q.y-topLeft.y)-(p.y-topLeft.y)*(q.x-topLeft.x) < 0 {
+		p, q = q, p
+	}
+
+	return topLeft, p, q
+}
+
+func distance(a finder, b finder) float64 {
+	return math.Hypot(a.x-b.x, a.y-b.y)
+}
+
+// sample Read modules of version at positions given by finder centers
+func (m bitmap) sample(version int, topLeft finder, topRight finder, bottomLeft finder) ([][]bool, bool) {
+	dimension := size(version)
+	span := float64(dimension - 7)
+
+	modules := make([][]bool, dimension)
+	for row := range modules {
+		modules[row] = make([]bool, dimension)
+		for column := range modules[row] {
+			u := (float64(column) + 0.5 - 3.5) / span
+			v := (float64(row) + 0.5 - 3.5) / span
+			x := int(math.Floor(topLeft.x + u*(topRight.x-topLeft.x) + v*(bottomLeft.x-topLeft.x)))
+			y := int(math.Floor(topLeft.y + u*(topRight.y-topLeft.y) + v*(bottomLeft.y-topLeft.y)))
+			if !m.inside(x, y) {
+				return nil, false
+			}
+			modules[row][column] = m.at(x, y)
+		}
+	}
+
+	return modules, true
+}
@@ -0,0 +1,120 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/boombuler/barcode"
+	encoder "github.com/boombuler/barcode/qr"
+)
+
+func encode(t *testing.T, text string, level encoder.ErrorCorrectionLevel, scale int) *image.Gray {
+	code, err := encoder.Encode(text, level, encoder.Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	width := code.Bounds().Dx() * scale
+	scaled, err := barcode.Scale(code, width, width)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Quiet zone around the code
+	img := image.NewGray(image.Rect(0, 0, width+8*scale, width+8*scale))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, scaled.Bounds().Add(image.Pt(4*scale, 4*scale)), scaled, image.Point{}, draw.Src)
+
+	return img
+}
+
+func TestDecode(t *testing.T) {
+	texts := []string{
+		"otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP&issuer=GitHub",
+		"0123456789012345",
+		"HELLO WORLD",
+		"otpauth-migration://offline?data=CjUKCkhlbGxvId6tvu8SGEV4YW1wbGU6YWxpY2VAZ29vZ2xlLmNvbRoHRXhhbXBsZSABKAEwAgorCgpIZWxsbyHerb7vEg9ib2JAZXhhbXBsZS5jb20aBEFDTUUgAigCMAE4KhABGAEgACiVmu86" +
+			"&padding=0123456789abcdefghijklmnopqrstuvwxyz0123456789abcdefghijklmnopqrstuvwxyz0123456789abcdefghijklmnopqrstuvwxyz",
+	}
+	for _, level := range []encoder.ErrorCorrectionLevel{encoder.L, encoder.M, encoder.Q, encoder.H} {
+		for _, text := range texts {
+			decoded, err := Decode(encode(t, text, level, 4))
+			if err != nil || decoded != text {
+				t.Errorf("level %v: decoded %q, %v", level, decoded, err)
+			}
+		}
+	}
+}
+
+func TestDecodeDamagedRotated(t *testing.T) {
+	text := "otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP&issuer=GitHub"
+	img := encode(t, text, encoder.H, 5)
+
+	//Damage a few modules in the middle, error correction restores them
+	bounds := img.Bounds()
+	for y := bounds.Dy()/2 - 10; y < bounds.Dy()/2+10; y++ {
+		for x := bounds.Dx()/2 - 10; x < bounds.Dx()/2+10; x++ {
+			img.SetGray(x, y, color.Gray{Y: 255 - img.GrayAt(x, y).Y})
+		}
+	}
+
+	//Rotate by 90 degrees
+	rotated := image.NewGray(image.Rect(0, 0, bounds.Dy(), bounds.Dx()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			rotated.SetGray(bounds.Dy()-1-y, x, img.GrayAt(x, y))
+		}
+	}
+
+	decoded, err := Decode(rotated)
+	if err != nil || decoded != text {
+		t.Errorf("decoded %q, %v", decoded, err)
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	//Version 1-M block of "01234567" from ISO/IEC 18004 annex I
+	block := []byte{0x10, 0x20, 0x0c, 0x56, 0x61, 0x80, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11,
+		0xa5, 0x24, 0xd4, 0xc1, 0xed, 0x36, 0xc7, 0x87, 0x2c, 0x55}
+	damaged := append([]byte{}, block...)
+	for _, i := range []int{0, 7, 13, 20, 25} {
+		damaged[i] ^= 0x5a
+	}
+
+	if err := correct(damaged, 10); err != nil {
+		t.Fatal(err)
+	}
+	if string(damaged) != string(block) {
+		t.Errorf("corrected %x, expected %x", damaged, block)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 100, 100))
+	if _, err := Decode(img); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	text := "otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP&issuer=GitHub"
+	img, err := Encode(text, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, err := Decode(img); err != nil || decoded != text {
+		t.Errorf("decoded %q, %v", decoded, err)
+	}
+
+	code, err := Terminal(text, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+	if width := img.Bounds().Dx() / 4; len(lines) != (width+1)/2 || utf8.RuneCountInString(lines[0]) != width {
+		t.Errorf("%d lines of %d characters for %d modules", len(lines), utf8.RuneCountInString(lines[0]), width)
+	}
+}
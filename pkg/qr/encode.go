@@ -0,0 +1,106 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/boombuler/barcode/qr"
+)
+
+// quietZone Light modules around a code, as wide as readers expect
+const quietZone = 4
+
+// Encode QR code of text as an image of scale pixels per module, quiet zone included
+func Encode(text string, scale int) (image.Image, error) {
+	if scale < 1 {
+		scale = 1
+	}
+	modules, err := encodeModules(text)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewGray(image.Rect(0, 0, len(modules)*scale, len(modules)*scale))
+	for y := range modules {
+		for x, dark := range modules[y] {
+			c := color.Gray{Y: 0xff}
+			if dark {
+				c = color.Gray{}
+			}
+			for i := 0; i < scale*scale; i++ {
+				img.SetGray(x*scale+i%scale, y*scale+i/scale, c)
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// Terminal QR code of text drawn by characters, quiet zone included.
+// Unicode codes pack two rows of modules into a line of half blocks, drawing the light modules so they're read
+// on dark terminals. ANSI codes draw modules as spaces of black and white background, read on any terminal.
+func Terminal(text string, ansi bool) (string, error) {
+	modules, err := encodeModules(text)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	if ansi {
+		for y := range modules {
+			for _, dark := range modules[y] {
+				if dark {
+					builder.WriteString("\033[40m  ")
+				} else {
+					builder.WriteString("\033[47m  ")
+				}
+			}
+			builder.WriteString("\033[0m\n")
+		}
+
+		return builder.String(), nil
+	}
+
+	for y := 0; y < len(modules); y += 2 {
+		for x := range modules[y] {
+			//Rows past the code are light, like the quiet zone
+			top, bottom := !modules[y][x], y+1 >= len(modules) || !modules[y+1][x]
+			switch {
+			case top && bottom:
+				builder.WriteString("█")
+			case top:
+				builder.WriteString("▀")
+			case bottom:
+				builder.WriteString("▄")
+			default:
+				builder.WriteString(" ")
+			}
+		}
+		builder.WriteString("\n")
+	}
+
+	return builder.String(), nil
+}
+
+// encodeModules Modules of QR code of text with medium error correction, true modules are dark
+func encodeModules(text string) ([][]bool, error) {
+	code, err := qr.Encode(text, qr.M, qr.Auto)
+	if err != nil {
+		return nil, err
+	}
+
+	size := code.Bounds().Dx()
+	modules := make([][]bool, size+2*quietZone)
+	for y := range modules {
+		modules[y] = make([]bool, size+2*quietZone)
+	}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			gray := color.GrayModel.Convert(code.At(code.Bounds().Min.X+x, code.Bounds().Min.Y+y)).(color.Gray)
+			modules[y+quietZone][x+quietZone] = gray.Y < 0x80
+		}
+	}
+
+	return modules, nil
+}
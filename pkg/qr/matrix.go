@@ -0,0 +1,286 @@
+package qr
+
+import (
+	"errors"
+	"math/bits"
+	"strings"
+)
+
+// decodeMatrix Decode modules of a QR code of version, dark modules are true
+func decodeMatrix(modules [][]bool, version int) (string, error) {
+	level, mask, err := readFormat(modules)
+	if err != nil {
+		return "", err
+	}
+
+	codewords := readCodewords(modules, version, mask)
+	data, err := correctBlocks(codewords, version, level)
+	if err != nil {
+		return "", err
+	}
+
+	return decodeSegments(data, version)
+}
+
+// formatPositions Row and column of every format bit, lowest bit first, in both copies
+func formatPositions(dimension int) [2][15][2]int {
+	var positions [2][15][2]int
+	for i := 0; i <= 5; i++ {
+		positions[0][i] = [2]int{i, 8}
+	}
+	positions[0][6] = [2]int{7, 8}
+	positions[0][7] = [2]int{8, 8}
+	positions[0][8] = [2]int{8, 7}
+	for i := 9; i < 15; i++ {
+		positions[0][i] = [2]int{8, 14 - i}
+	}
+
+	for i := 0; i < 8; i++ {
+		positions[1][i] = [2]int{8, dimension - 1 - i}
+	}
+	for i := 8; i < 15; i++ {
+		positions[1][i] = [2]int{dimension - 15 + i, 8}
+	}
+
+	return positions
+}
+
+// readFormat Error correction level and mask of the valid format closest to either copy
+func readFormat(modules [][]bool) (int, int, error) {
+	best, bestDistance := 0, 16
+	for _, copyPositions := range formatPositions(len(modules)) {
+		read := 0
+		for i, position := range copyPositions {
+			if modules[position[0]][position[1]] {
+				read |= 1 << i
+			}
+		}
+
+		for data := 0; data < 32; data++ {
+			if distance := bits.OnesCount(uint(read ^ formatBits(data))); distance < bestDistance {
+				best, bestDistance = data, distance
+			}
+		}
+	}
+	if bestDistance > 3 {
+		return 0, 0, errors.New("format information unreadable")
+	}
+
+	return formatLevels[best>>3], best & 7, nil
+}
+
+// formatBits Format information of level and mask bits with BCH code and mask
+func formatBits(data int) int {
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 9) * 0x537)
+	}
+
+	return (data<<10 | remainder) ^ 0x5412
+}
+
+func masked(mask int, row int, column int) bool {
+	switch mask {
+	case 0:
+		return (row+column)%2 == 0
+	case 1:
+		return row%2 == 0
+	case 2:
+		return column%3 == 0
+	case 3:
+		return (row+column)%3 == 0
+	case 4:
+		return (row/2+column/3)%2 == 0
+	case 5:
+		return row*column%2+row*column%3 == 0
+	case 6:
+		return (row*column%2+row*column%3)%2 == 0
+	default:
+		return ((row+column)%2+row*column%3)%2 == 0
+	}
+}
+
+// readCodewords Read codewords in zigzag order from the bottom right, two columns at a time
+func readCodewords(modules [][]bool, version int, mask int) []byte {
+	dimension := len(modules)
+	function := functionModules(version)
+	codewords := make([]byte, rawCodewords(version))
+
+	i := 0
+	for right := dimension - 1; right >= 1; right -= 2 {
+		//Vertical timing pattern is skipped as a whole column
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vertical := 0; vertical < dimension; vertical++ {
+			row := vertical
+			if upward {
+				row = dimension - 1 - vertical
+			}
+			for j := 0; j < 2; j++ {
+				column := right - j
+				if function[row][column] || i >= len(codewords)*8 {
+					continue
+				}
+				if modules[row][column] != masked(mask, row, column) {
+					codewords[i>>3] |= 0x80 >> (i & 7)
+				}
+				i++
+			}
+		}
+	}
+
+	return codewords
+}
+
+// correctBlocks Deinterleave codewords into blocks, correct every block and join their data
+func correctBlocks(codewords []byte, version int, level int) ([]byte, error) {
+	blockCount := ecBlocks[level][version]
+	ecLength := ecCodewordsPerBlock[level][version]
+	shortBlocks := blockCount - len(codewords)%blockCount
+	shortLength := len(codewords) / blockCount
+	shortData := shortLength - ecLength
+
+	blocks := make([][]byte, blockCount)
+	for j := range blocks {
+		length := shortLength
+		if j >= shortBlocks {
+			length++
+		}
+		blocks[j] = make([]byte, 0, length)
+	}
+
+	//Short blocks lack one data codeword, long blocks have it at index shortData
+	next := 0
+	for i := 0; i <= shortLength; i++ {
+		for j := range blocks {
+			if i == shortData && j < shortBlocks {
+				continue
+			}
+			blocks[j] = append(blocks[j], codewords[next])
+			next++
+		}
+	}
+
+	var data []byte
+	for _, block := range blocks {
+		if err := correct(block, ecLength); err != nil {
+			return nil, err
+		}
+		data = append(data, block[:len(block)-ecLength]...)
+	}
+
+	return data, nil
+}
+
+const alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// bitReader Read bits of data, most significant first
+type bitReader struct {
+	data     []byte
+	position int
+}
+
+func (reader *bitReader) available() int {
+	return len(reader.data)*8 - reader.position
+}
+
+func (reader *bitReader) read(count int) int {
+	value := 0
+	for i := 0; i < count; i++ {
+		value <<= 1
+		if reader.data[reader.position>>3]&(0x80>>(reader.position&7)) != 0 {
+			value |= 1
+		}
+		reader.position++
+	}
+
+	return value
+}
+
+// decodeSegments Decode numeric, alphanumeric and byte segments, ECI designators are skipped
+func decodeSegments(data []byte, version int) (string, error) {
+	reader := &bitReader{data: data}
+	var text strings.Builder
+	sizeClass := 0
+	if version >= 10 {
+		sizeClass = 1
+	}
+	if version >= 27 {
+		sizeClass = 2
+	}
+	errTruncated := errors.New("data truncated")
+
+	for reader.available() >= 4 {
+		mode := reader.read(4)
+		switch mode {
+		case 0:
+			return text.String(), nil
+		case 7:
+			//ECI designator of one to three bytes
+			if reader.available() < 8 {
+				return "", errTruncated
+			}
+			first := reader.read(8)
+			switch {
+			case first&0x80 == 0:
+			case first&0xc0 == 0x80:
+				reader.read(8)
+			default:
+				reader.read(16)
+			}
+		case 1:
+			count := reader.read([3]int{10, 12, 14}[sizeClass])
+			for ; count >= 3; count -= 3 {
+				if reader.available() < 10 {
+					return "", errTruncated
+				}
+				text.WriteString(padNumber(reader.read(10), 3))
+			}
+			if count == 2 {
+				text.WriteString(padNumber(reader.read(7), 2))
+			} else if count == 1 {
+				text.WriteString(padNumber(reader.read(4), 1))
+			}
+		case 2:
+			count := reader.read([3]int{9, 11, 13}[sizeClass])
+			for ; count >= 2; count -= 2 {
+				if reader.available() < 11 {
+					return "", errTruncated
+				}
+				pair := reader.read(11)
+				if pair/45 >= 45 {
+					return "", errors.New("invalid alphanumeric data")
+				}
+				text.WriteByte(alphanumeric[pair/45])
+				text.WriteByte(alphanumeric[pair%45])
+			}
+			if count == 1 {
+				text.WriteByte(alphanumeric[reader.read(6)%45])
+			}
+		case 4:
+			count := reader.read([3]int{8, 16, 16}[sizeClass])
+			if reader.available() < count*8 {
+				return "", errTruncated
+			}
+			for i := 0; i < count; i++ {
+				text.WriteByte(byte(reader.read(8)))
+			}
+		default:
+			return "", errors.New("unsupported data mode")
+		}
+	}
+
+	return text.String(), nil
+}
+
+func padNumber(value int, digits int) string {
+	number := []byte(strings.Repeat("0", digits))
+	for i := digits - 1; i >= 0; i-- {
+		number[i] = byte('0' + value%10)
+		value /= 10
+	}
+
+	return string(number)
+}
@@ -0,0 +1,159 @@
+package qr
+
+import "errors"
+
+var errTooManyErrors = errors.New("too many errors to correct")
+
+// Arithmetic of GF(256) with primitive polynomial x^8 + x^4 + x^3 + x^2 + 1, as used by QR codes
+var gfExp, gfLog = func() ([512]byte, [256]int) {
+	var exp [512]byte
+	var log [256]int
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+
+	return exp, log
+}()
+
+func gfMul(a byte, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	return gfExp[gfLog[a]+gfLog[b]]
+}
+
+func gfDiv(a byte, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+
+	return gfExp[gfLog[a]+255-gfLog[b]]
+}
+
+// gfPow Power of generator α
+func gfPow(power int) byte {
+	power %= 255
+	if power < 0 {
+		power += 255
+	}
+
+	return gfExp[power]
+}
+
+// evaluate Value of polynomial with lowest degree coefficient first
+func evaluate(polynomial []byte, x byte) byte {
+	var y byte
+	for i := len(polynomial) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ polynomial[i]
+	}
+
+	return y
+}
+
+// correct Correct block in place, whose last ecLength bytes are error correction codewords.
+// The first byte of block is the coefficient of the highest degree.
+func correct(block []byte, ecLength int) error {
+	n := len(block)
+
+	syndromes := make([]byte, ecLength)
+	clean := true
+	for j := range syndromes {
+		for _, c := range block {
+			syndromes[j] = gfMul(syndromes[j], gfPow(j)) ^ c
+		}
+		if syndromes[j] != 0 {
+			clean = false
+		}
+	}
+	if clean {
+		return nil
+	}
+
+	//Berlekamp-Massey, locator polynomial with lowest degree coefficient first
+	locator, previous := []byte{1}, []byte{1}
+	errorCount, shift := 0, 1
+	var previousDiscrepancy byte = 1
+	for i := 0; i < ecLength; i++ {
+		discrepancy := syndromes[i]
+		for j := 1; j <= errorCount && j < len(locator); j++ {
+			discrepancy ^= gfMul(locator[j], syndromes[i-j])
+		}
+		if discrepancy == 0 {
+			shift++
+			continue
+		}
+
+		factor := gfDiv(discrepancy, previousDiscrepancy)
+		updated := make([]byte, max(len(locator), len(previous)+shift))
+		copy(updated, locator)
+		for j, c := range previous {
+			updated[j+shift] ^= gfMul(factor, c)
+		}
+
+		if 2*errorCount <= i {
+			previous = locator
+			errorCount = i + 1 - errorCount
+			previousDiscrepancy = discrepancy
+			shift = 1
+		} else {
+			shift++
+		}
+		locator = updated
+	}
+	if 2*errorCount > ecLength {
+		return errTooManyErrors
+	}
+
+	//Chien search, an error at degree e makes α^-e a root of the locator
+	var degrees []int
+	for e := 0; e < n; e++ {
+		if evaluate(locator, gfPow(-e)) == 0 {
+			degrees = append(degrees, e)
+		}
+	}
+	if len(degrees) != errorCount {
+		return errTooManyErrors
+	}
+
+	//Forney, evaluator polynomial is syndromes times locator modulo x^ecLength
+	evaluator := make([]byte, ecLength)
+	for i := range evaluator {
+		for j := 0; j <= i && j < len(locator); j++ {
+			evaluator[i] ^= gfMul(locator[j], syndromes[i-j])
+		}
+	}
+	derivative := make([]byte, len(locator))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+
+	for _, e := range degrees {
+		inverse := gfPow(-e)
+		denominator := evaluate(derivative, inverse)
+		if denominator == 0 {
+			return errTooManyErrors
+		}
+		magnitude := gfMul(gfPow(e), gfDiv(evaluate(evaluator, inverse), denominator))
+		block[n-1-e] ^= magnitude
+	}
+
+	return nil
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
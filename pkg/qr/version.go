@@ -0,0 +1,108 @@
+package qr
+
+// Error correction levels in the order of the tables below. Format information encodes them as 1, 0, 3, 2.
+const (
+	levelL = iota
+	levelM
+	levelQ
+	levelH
+)
+
+var formatLevels = [4]int{levelM, levelL, levelH, levelQ}
+
+// ecCodewordsPerBlock Error correction codewords of every block, by level and version
+var ecCodewordsPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// ecBlocks Number of blocks, by level and version
+var ecBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+func size(version int) int {
+	return version*4 + 17
+}
+
+// alignmentPositions Row and column coordinates of alignment pattern centers
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+
+	count := version/7 + 2
+	step := 26
+	if version != 32 {
+		step = (version*4 + count*2 + 1) / (count*2 - 2) * 2
+	}
+
+	positions := make([]int, count)
+	positions[0] = 6
+	for i, position := count-1, size(version)-7; i > 0; i, position = i-1, position-step {
+		positions[i] = position
+	}
+
+	return positions
+}
+
+// rawCodewords Codewords of data and error correction fitting into version
+func rawCodewords(version int) int {
+	bits := (16*version+128)*version + 64
+	if version >= 2 {
+		count := version/7 + 2
+		bits -= (25*count-10)*count - 55
+		if version >= 7 {
+			bits -= 36
+		}
+	}
+
+	return bits / 8
+}
+
+// functionModules Modules of finder, timing and alignment patterns, format and version information
+func functionModules(version int) [][]bool {
+	dimension := size(version)
+	function := make([][]bool, dimension)
+	for i := range function {
+		function[i] = make([]bool, dimension)
+	}
+	setRegion := func(row int, column int, height int, width int) {
+		for y := row; y < row+height; y++ {
+			for x := column; x < column+width; x++ {
+				function[y][x] = true
+			}
+		}
+	}
+
+	//Finder patterns with separators and format information
+	setRegion(0, 0, 9, 9)
+	setRegion(0, dimension-8, 9, 8)
+	setRegion(dimension-8, 0, 8, 9)
+
+	positions := alignmentPositions(version)
+	for i, row := range positions {
+		for j, column := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == len(positions)-1) || (i == len(positions)-1 && j == 0) {
+				continue
+			}
+			setRegion(row-2, column-2, 5, 5)
+		}
+	}
+
+	//Timing patterns
+	setRegion(6, 9, 1, dimension-17)
+	setRegion(9, 6, dimension-17, 1)
+
+	if version >= 7 {
+		setRegion(0, dimension-11, 6, 3)
+		setRegion(dimension-11, 0, 3, 6)
+	}
+
+	return function
+}
@@ -0,0 +1,108 @@
+package strength
+
+import (
+	"math"
+	"strings"
+)
+
+// Feedback Warning about what makes a password guessable, and suggestions to make it less so
+type Feedback struct {
+	Warning     string   `json:"warning"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// feedback Feedback of password of score, by the longest match of its sequence
+func feedback(score int, sequence []*match) Feedback {
+	if len(sequence) == 0 {
+		return Feedback{Suggestions: []string{
+			"Use a few words, avoid common phrases",
+			"No need for symbols, digits, or uppercase letters",
+		}}
+	}
+	if score > Weak {
+		return Feedback{Suggestions: []string{}}
+	}
+
+	longest := sequence[0]
+	for _, m := range sequence[1:] {
+		if len(m.token) > len(longest.token) {
+			longest = m
+		}
+	}
+
+	result := matchFeedback(longest, len(sequence) == 1)
+	result.Suggestions = append([]string{"Add another word or two. Uncommon words are better."}, result.Suggestions...)
+
+	return result
+}
+
+// matchFeedback Feedback of a match, a match being the whole password is warned about more
+func matchFeedback(m *match, sole bool) Feedback {
+	switch m.pattern {
+	case "dictionary":
+		return dictionaryFeedback(m, sole)
+	case "spatial":
+		warning := "Short keyboard patterns are easy to guess"
+		if m.turns == 1 {
+			warning = "Straight rows of keys are easy to guess"
+		}
+		return Feedback{Warning: warning, Suggestions: []string{"Use a longer keyboard pattern with more turns"}}
+	case "repeat":
+		warning := `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`
+		if len([]rune(m.base)) == 1 {
+			warning = `Repeats like "aaa" are easy to guess`
+		}
+		return Feedback{Warning: warning, Suggestions: []string{"Avoid repeated words and characters"}}
+	case "sequence":
+		return Feedback{Warning: "Sequences like abc or 6543 are easy to guess", Suggestions: []string{"Avoid sequences"}}
+	case "year":
+		return Feedback{Warning: "Recent years are easy to guess", Suggestions: []string{"Avoid recent years", "Avoid years that are associated with you"}}
+	case "date":
+		return Feedback{Warning: "Dates are often easy to guess", Suggestions: []string{"Avoid dates and years that are associated with you"}}
+	}
+
+	return Feedback{Suggestions: []string{}}
+}
+
+func dictionaryFeedback(m *match, sole bool) Feedback {
+	var result Feedback
+	switch m.dictionary {
+	case "passwords":
+		switch {
+		case sole && len(m.sub) == 0 && !m.reversed && m.rank <= 10:
+			result.Warning = "This is a top-10 common password"
+		case sole && len(m.sub) == 0 && !m.reversed && m.rank <= 100:
+			result.Warning = "This is a top-100 common password"
+		case sole && len(m.sub) == 0 && !m.reversed:
+			result.Warning = "This is a very common password"
+		case math.Log10(m.guesses) <= 4:
+			result.Warning = "This is similar to a commonly used password"
+		}
+	case "english":
+		if sole {
+			result.Warning = "A word by itself is easy to guess"
+		}
+	case "surnames", "male_names", "female_names":
+		result.Warning = "Common names and surnames are easy to guess"
+		if sole {
+			result.Warning = "Names and surnames by themselves are easy to guess"
+		}
+	case "user_inputs":
+		result.Warning = "Passwords containing your username or the site are easy to guess"
+	}
+
+	result.Suggestions = []string{}
+	if startUpper.MatchString(m.token) {
+		result.Suggestions = append(result.Suggestions, "Capitalization doesn't help very much")
+	} else if allUpper.MatchString(m.token) && strings.ToLower(m.token) != m.token {
+		result.Suggestions = append(result.Suggestions, "All-uppercase is almost as easy to guess as all-lowercase")
+	}
+	if m.reversed && len([]rune(m.token)) >= 4 {
+		result.Suggestions = append(result.Suggestions, "Reversed words aren't much harder to guess")
+	}
+	if len(m.sub) > 0 {
+		result.Suggestions = append(result.Suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+	}
+
+	return result
+}
@@ -0,0 +1,302 @@
+package strength
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Guesses of patterns, a part of password is guessed 10 times at least as it's unknown where it starts and ends
+const (
+	bruteforceCardinality           = 10
+	minSubmatchGuessesSingleChar    = 10
+	minSubmatchGuessesMultiChar     = 50
+	minGuessesBeforeGrowingSequence = 10000
+	minYearSpace                    = 20
+)
+
+var (
+	startUpper = regexp.MustCompile(`^[A-Z][^A-Z]+$`)
+	endUpper   = regexp.MustCompile(`^[^A-Z]+[A-Z]$`)
+	allUpper   = regexp.MustCompile(`^[^a-z]+$`)
+	allLower   = regexp.MustCompile(`^[^A-Z]+$`)
+)
+
+// referenceYear Year dates and years are guessed around
+func referenceYear() int {
+	return time.Now().Year()
+}
+
+// guessedSequence Matches covering the password, guessed one after another
+type guessedSequence struct {
+	matches []*match
+	guesses float64
+}
+
+// mostGuessableSequence Sequence of non-overlapping matches and bruteforce parts between them covering password,
+// which takes the fewest guesses. Guesses of a sequence are the product of guesses of its matches, times the ways
+// the same count of matches is ordered, plus guesses of all shorter sequences.
+func mostGuessableSequence(password []rune, matches []*match) guessedSequence {
+	n := len(password)
+	if n == 0 {
+		return guessedSequence{guesses: 1}
+	}
+
+	byEnd := make([][]*match, n)
+	for _, m := range matches {
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+
+	//Best sequences ending at k by count of matches l: last match, product of guesses and overall guesses
+	type optimum struct {
+		m  *match
+		pi float64
+		g  float64
+	}
+	optimal := make([]map[int]optimum, n)
+	for k := range optimal {
+		optimal[k] = map[int]optimum{}
+	}
+
+	update := func(m *match, l int) {
+		k := m.j
+		pi := estimateGuesses(m, n)
+		if l > 1 {
+			pi *= optimal[m.i-1][l-1].pi
+		}
+		g := factorial(l)*pi + math.Pow(minGuessesBeforeGrowingSequence, float64(l-1))
+		for competingL, competing := range optimal[k] {
+			if competingL <= l && competing.g <= g {
+				return
+			}
+		}
+		optimal[k][l] = optimum{m: m, pi: pi, g: g}
+	}
+	bruteforce := func(i int, j int) *match {
+		return &match{pattern: "bruteforce", i: i, j: j, token: string(password[i : j+1])}
+	}
+
+	for k := 0; k < n; k++ {
+		for _, m := range byEnd[k] {
+			if m.i == 0 {
+				update(m, 1)
+				continue
+			}
+			for l := range optimal[m.i-1] {
+				update(m, l+1)
+			}
+		}
+
+		//Bruteforce parts never follow each other, they'd be one longer part
+		update(bruteforce(0, k), 1)
+		for i := 1; i <= k; i++ {
+			for l, last := range optimal[i-1] {
+				if last.m.pattern != "bruteforce" {
+					update(bruteforce(i, k), l+1)
+				}
+			}
+		}
+	}
+
+	l, g := 0, math.Inf(1)
+	for candidateL, candidate := range optimal[n-1] {
+		if candidate.g < g || (candidate.g == g && candidateL < l) {
+			l, g = candidateL, candidate.g
+		}
+	}
+	sequence := make([]*match, l)
+	for k := n - 1; k >= 0; l-- {
+		m := optimal[k][l].m
+		sequence[l-1] = m
+		k = m.i - 1
+	}
+
+	return guessedSequence{matches: sequence, guesses: g}
+}
+
+// estimateGuesses Guesses of match, counted once. Parts of password are guessed a minimum of times.
+func estimateGuesses(m *match, passwordLength int) float64 {
+	if m.guesses != 0 {
+		return m.guesses
+	}
+
+	length := m.j - m.i + 1
+	minGuesses := 1.0
+	if length < passwordLength {
+		minGuesses = minSubmatchGuessesMultiChar
+		if length == 1 {
+			minGuesses = minSubmatchGuessesSingleChar
+		}
+	}
+
+	var guesses float64
+	switch m.pattern {
+	case "bruteforce":
+		guesses = math.Min(math.Pow(bruteforceCardinality, float64(length)), math.MaxFloat64)
+		minGuesses++
+	case "dictionary":
+		guesses = float64(m.rank) * uppercaseVariations(m.token) * l33tVariations(m)
+		if m.reversed {
+			guesses *= 2
+		}
+	case "spatial":
+		guesses = spatialGuesses(m)
+	case "repeat":
+		guesses = m.baseGuesses * float64(m.repeats)
+	case "sequence":
+		guesses = sequenceGuesses(m)
+	case "year":
+		guesses = float64(yearSpace(m.year))
+	case "date":
+		guesses = float64(yearSpace(m.year)) * 365
+		if m.separator {
+			guesses *= 4
+		}
+	}
+
+	m.guesses = math.Max(guesses, minGuesses)
+
+	return m.guesses
+}
+
+func yearSpace(year int) int {
+	space := abs(year - referenceYear())
+	if space < minYearSpace {
+		return minYearSpace
+	}
+
+	return space
+}
+
+// uppercaseVariations Ways to capitalize a word like token. Capitalizing the first or last letter or all of them
+// is common, other ways are counted by the uppercase and lowercase letters.
+func uppercaseVariations(token string) float64 {
+	if allLower.MatchString(token) {
+		return 1
+	}
+	if startUpper.MatchString(token) || endUpper.MatchString(token) || allUpper.MatchString(token) {
+		return 2
+	}
+
+	upper, lower := 0, 0
+	for _, c := range token {
+		if unicode.IsUpper(c) {
+			upper++
+		} else if unicode.IsLower(c) {
+			lower++
+		}
+	}
+
+	return variations(upper, lower)
+}
+
+// l33tVariations Ways to substitute letters of word like match, every l33t character may replace some of its letters
+func l33tVariations(m *match) float64 {
+	if len(m.sub) == 0 {
+		return 1
+	}
+
+	result := 1.0
+	token := strings.ToLower(m.token)
+	for c, letter := range m.sub {
+		subbed, unsubbed := strings.Count(token, string(c)), strings.Count(token, string(letter))
+		if subbed == 0 || unsubbed == 0 {
+			//Either all of the letters are substituted or none, the other is guessed too
+			result *= 2
+		} else {
+			result *= variations(subbed, unsubbed)
+		}
+	}
+
+	return result
+}
+
+// variations Ways to choose up to the smaller of a and b characters among a+b characters
+func variations(a int, b int) float64 {
+	smaller := a
+	if b < a {
+		smaller = b
+	}
+
+	result := 0.0
+	for i := 1; i <= smaller; i++ {
+		result += binomial(a+b, i)
+	}
+
+	return result
+}
+
+// spatialGuesses Keyboard patterns of the length and turns of match, from every starting key, times ways to shift keys
+func spatialGuesses(m *match) float64 {
+	var g graph
+	for _, candidate := range graphs {
+		if candidate.name == m.graph {
+			g = candidate
+		}
+	}
+
+	length := m.j - m.i + 1
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		possibleTurns := m.turns
+		if i-1 < possibleTurns {
+			possibleTurns = i - 1
+		}
+		for j := 1; j <= possibleTurns; j++ {
+			guesses += binomial(i-1, j-1) * g.starts * math.Pow(g.degree, float64(j))
+		}
+	}
+
+	if m.shifted > 0 {
+		unshifted := length - m.shifted
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			guesses *= variations(m.shifted, unshifted)
+		}
+	}
+
+	return guesses
+}
+
+// sequenceGuesses Sequences starting from obvious characters like a or 1 are guessed first, descending ones later
+func sequenceGuesses(m *match) float64 {
+	first := []rune(m.token)[0]
+	var base float64
+	switch {
+	case strings.ContainsRune("aAzZ019", first):
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+	if !m.ascending {
+		base *= 2
+	}
+
+	return base * float64(m.j-m.i+1)
+}
+
+func binomial(n int, k int) float64 {
+	if k > n {
+		return 0
+	}
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+
+	return result
+}
+
+func factorial(n int) float64 {
+	result := 1.0
+	for i := 2; i <= n; i++ {
+		result *= float64(i)
+	}
+
+	return result
+}
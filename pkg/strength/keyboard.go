@@ -0,0 +1,149 @@
+package strength
+
+import (
+	"strings"
+)
+
+// Keyboard layouts, keys are tokens of their unshifted and shifted characters
+const (
+	qwertyLayout = `
+` + "`~" + ` 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+
+    qQ wW eE rR tT yY uU iI oO pP [{ ]} \|
+     aA sS dD fF gG hH jJ kK lL ;: '"
+      zZ xX cC vV bB nN mM ,< .> /?
+`
+	dvorakLayout = `
+` + "`~" + ` 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) [{ ]}
+    '" ,< .> pP yY fF gG cC rR lL /? =+ \|
+     aA oO eE uU iI dD hH tT nN sS -_
+      ;: qQ jJ kK xX bB mM wW vV zZ
+`
+	keypadLayout = `
+  / * -
+7 8 9 +
+4 5 6
+1 2 3
+  0 .
+`
+)
+
+// graph Adjacent keys of every character, in fixed directions of the layout.
+// A missing neighbour is an empty string, so the direction of a turn is the index of the neighbour.
+type graph struct {
+	name      string
+	neighbors map[rune][]string
+	//shifted are second characters of keys, typed along with shift
+	shifted map[rune]bool
+	//starts are characters a pattern can start from, degree is the average count of neighbours
+	starts float64
+	degree float64
+}
+
+var graphs = []graph{
+	newGraph("qwerty", qwertyLayout, true),
+	newGraph("dvorak", dvorakLayout, true),
+	newGraph("keypad", keypadLayout, false),
+}
+
+// newGraph Graph of layout. Rows of keyboards are slanted, a key touches two keys of the row above and below it,
+// keys of keypads are aligned and touch eight keys.
+func newGraph(name string, layout string, slanted bool) graph {
+	type position struct{ x, y int }
+	positions := map[position]string{}
+	for y, line := range strings.Split(layout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		unit := len(fields[0]) + 1
+		slant := 0
+		if slanted {
+			slant = y - 1
+		}
+		offset := 0
+		for _, token := range fields {
+			index := strings.Index(line[offset:], token) + offset
+			offset = index + len(token)
+			positions[position{(index - slant) / unit, y}] = token
+		}
+	}
+
+	var directions []position
+	if slanted {
+		directions = []position{{-1, 0}, {0, -1}, {1, -1}, {1, 0}, {0, 1}, {-1, 1}}
+	} else {
+		directions = []position{{-1, 0}, {-1, -1}, {0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}}
+	}
+
+	g := graph{name: name, neighbors: map[rune][]string{}, shifted: map[rune]bool{}}
+	total := 0
+	for p, token := range positions {
+		var neighbors []string
+		for _, d := range directions {
+			neighbor := positions[position{p.x + d.x, p.y + d.y}]
+			if neighbor != "" {
+				total++
+			}
+			neighbors = append(neighbors, neighbor)
+		}
+		for i, c := range token {
+			g.neighbors[c] = neighbors
+			g.shifted[c] = i > 0
+		}
+	}
+	g.starts = float64(len(g.neighbors))
+	g.degree = float64(total) / float64(len(positions))
+
+	return g
+}
+
+// matchSpatial Runs of 3 keys at least, each adjacent to the one before it
+func matchSpatial(password []rune) []match {
+	var matches []match
+	for _, g := range graphs {
+		for i := 0; i < len(password)-1; {
+			j := i + 1
+			lastDirection := -1
+			turns := 0
+			shifted := 0
+			if g.shifted[password[i]] {
+				shifted++
+			}
+
+			for ; j < len(password); j++ {
+				direction, shift := adjacent(g, password[j-1], password[j])
+				if direction < 0 {
+					break
+				}
+				if shift {
+					shifted++
+				}
+				if direction != lastDirection {
+					turns++
+					lastDirection = direction
+				}
+			}
+
+			if j-i > 2 {
+				matches = append(matches, match{
+					pattern: "spatial", i: i, j: j - 1, token: string(password[i:j]),
+					graph: g.name, turns: turns, shifted: shifted,
+				})
+			}
+			i = j
+		}
+	}
+
+	return matches
+}
+
+// adjacent Direction of key of next from key of previous, -1 if they aren't adjacent, and whether next is shifted
+func adjacent(g graph, previous rune, next rune) (int, bool) {
+	for direction, neighbor := range g.neighbors[previous] {
+		if i := strings.IndexRune(neighbor, next); i >= 0 {
+			return direction, i > 0
+		}
+	}
+
+	return -1, false
+}
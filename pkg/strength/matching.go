@@ -0,0 +1,520 @@
+package strength
+
+import (
+	"bufio"
+	"embed"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed dictionaries
+var dictionaryFiles embed.FS
+
+// match Part of password from index i to j of runes, guessed as a pattern.
+// Fields following pattern are set by the pattern they belong to.
+type match struct {
+	pattern string
+	i, j    int
+	token   string
+	guesses float64
+
+	//dictionary is the name of dictionary word of rank is found in, sub maps l33t characters to letters they replace
+	dictionary string
+	word       string
+	rank       int
+	reversed   bool
+	sub        map[rune]rune
+
+	graph   string
+	turns   int
+	shifted int
+
+	base        string
+	baseGuesses float64
+	repeats     int
+
+	ascending bool
+
+	year      int
+	separator bool
+}
+
+var (
+	rankedDictionaries map[string]map[string]int
+	loadDictionaries   sync.Once
+)
+
+// dictionaries Rank of words by dictionary, the most used word of a dictionary is rank 1.
+// Dictionaries are read once, when a password is estimated at first.
+func dictionaries() map[string]map[string]int {
+	loadDictionaries.Do(func() {
+		rankedDictionaries = map[string]map[string]int{}
+		files, err := dictionaryFiles.ReadDir("dictionaries")
+		if err != nil {
+			panic(err)
+		}
+		for _, file := range files {
+			content, err := dictionaryFiles.ReadFile(path.Join("dictionaries", file.Name()))
+			if err != nil {
+				panic(err)
+			}
+
+			ranked := map[string]int{}
+			scanner := bufio.NewScanner(strings.NewReader(string(content)))
+			for scanner.Scan() {
+				if word := scanner.Text(); word != "" {
+					ranked[word] = len(ranked) + 1
+				}
+			}
+			rankedDictionaries[strings.TrimSuffix(file.Name(), ".txt")] = ranked
+		}
+	})
+
+	return rankedDictionaries
+}
+
+// userDictionary Words of user inputs, such as username and site, ranked in order they're given.
+// Inputs are ranked as a whole and by their words.
+func userDictionary(userInputs []string) map[string]int {
+	ranked := map[string]int{}
+	add := func(word string) {
+		if _, ok := ranked[word]; word != "" && !ok {
+			ranked[word] = len(ranked) + 1
+		}
+	}
+	for _, input := range userInputs {
+		input = strings.ToLower(input)
+		add(input)
+		for _, word := range strings.FieldsFunc(input, func(c rune) bool {
+			return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+		}) {
+			add(word)
+		}
+	}
+
+	return ranked
+}
+
+// omnimatch Every match of every pattern in password
+func omnimatch(password []rune, ranked map[string]map[string]int) []*match {
+	var matches []match
+	matches = append(matches, matchDictionary(password, ranked)...)
+	matches = append(matches, matchReversed(password, ranked)...)
+	matches = append(matches, matchL33t(password, ranked)...)
+	matches = append(matches, matchSpatial(password)...)
+	matches = append(matches, matchRepeat(password, ranked)...)
+	matches = append(matches, matchSequence(password)...)
+	matches = append(matches, matchYear(password)...)
+	matches = append(matches, matchDate(password)...)
+
+	result := make([]*match, len(matches))
+	for i := range matches {
+		result[i] = &matches[i]
+	}
+	sort.SliceStable(result, func(a, b int) bool {
+		if result[a].i != result[b].i {
+			return result[a].i < result[b].i
+		}
+		return result[a].j < result[b].j
+	})
+
+	return result
+}
+
+// lower Lowercase of password rune by rune, so indexes are kept
+func lower(password []rune) []rune {
+	lowered := make([]rune, len(password))
+	for i, c := range password {
+		lowered[i] = unicode.ToLower(c)
+	}
+
+	return lowered
+}
+
+// matchDictionary Every part of password which is a word of a dictionary, case-insensitively
+func matchDictionary(password []rune, ranked map[string]map[string]int) []match {
+	var matches []match
+	lowered := lower(password)
+	for i := range lowered {
+		for j := i; j < len(lowered); j++ {
+			word := string(lowered[i : j+1])
+			for name, dictionary := range ranked {
+				if rank, ok := dictionary[word]; ok {
+					matches = append(matches, match{
+						pattern: "dictionary", i: i, j: j, token: string(password[i : j+1]),
+						dictionary: name, word: word, rank: rank,
+					})
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// matchReversed Words of dictionaries written backwards
+func matchReversed(password []rune, ranked map[string]map[string]int) []match {
+	n := len(password)
+	reversed := make([]rune, n)
+	for i, c := range password {
+		reversed[n-1-i] = c
+	}
+
+	var matches []match
+	for _, m := range matchDictionary(reversed, ranked) {
+		//Single characters read the same backwards, they're matched forwards already
+		if m.i == m.j {
+			continue
+		}
+		m.i, m.j = n-1-m.j, n-1-m.i
+		m.token = string(password[m.i : m.j+1])
+		m.reversed = true
+		matches = append(matches, m)
+	}
+
+	return matches
+}
+
+// l33tTable Characters substituting letters in l33t speak
+var l33tTable = map[rune]string{
+	'a': "4@", 'b': "8", 'c': "({[<", 'e': "3", 'g': "69", 'i': "1!|",
+	'l': "1|7", 'o': "0", 's': "$5", 't': "+7", 'x': "%", 'z': "2",
+}
+
+// matchL33t Words of dictionaries with letters substituted by l33t characters, such as p4ssw0rd.
+// Every way to read the l33t characters of password is tried, 1 is either i or l for example.
+func matchL33t(password []rune, ranked map[string]map[string]int) []match {
+	letters := map[rune][]rune{}
+	for letter, characters := range l33tTable {
+		for _, c := range characters {
+			if strings.ContainsRune(string(password), c) {
+				letters[c] = append(letters[c], letter)
+			}
+		}
+	}
+	if len(letters) == 0 {
+		return nil
+	}
+	var characters []rune
+	for c := range letters {
+		characters = append(characters, c)
+		sort.Slice(letters[c], func(a, b int) bool { return letters[c][a] < letters[c][b] })
+	}
+	sort.Slice(characters, func(a, b int) bool { return characters[a] < characters[b] })
+
+	//Every combination of letters, one letter for every l33t character
+	subs := []map[rune]rune{{}}
+	for _, c := range characters {
+		var expanded []map[rune]rune
+		for _, sub := range subs {
+			for _, letter := range letters[c] {
+				next := map[rune]rune{c: letter}
+				for k, v := range sub {
+					next[k] = v
+				}
+				expanded = append(expanded, next)
+			}
+		}
+		subs = expanded
+	}
+
+	var matches []match
+	seen := map[string]bool{}
+	for _, sub := range subs {
+		translated := make([]rune, len(password))
+		for i, c := range password {
+			translated[i] = c
+			if letter, ok := sub[c]; ok {
+				translated[i] = letter
+			}
+		}
+
+		for _, m := range matchDictionary(translated, ranked) {
+			m.token = string(password[m.i : m.j+1])
+			//Single l33t characters are rather digits and symbols than words
+			if string(lower([]rune(m.token))) == m.word || m.i == m.j {
+				continue
+			}
+			m.sub = map[rune]rune{}
+			for c, letter := range sub {
+				if strings.ContainsRune(m.token, c) {
+					m.sub[c] = letter
+				}
+			}
+
+			key := m.dictionary + "\x00" + m.word + "\x00" + strconv.Itoa(m.i) + "\x00" + strconv.Itoa(m.j)
+			if !seen[key] {
+				seen[key] = true
+				matches = append(matches, m)
+			}
+		}
+	}
+
+	return matches
+}
+
+// matchRepeat Repeats of a base, such as aaa or abcabc. The longest repeat from a position is taken,
+// its base is the shortest one repeating to that length.
+func matchRepeat(password []rune, ranked map[string]map[string]int) []match {
+	var matches []match
+	n := len(password)
+	for i := 0; i < n-1; {
+		length, baseLength := 0, 0
+		for base := 1; base <= (n-i)/2; base++ {
+			repeats := 1
+			for i+(repeats+1)*base <= n && string(password[i+repeats*base:i+(repeats+1)*base]) == string(password[i:i+base]) {
+				repeats++
+			}
+			if repeats > 1 && repeats*base > length {
+				length, baseLength = repeats*base, base
+			}
+		}
+		if length == 0 {
+			i++
+			continue
+		}
+
+		base := password[i : i+baseLength]
+		matches = append(matches, match{
+			pattern: "repeat", i: i, j: i + length - 1, token: string(password[i : i+length]),
+			base: string(base), baseGuesses: mostGuessableSequence(base, omnimatch(base, ranked)).guesses,
+			repeats: length / baseLength,
+		})
+		i += length
+	}
+
+	return matches
+}
+
+// maxSequenceDelta Longest step between characters of a sequence, such as 2 of "aceg"
+const maxSequenceDelta = 5
+
+// matchSequence Runs of characters of the same step, such as abcd, 9753 or ZYX
+func matchSequence(password []rune) []match {
+	var matches []match
+	if len(password) < 2 {
+		return matches
+	}
+
+	add := func(i int, j int, delta int) {
+		if delta < 0 {
+			delta = -delta
+		}
+		if (j-i > 1 || delta == 1) && delta > 0 && delta <= maxSequenceDelta {
+			matches = append(matches, match{
+				pattern: "sequence", i: i, j: j, token: string(password[i : j+1]),
+				ascending: password[j] > password[i],
+			})
+		}
+	}
+
+	i := 0
+	lastDelta := int(password[1]) - int(password[0])
+	for k := 2; k < len(password); k++ {
+		delta := int(password[k]) - int(password[k-1])
+		if delta == lastDelta {
+			continue
+		}
+		add(i, k-1, lastDelta)
+		i = k - 1
+		lastDelta = delta
+	}
+	add(i, len(password)-1, lastDelta)
+
+	return matches
+}
+
+// digits Number of runes of password from i to j, false if one of them isn't a digit
+func digits(password []rune, i int, j int) (int, bool) {
+	if i > j {
+		return 0, false
+	}
+	number := 0
+	for _, c := range password[i : j+1] {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		number = number*10 + int(c-'0')
+	}
+
+	return number, true
+}
+
+// matchYear Years of the last and this century
+func matchYear(password []rune) []match {
+	var matches []match
+	for i := 0; i+4 <= len(password); i++ {
+		year, ok := digits(password, i, i+3)
+		if ok && year >= 1900 && year <= 2099 {
+			matches = append(matches, match{pattern: "year", i: i, j: i + 3, token: string(password[i : i+4]), year: year})
+		}
+	}
+
+	return matches
+}
+
+// Years of dates, two digit years are of 1951 to 2050
+const (
+	dateMinYear = 1000
+	dateMaxYear = 2050
+)
+
+// dateSplits Where digits of dates without separators are split into day, month and year, by length of date
+var dateSplits = map[int][][2]int{
+	4: {{1, 2}, {2, 3}},
+	5: {{1, 3}, {2, 3}},
+	6: {{1, 2}, {2, 4}, {4, 5}},
+	7: {{1, 3}, {2, 3}, {4, 5}, {4, 6}},
+	8: {{2, 4}, {4, 6}},
+}
+
+// dateSeparators Characters between numbers of dates
+const dateSeparators = " /\\_.-"
+
+// matchDate Dates of day, month and year in any order, of 4 to 8 digits or with separators like 13.2.1990.
+// Dates inside of longer dates are left out.
+func matchDate(password []rune) []match {
+	var matches []match
+	reference := referenceYear()
+
+	for i := 0; i+4 <= len(password); i++ {
+		for j := i + 3; j <= i+7 && j < len(password); j++ {
+			if _, ok := digits(password, i, j); !ok {
+				continue
+			}
+
+			best, found := 0, false
+			for _, split := range dateSplits[j-i+1] {
+				a, _ := digits(password, i, i+split[0]-1)
+				b, _ := digits(password, i+split[0], i+split[1]-1)
+				c, _ := digits(password, i+split[1], j)
+				if year, ok := dateYear(a, b, c); ok && (!found || abs(year-reference) < abs(best-reference)) {
+					best, found = year, true
+				}
+			}
+			if found {
+				matches = append(matches, match{pattern: "date", i: i, j: j, token: string(password[i : j+1]), year: best})
+			}
+		}
+	}
+
+	for i := 0; i+6 <= len(password); i++ {
+		for j := i + 5; j <= i+9 && j < len(password); j++ {
+			if a, b, c, ok := separatedDate(password[i : j+1]); ok {
+				if year, ok := dateYear(a, b, c); ok {
+					matches = append(matches, match{pattern: "date", i: i, j: j, token: string(password[i : j+1]), year: year, separator: true})
+				}
+			}
+		}
+	}
+
+	var result []match
+	for _, m := range matches {
+		inside := false
+		for _, other := range matches {
+			if (other.i != m.i || other.j != m.j) && other.i <= m.i && other.j >= m.j {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			result = append(result, m)
+		}
+	}
+
+	return result
+}
+
+// separatedDate Numbers of token of 1 to 4, 1 to 2 and 1 to 4 digits between the same separator
+func separatedDate(token []rune) (int, int, int, bool) {
+	first := -1
+	for k, c := range token {
+		if strings.ContainsRune(dateSeparators, c) {
+			first = k
+			break
+		}
+	}
+	if first < 1 || first > 4 {
+		return 0, 0, 0, false
+	}
+	separator := token[first]
+	second := -1
+	for k := first + 1; k < len(token); k++ {
+		if token[k] == separator {
+			second = k
+			break
+		}
+	}
+	if second < first+2 || second > first+3 || second == len(token)-1 || len(token)-1-second > 4 {
+		return 0, 0, 0, false
+	}
+
+	a, okA := digits(token, 0, first-1)
+	b, okB := digits(token, first+1, second-1)
+	c, okC := digits(token, second+1, len(token)-1)
+
+	return a, b, c, okA && okB && okC
+}
+
+// dateYear Year of numbers read as a date, with day and month in either order and the year first or last
+func dateYear(a int, b int, c int) (int, bool) {
+	if b > 31 || b <= 0 {
+		return 0, false
+	}
+
+	over12, over31, under1 := 0, 0, 0
+	for _, number := range []int{a, b, c} {
+		if (number > 99 && number < dateMinYear) || number > dateMaxYear {
+			return 0, false
+		}
+		if number > 31 {
+			over31++
+		}
+		if number > 12 {
+			over12++
+		}
+		if number <= 0 {
+			under1++
+		}
+	}
+	if over31 >= 2 || over12 == 3 || under1 >= 2 {
+		return 0, false
+	}
+
+	splits := [][3]int{{c, a, b}, {a, b, c}}
+	for _, split := range splits {
+		if split[0] >= dateMinYear && split[0] <= dateMaxYear {
+			return split[0], dayMonth(split[1], split[2])
+		}
+	}
+	for _, split := range splits {
+		if dayMonth(split[1], split[2]) {
+			year := split[0]
+			if year <= 50 {
+				year += 2000
+			} else if year <= 99 {
+				year += 1900
+			}
+			return year, true
+		}
+	}
+
+	return 0, false
+}
+
+// dayMonth Whether numbers are day and month in either order
+func dayMonth(a int, b int) bool {
+	return (a >= 1 && a <= 31 && b >= 1 && b <= 12) || (b >= 1 && b <= 31 && a >= 1 && a <= 12)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,208 @@
+// Package strength estimates guesses needed to crack a password, the way zxcvbn does. Password is covered by
+// dictionary words, keyboard patterns, repeats, sequences, years and dates, or guessed by brute force between them,
+// and the sequence of them guessed the soonest is what the estimate is of.
+package strength
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxLength Runes of password matched against patterns, so long inputs can't take the estimator long.
+// The rest is guessed by brute force, except where it goes on repeating the end of the matched runes.
+const maxLength = 100
+
+// maxGuesses Guesses of passwords longer than anything guessable, so they stay a finite number
+const maxGuesses = 1e300
+
+// Weak Scores of passwords to be warned about
+const Weak = 2
+
+// Result Estimate of a password. Score is 0 of too guessable passwords to 4 of very unguessable ones.
+type Result struct {
+	Guesses      float64     `json:"guesses"`
+	GuessesLog10 float64     `json:"guessesLog10"`
+	Score        int         `json:"score"`
+	CrackTimes   []CrackTime `json:"crackTimes"`
+	Feedback     Feedback    `json:"feedback"`
+	Sequence     []Pattern   `json:"sequence"`
+}
+
+// CrackTime Time to guess a password by attackers of a speed
+type CrackTime struct {
+	Scenario         string  `json:"scenario"`
+	GuessesPerSecond float64 `json:"guessesPerSecond"`
+	Seconds          float64 `json:"seconds"`
+	Display          string  `json:"display"`
+}
+
+// Pattern Pattern covering runes of password from Start to End, parts of passwords aren't included
+type Pattern struct {
+	Pattern    string  `json:"pattern"`
+	Start      int     `json:"start"`
+	End        int     `json:"end"`
+	Guesses    float64 `json:"guesses"`
+	Dictionary string  `json:"dictionary,omitempty"`
+}
+
+// scenarios Attackers guessing online against a rate limit or without, and offline against slow or fast hashes
+var scenarios = []CrackTime{
+	{Scenario: "online, throttled", GuessesPerSecond: 100.0 / 3600},
+	{Scenario: "online, unthrottled", GuessesPerSecond: 10},
+	{Scenario: "offline, slow hashing", GuessesPerSecond: 1e4},
+	{Scenario: "offline, fast hashing", GuessesPerSecond: 1e10},
+}
+
+// Estimate Estimate guesses needed to crack password. User inputs, such as username and site, are guessed first
+// as they're known to attackers.
+func Estimate(password string, userInputs ...string) Result {
+	ranked := map[string]map[string]int{"user_inputs": userDictionary(userInputs)}
+	for name, dictionary := range dictionaries() {
+		ranked[name] = dictionary
+	}
+
+	//Runes going on repeating the end of matched ones only add to the count of that repeat
+	runes := []rune(password)
+	length := len(runes)
+	var continued *match
+	rest := 0
+	if len(runes) > maxLength {
+		var repeated int
+		continued, repeated = continuedRepeat(runes, maxLength, ranked)
+		runes, rest = runes[:maxLength], len(runes)-maxLength-repeated
+	}
+	//Runes before a continued repeat are guessed on their own, as matches of the repeated runes can't count repeats past them
+	var sequence guessedSequence
+	if continued != nil {
+		before := runes[:continued.i]
+		sequence = mostGuessableSequence(before, omnimatch(before, ranked))
+		continued.guesses = estimateGuesses(continued, length)
+		sequence.guesses *= continued.guesses
+		sequence.matches = append(sequence.matches, continued)
+	} else {
+		sequence = mostGuessableSequence(runes, omnimatch(runes, ranked))
+	}
+
+	guesses := math.Min(sequence.guesses*math.Pow(bruteforceCardinality, float64(rest)), maxGuesses)
+	result := Result{Guesses: guesses, GuessesLog10: math.Log10(guesses), Score: score(guesses), Sequence: []Pattern{}}
+	for _, scenario := range scenarios {
+		scenario.Seconds = guesses / scenario.GuessesPerSecond
+		scenario.Display = displayTime(scenario.Seconds)
+		result.CrackTimes = append(result.CrackTimes, scenario)
+	}
+	for _, m := range sequence.matches {
+		result.Sequence = append(result.Sequence, Pattern{Pattern: m.pattern, Start: m.i, End: m.j, Guesses: m.guesses, Dictionary: m.dictionary})
+	}
+	result.Feedback = feedback(result.Score, sequence.matches)
+
+	return result
+}
+
+// continuedRepeat Repeat match of the base the first matched runes of password end with, counting repeats past them,
+// and how many runes past them it covers. The match spans every rune it covers, so nothing past them is guessed again.
+// The longest run is taken, nil is returned if no whole repeat goes on past them.
+func continuedRepeat(password []rune, matched int, ranked map[string]map[string]int) (*match, int) {
+	repeated, period := 0, 0
+	for base := 1; base <= matched/2; base++ {
+		if string(password[matched-base:matched]) != string(password[matched-2*base:matched-base]) {
+			continue
+		}
+
+		n := matched
+		for n < len(password) && password[n] == password[n-base] {
+			n++
+		}
+		if n-matched > repeated {
+			repeated, period = n-matched, base
+		}
+	}
+	if repeated == 0 {
+		return nil, 0
+	}
+
+	//The repeat starts where matched runes stop repeating the base
+	start := matched - 2*period
+	for start > 0 && password[start-1] == password[start-1+period] {
+		start--
+	}
+	base := password[start : start+period]
+	//The match ends with the last whole repeat, runes of a partial one are left to be guessed on their own
+	repeats := (matched + repeated - start) / period
+	end := start + repeats*period
+	if end <= matched {
+		return nil, 0
+	}
+
+	return &match{
+		pattern: "repeat", i: start, j: end - 1, token: string(password[start:end]),
+		base: string(base), baseGuesses: mostGuessableSequence(base, omnimatch(base, ranked)).guesses,
+		repeats: repeats,
+	}, end - matched
+}
+
+// Weak Whether password of result is guessable enough to be warned about
+func (result Result) Weak() bool {
+	return result.Score <= Weak
+}
+
+// Summary Score and time to crack offline against a slow hash, with the warning if there's one
+func (result Result) Summary() string {
+	summary := fmt.Sprintf("score %d/4, cracked in %s %s", result.Score, result.CrackTimes[2].Display, result.CrackTimes[2].Scenario)
+	if result.Feedback.Warning != "" {
+		summary += ", " + result.Feedback.Warning
+	}
+
+	return summary
+}
+
+// score Score of guesses, a little more than a power of ten is still of the lower score
+func score(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	}
+
+	return 4
+}
+
+// displayTime Seconds in the largest unit of them, such as 3 hours
+func displayTime(seconds float64) string {
+	const (
+		minute  = 60.0
+		hour    = minute * 60
+		day     = hour * 24
+		month   = day * 31
+		year    = month * 12
+		century = year * 100
+	)
+
+	units := []struct {
+		seconds float64
+		name    string
+	}{{1, "second"}, {minute, "minute"}, {hour, "hour"}, {day, "day"}, {month, "month"}, {year, "year"}}
+	switch {
+	case seconds < 1:
+		return "less than a second"
+	case seconds >= century:
+		return "centuries"
+	}
+
+	for i := len(units) - 1; i >= 0; i-- {
+		if seconds >= units[i].seconds {
+			count := math.Round(seconds / units[i].seconds)
+			if count == 1 {
+				return fmt.Sprintf("1 %s", units[i].name)
+			}
+			return fmt.Sprintf("%.0f %ss", count, units[i].name)
+		}
+	}
+
+	return "less than a second"
+}
@@ -0,0 +1,72 @@
+package strength
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		password string
+		score    int
+		patterns string
+		warning  string
+	}{
+		{"password", 0, "dictionary", "top-10 common password"},
+		{"P@ssw0rd", 0, "dictionary", "similar to a commonly used password"},
+		{"drowssap", 0, "dictionary", "similar to a commonly used password"},
+		{"dfghjk", 1, "spatial", "Straight rows of keys"},
+		{"zxcftyhn", 2, "spatial", "Short keyboard patterns"},
+		{"aaaaaaaa", 0, "repeat", `Repeats like "aaa"`},
+		{"abcdefgh", 0, "sequence", "Sequences"},
+		{"13.02.1990", 1, "date", "Dates"},
+		{"1987", 0, "year", "Recent years"},
+		{"informer", 0, "dictionary", "your username or the site"},
+		{"correcthorsebatterystaple", 4, "dictionary dictionary dictionary dictionary", ""},
+		{"g-tqJ*2Dq)Z7", 4, "bruteforce", ""},
+	}
+
+	for _, test := range tests {
+		result := Estimate(test.password, "junjie", "informer")
+		var patterns []string
+		for _, pattern := range result.Sequence {
+			patterns = append(patterns, pattern.Pattern)
+		}
+		if result.Score != test.score || strings.Join(patterns, " ") != test.patterns || !strings.Contains(result.Feedback.Warning, test.warning) {
+			t.Errorf("%q: score %d, patterns %v, warning %q", test.password, result.Score, patterns, result.Feedback.Warning)
+		}
+		if len(result.CrackTimes) != 4 || result.CrackTimes[0].Seconds <= result.CrackTimes[3].Seconds {
+			t.Errorf("%q: crack times %v", test.password, result.CrackTimes)
+		}
+	}
+}
+
+func TestLongPassword(t *testing.T) {
+	//Repeats past the matched runes are guessed by their count, not rune by rune
+	for _, password := range []string{strings.Repeat("password", 1000), strings.Repeat("password", 1000) + "1", strings.Repeat("ab", 5000)} {
+		if result := Estimate(password); result.Score > 1 {
+			t.Errorf("long repeat %.12q: score %d, guesses %v", password, result.Score, result.Guesses)
+		}
+	}
+
+	//The repeat spans the runes it covers past the matched ones, no other pattern overlaps it
+	sequence := Estimate(strings.Repeat("password", 1000) + "pass").Sequence
+	repeat := sequence[len(sequence)-1]
+	if repeat.Pattern != "repeat" || repeat.End != 7999 || len(sequence) > 1 && sequence[len(sequence)-2].End >= repeat.Start {
+		t.Errorf("long repeat: sequence %+v", sequence)
+	}
+
+	result := Estimate(strings.Repeat("password", 20) + strings.Repeat("x7#Kq9!mZ2", 100))
+	if result.Score != 4 || result.Guesses > maxGuesses {
+		t.Errorf("long password: score %d, guesses %v", result.Score, result.Guesses)
+	}
+}
+
+func TestDisplayTime(t *testing.T) {
+	times := map[float64]string{0.5: "less than a second", 1: "1 second", 150: "3 minutes", 86400 * 2: "2 days", 1e12: "centuries"}
+	for seconds, display := range times {
+		if displayTime(seconds) != display {
+			t.Errorf("%v seconds are displayed as %q", seconds, displayTime(seconds))
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package webauthn
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+const (
+	// challengeTimeout Time a ceremony has to be completed in, also given to browsers as timeout
+	challengeTimeout = 5 * time.Minute
+	// challengeRandomSize Random bytes of a challenge, which follow its expiry and come before its MAC
+	challengeRandomSize = 16
+	// challengeMACSize Bytes of HMAC-SHA256 kept in a challenge
+	challengeMACSize = 16
+)
+
+// Challenges Challenges issued to browsers, each can be used once before it expires.
+// A challenge carries its expiry and a MAC of it, so nothing is kept for issued ones: login options need no login,
+// so anyone can ask for them, and a flood of them can't push out the challenges of others.
+// Only used challenges are kept until they expire, and only verified ceremonies use one.
+type Challenges struct {
+	mutex sync.Mutex
+	key   []byte
+	used  map[string]time.Time
+}
+
+// New Issue a random challenge, encoded in base64url like the rest of options
+func (challenges *Challenges) New() (string, error) {
+	raw := make([]byte, 8+challengeRandomSize, 8+challengeRandomSize+challengeMACSize)
+	binary.BigEndian.PutUint64(raw, uint64(time.Now().Add(challengeTimeout).Unix()))
+	if _, err := rand.Read(raw[8:]); err != nil {
+		return "", err
+	}
+
+	challenges.mutex.Lock()
+	defer challenges.mutex.Unlock()
+	mac, err := challenges.mac(raw)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(append(raw, mac...)), nil
+}
+
+// valid Whether challenge was issued, hasn't expired and wasn't used
+func (challenges *Challenges) valid(challenge string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(challenge)
+	if err != nil || len(raw) != 8+challengeRandomSize+challengeMACSize {
+		return false
+	}
+	issued, mac := raw[:8+challengeRandomSize], raw[8+challengeRandomSize:]
+
+	challenges.mutex.Lock()
+	defer challenges.mutex.Unlock()
+	expected, err := challenges.mac(issued)
+	if err != nil || !hmac.Equal(mac, expected) {
+		return false
+	}
+	if time.Now().After(time.Unix(int64(binary.BigEndian.Uint64(issued)), 0)) {
+		return false
+	}
+	_, used := challenges.used[challenge]
+
+	return !used
+}
+
+// Use Whether challenge is valid, it can't be used again. Call it once the ceremony is verified otherwise.
+func (challenges *Challenges) Use(challenge string) bool {
+	if !challenges.valid(challenge) {
+		return false
+	}
+
+	challenges.mutex.Lock()
+	defer challenges.mutex.Unlock()
+	if _, used := challenges.used[challenge]; used {
+		return false
+	}
+	if challenges.used == nil {
+		challenges.used = map[string]time.Time{}
+	}
+	//Expired challenges are refused anyway, they needn't be kept
+	now := time.Now()
+	for used, expiry := range challenges.used {
+		if now.After(expiry) {
+			delete(challenges.used, used)
+		}
+	}
+	challenges.used[challenge] = now.Add(challengeTimeout)
+
+	return true
+}
+
+// mac MAC of an issued challenge by a key made for challenges, mutex must be held
+func (challenges *Challenges) mac(issued []byte) ([]byte, error) {
+	if challenges.key == nil {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		challenges.key = key
+	}
+
+	mac := hmac.New(sha256.New, challenges.key)
+	mac.Write(issued)
+
+	return mac.Sum(nil)[:challengeMACSize], nil
+}
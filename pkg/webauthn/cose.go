@@ -0,0 +1,107 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithms of credentials, ES256 is supported by every authenticator
+const (
+	AlgorithmES256 = -7
+	AlgorithmEdDSA = -8
+	AlgorithmRS256 = -257
+)
+
+// COSE key types and curves
+const (
+	keyTypeOKP     = 1
+	keyTypeEC2     = 2
+	keyTypeRSA     = 3
+	curveP256      = 1
+	curveEd25519   = 6
+	coseKeyType    = 1
+	coseAlgorithm  = 3
+	coseCurve      = -1
+	coseX          = -2
+	coseY          = -3
+	coseRSAModulus = -1
+	coseRSAExp     = -2
+)
+
+// publicKey Public key of a credential parsed from COSE key
+type publicKey struct {
+	algorithm int64
+	key       crypto.PublicKey
+}
+
+// parsePublicKey Parse COSE key of a supported algorithm
+func parsePublicKey(coseKey []byte) (publicKey, error) {
+	item, rest, err := decodeCBOR(coseKey)
+	if err != nil {
+		return publicKey{}, err
+	}
+	if len(rest) > 0 {
+		return publicKey{}, errors.New("public key is followed by trailing data")
+	}
+	parameters, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return publicKey{}, errors.New("public key isn't a COSE key")
+	}
+	keyType, _ := parameters[int64(coseKeyType)].(int64)
+	algorithm, _ := parameters[int64(coseAlgorithm)].(int64)
+
+	switch {
+	case keyType == keyTypeEC2 && algorithm == AlgorithmES256:
+		curve, _ := parameters[int64(coseCurve)].(int64)
+		x, _ := parameters[int64(coseX)].([]byte)
+		y, _ := parameters[int64(coseY)].([]byte)
+		if curve != curveP256 || len(x) != 32 || len(y) != 32 {
+			return publicKey{}, errors.New("invalid P-256 public key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return publicKey{}, errors.New("public key isn't on P-256")
+		}
+		return publicKey{algorithm: algorithm, key: key}, nil
+	case keyType == keyTypeOKP && algorithm == AlgorithmEdDSA:
+		curve, _ := parameters[int64(coseCurve)].(int64)
+		x, _ := parameters[int64(coseX)].([]byte)
+		if curve != curveEd25519 || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, errors.New("invalid Ed25519 public key")
+		}
+		return publicKey{algorithm: algorithm, key: ed25519.PublicKey(x)}, nil
+	case keyType == keyTypeRSA && algorithm == AlgorithmRS256:
+		n, _ := parameters[int64(coseRSAModulus)].([]byte)
+		e, _ := parameters[int64(coseRSAExp)].([]byte)
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) < 256 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return publicKey{}, errors.New("invalid RSA public key")
+		}
+		return publicKey{algorithm: algorithm, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}}, nil
+	}
+
+	return publicKey{}, fmt.Errorf("unsupported public key of type %d and algorithm %d", keyType, algorithm)
+}
+
+// verify Whether signature of data is made by the private key
+func (key publicKey) verify(data []byte, signature []byte) bool {
+	switch key.algorithm {
+	case AlgorithmES256:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key.key.(*ecdsa.PublicKey), digest[:], signature)
+	case AlgorithmEdDSA:
+		return ed25519.Verify(key.key.(ed25519.PublicKey), data, signature)
+	case AlgorithmRS256:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key.key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	}
+
+	return false
+}
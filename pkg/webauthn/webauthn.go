@@ -0,0 +1,365 @@
+// Package webauthn verifies WebAuthn registration and assertion ceremonies of a relying party.
+// Attestation "none" is requested, so attestation statements aren't verified: credentials are trusted
+// as they're registered by a logged in user. Binary values of options and responses are base64url.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Flags of authenticator data
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+var ErrChallenge = errors.New("challenge is unknown, used or expired")
+
+// RelyingParty Site credentials are scoped to, ID is its domain and Origin the origin browsers report.
+// With UserVerification, authenticators must verify the user, such as by PIN or fingerprint, not only their presence.
+type RelyingParty struct {
+	ID               string
+	Name             string
+	Origin           string
+	UserVerification bool
+}
+
+// Credential Registered credential, PublicKey is its COSE key
+type Credential struct {
+	ID        string    `json:"id" yaml:"id"`
+	Name      string    `json:"name" yaml:"name"`
+	PublicKey string    `json:"-" yaml:"public-key"`
+	SignCount uint32    `json:"signCount" yaml:"sign-count"`
+	Created   time.Time `json:"created" yaml:"created"`
+}
+
+type Entity struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+type CredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions Options of navigator.credentials.create
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RelyingParty           Entity                 `json:"rp"`
+	User                   Entity                 `json:"user"`
+	Parameters             []CredentialParameter  `json:"pubKeyCredParams"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Timeout                int64                  `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions Options of navigator.credentials.get
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RelyingPartyID   string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+	Timeout          int64                  `json:"timeout"`
+}
+
+// AttestationResponse PublicKeyCredential created by navigator.credentials.create
+type AttestationResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse PublicKeyCredential asserted by navigator.credentials.get
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// clientData CollectedClientData signed by authenticators
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// authenticatorData Authenticator data of a ceremony, credential ID and key are only in attested data
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// CreationOptions Options registering a credential of user for challenge, credentials of user are excluded
+func (rp RelyingParty) CreationOptions(challenge string, userID []byte, userName string, credentials []Credential) CreationOptions {
+	return CreationOptions{
+		Challenge:    challenge,
+		RelyingParty: Entity{ID: rp.ID, Name: rp.Name},
+		User:         Entity{ID: base64.RawURLEncoding.EncodeToString(userID), Name: userName, DisplayName: userName},
+		Parameters: []CredentialParameter{
+			{Type: "public-key", Algorithm: AlgorithmES256},
+			{Type: "public-key", Algorithm: AlgorithmEdDSA},
+			{Type: "public-key", Algorithm: AlgorithmRS256},
+		},
+		ExcludeCredentials:     descriptors(credentials),
+		AuthenticatorSelection: AuthenticatorSelection{ResidentKey: "discouraged", UserVerification: rp.userVerification()},
+		Timeout:                challengeTimeout.Milliseconds(),
+		Attestation:            "none",
+	}
+}
+
+// RequestOptions Options asserting one of credentials for challenge
+func (rp RelyingParty) RequestOptions(challenge string, credentials []Credential) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		RelyingPartyID:   rp.ID,
+		AllowCredentials: descriptors(credentials),
+		UserVerification: rp.userVerification(),
+		Timeout:          challengeTimeout.Milliseconds(),
+	}
+}
+
+// userVerification User verification requirement of options
+func (rp RelyingParty) userVerification() string {
+	if rp.UserVerification {
+		return "required"
+	}
+
+	return "preferred"
+}
+
+// Register Verify a new credential created for one of challenges, and return it named name
+func (rp RelyingParty) Register(response AttestationResponse, challenges *Challenges, name string) (Credential, error) {
+	challenge, err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.create", challenges)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	rawObject, err := decodeBase64(response.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, err
+	}
+	item, _, err := decodeCBOR(rawObject)
+	if err != nil {
+		return Credential{}, err
+	}
+	attestationObject, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return Credential{}, errors.New("attestation object isn't a map")
+	}
+	rawData, ok := attestationObject["authData"].([]byte)
+	if !ok {
+		return Credential{}, errors.New("attestation object has no authenticator data")
+	}
+
+	data, err := rp.verifyAuthenticatorData(rawData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if data.flags&flagAttested == 0 {
+		return Credential{}, errors.New("authenticator data has no credential")
+	}
+	if id, err := decodeBase64(response.ID); err != nil || !bytes.Equal(id, data.credentialID) {
+		return Credential{}, errors.New("credential ID doesn't match authenticator data")
+	}
+	if _, err := parsePublicKey(data.publicKey); err != nil {
+		return Credential{}, err
+	}
+	if !challenges.Use(challenge) {
+		return Credential{}, ErrChallenge
+	}
+
+	return Credential{
+		ID:        base64.RawURLEncoding.EncodeToString(data.credentialID),
+		Name:      name,
+		PublicKey: base64.RawURLEncoding.EncodeToString(data.publicKey),
+		SignCount: data.signCount,
+		Created:   time.Now(),
+	}, nil
+}
+
+// Login Verify assertion of one of credentials for one of challenges, and return the credential asserted
+// with its new sign count. Sign counts which don't move on tell the credential is cloned, they're rejected.
+func (rp RelyingParty) Login(response AssertionResponse, challenges *Challenges, credentials []Credential) (Credential, error) {
+	id, err := decodeBase64(response.ID)
+	if err != nil {
+		return Credential{}, err
+	}
+	var credential Credential
+	found := false
+	for _, registered := range credentials {
+		if registered.ID == base64.RawURLEncoding.EncodeToString(id) {
+			credential, found = registered, true
+			break
+		}
+	}
+	if !found {
+		return Credential{}, errors.New("credential isn't registered")
+	}
+
+	challenge, err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.get", challenges)
+	if err != nil {
+		return Credential{}, err
+	}
+	rawData, err := decodeBase64(response.Response.AuthenticatorData)
+	if err != nil {
+		return Credential{}, err
+	}
+	data, err := rp.verifyAuthenticatorData(rawData)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	//Signature is of authenticator data followed by hash of client data
+	rawClientData, err := decodeBase64(response.Response.ClientDataJSON)
+	if err != nil {
+		return Credential{}, err
+	}
+	signature, err := decodeBase64(response.Response.Signature)
+	if err != nil {
+		return Credential{}, err
+	}
+	rawKey, err := decodeBase64(credential.PublicKey)
+	if err != nil {
+		return Credential{}, err
+	}
+	key, err := parsePublicKey(rawKey)
+	if err != nil {
+		return Credential{}, err
+	}
+	clientDataHash := sha256.Sum256(rawClientData)
+	if !key.verify(append(append([]byte{}, rawData...), clientDataHash[:]...), signature) {
+		return Credential{}, errors.New("signature isn't correct")
+	}
+
+	//Authenticators without counters always report 0
+	if (data.signCount != 0 || credential.SignCount != 0) && data.signCount <= credential.SignCount {
+		return Credential{}, fmt.Errorf("sign count %d doesn't follow %d, credential may be cloned", data.signCount, credential.SignCount)
+	}
+	if !challenges.Use(challenge) {
+		return Credential{}, ErrChallenge
+	}
+	credential.SignCount = data.signCount
+
+	return credential, nil
+}
+
+// verifyClientData Verify ceremony type, origin and challenge of client data, and return the challenge.
+// The challenge is only used once the whole ceremony is verified, so unverified ones can't use up challenges.
+func (rp RelyingParty) verifyClientData(encoded string, ceremony string, challenges *Challenges) (string, error) {
+	raw, err := decodeBase64(encoded)
+	if err != nil {
+		return "", err
+	}
+	var data clientData
+	err = json.Unmarshal(raw, &data)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := strings.TrimRight(data.Challenge, "=")
+	if !challenges.valid(challenge) {
+		return "", ErrChallenge
+	}
+	if data.Type != ceremony {
+		return "", fmt.Errorf("client data is of %q instead of %q", data.Type, ceremony)
+	}
+	if data.Origin != rp.Origin {
+		return "", fmt.Errorf("origin %q isn't %q", data.Origin, rp.Origin)
+	}
+
+	return challenge, nil
+}
+
+// verifyAuthenticatorData Parse authenticator data and verify it's of relying party and user is present,
+// or verified if relying party requires it
+func (rp RelyingParty) verifyAuthenticatorData(raw []byte) (authenticatorData, error) {
+	if len(raw) < 37 {
+		return authenticatorData{}, errors.New("authenticator data is too short")
+	}
+	data := authenticatorData{rpIDHash: raw[:32], flags: raw[32], signCount: binary.BigEndian.Uint32(raw[33:37])}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(data.rpIDHash, rpIDHash[:]) {
+		return authenticatorData{}, errors.New("authenticator data is of another relying party")
+	}
+	if data.flags&flagUserPresent == 0 {
+		return authenticatorData{}, errors.New("user isn't present")
+	}
+	if rp.UserVerification && data.flags&flagUserVerified == 0 {
+		return authenticatorData{}, errors.New("user isn't verified")
+	}
+
+	//Attested credential data is AAGUID, length of credential ID, credential ID and COSE key
+	if data.flags&flagAttested != 0 {
+		rest := raw[37:]
+		if len(rest) < 18 {
+			return authenticatorData{}, errors.New("attested credential data is too short")
+		}
+		length := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < length {
+			return authenticatorData{}, errors.New("attested credential data is too short")
+		}
+		data.credentialID = rest[:length]
+		rest = rest[length:]
+
+		_, extensions, err := decodeCBOR(rest)
+		if err != nil {
+			return authenticatorData{}, err
+		}
+		data.publicKey = rest[:len(rest)-len(extensions)]
+	}
+
+	return data, nil
+}
+
+// descriptors Descriptors of credentials for options
+func descriptors(credentials []Credential) []CredentialDescriptor {
+	descriptors := []CredentialDescriptor{}
+	for _, credential := range credentials {
+		descriptors = append(descriptors, CredentialDescriptor{Type: "public-key", ID: credential.ID})
+	}
+
+	return descriptors
+}
+
+// decodeBase64 Decode base64url as sent by browsers, padded values and standard base64 are accepted too
+func decodeBase64(value string) ([]byte, error) {
+	value = strings.TrimRight(value, "=")
+	value = strings.NewReplacer("+", "-", "/", "_").Replace(value)
+
+	return base64.RawURLEncoding.DecodeString(value)
+}
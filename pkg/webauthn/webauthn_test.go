@@ -0,0 +1,284 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"testing"
+)
+
+// encodeCBOR Encode integers, byte and text strings, and maps of them
+func encodeCBOR(value interface{}) []byte {
+	head := func(major byte, argument uint64) []byte {
+		switch {
+		case argument < 24:
+			return []byte{major<<5 | byte(argument)}
+		case argument < 1<<8:
+			return []byte{major<<5 | 24, byte(argument)}
+		case argument < 1<<16:
+			return []byte{major<<5 | 25, byte(argument >> 8), byte(argument)}
+		}
+		encoded := []byte{major<<5 | 26, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(encoded[1:], uint32(argument))
+		return encoded
+	}
+
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case map[interface{}]interface{}:
+		//Keys are sorted so that encoding is the same every time
+		var keys []interface{}
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return string(encodeCBOR(keys[i])) < string(encodeCBOR(keys[j]))
+		})
+		encoded := head(5, uint64(len(v)))
+		for _, key := range keys {
+			encoded = append(encoded, encodeCBOR(key)...)
+			encoded = append(encoded, encodeCBOR(v[key])...)
+		}
+		return encoded
+	}
+
+	panic("unsupported value")
+}
+
+// authenticator Software authenticator of one credential
+type authenticator struct {
+	id        []byte
+	signer    crypto.Signer
+	signCount uint32
+	verified  bool
+}
+
+func newAuthenticator(t *testing.T, eddsa bool) *authenticator {
+	var signer crypto.Signer
+	var err error
+	if eddsa {
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	} else {
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return &authenticator{id: id, signer: signer}
+}
+
+func (a *authenticator) coseKey() []byte {
+	switch key := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		x, y := make([]byte, 32), make([]byte, 32)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+		return encodeCBOR(map[interface{}]interface{}{1: 2, 3: AlgorithmES256, -1: 1, -2: x, -3: y})
+	case ed25519.PublicKey:
+		return encodeCBOR(map[interface{}]interface{}{1: 1, 3: AlgorithmEdDSA, -1: 6, -2: []byte(key)})
+	}
+
+	return nil
+}
+
+func (a *authenticator) authenticatorData(rpID string, attested bool) []byte {
+	a.signCount++
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	flags := byte(flagUserPresent)
+	if a.verified {
+		flags |= flagUserVerified
+	}
+	if attested {
+		flags |= flagAttested
+	}
+	data = append(data, flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = append(data, byte(len(a.id)>>8), byte(len(a.id)))
+		data = append(data, a.id...)
+		data = append(data, a.coseKey()...)
+	}
+
+	return data
+}
+
+func clientDataJSON(ceremony string, challenge string, origin string) string {
+	data, _ := json.Marshal(clientData{Type: ceremony, Challenge: challenge, Origin: origin})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (a *authenticator) create(rpID string, origin string, challenge string) AttestationResponse {
+	var response AttestationResponse
+	response.ID = base64.RawURLEncoding.EncodeToString(a.id)
+	response.Type = "public-key"
+	response.Response.ClientDataJSON = clientDataJSON("webauthn.create", challenge, origin)
+	response.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(encodeCBOR(map[interface{}]interface{}{
+		"fmt": "none", "attStmt": map[interface{}]interface{}{}, "authData": a.authenticatorData(rpID, true),
+	}))
+
+	return response
+}
+
+func (a *authenticator) get(t *testing.T, rpID string, origin string, challenge string) AssertionResponse {
+	var response AssertionResponse
+	response.ID = base64.RawURLEncoding.EncodeToString(a.id)
+	response.Type = "public-key"
+	response.Response.ClientDataJSON = clientDataJSON("webauthn.get", challenge, origin)
+	data := a.authenticatorData(rpID, false)
+	response.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(data)
+
+	rawClientData, _ := base64.RawURLEncoding.DecodeString(response.Response.ClientDataJSON)
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := append(data, clientDataHash[:]...)
+	var signature []byte
+	var err error
+	if _, ok := a.signer.(ed25519.PrivateKey); ok {
+		signature, err = a.signer.Sign(rand.Reader, signed, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(signed)
+		signature, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Response.Signature = base64.RawURLEncoding.EncodeToString(signature)
+
+	return response
+}
+
+func TestCeremonies(t *testing.T) {
+	rp := RelyingParty{ID: "informer.example", Name: "Informer", Origin: "https://informer.example"}
+	var challenges Challenges
+
+	for _, eddsa := range []bool{false, true} {
+		a := newAuthenticator(t, eddsa)
+
+		challenge, _ := challenges.New()
+		credential, err := rp.Register(a.create(rp.ID, rp.Origin, challenge), &challenges, "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rp.Register(a.create(rp.ID, rp.Origin, challenge), &challenges, "key"); err != ErrChallenge {
+			t.Errorf("challenge used twice: %v", err)
+		}
+		credentials := []Credential{credential}
+
+		challenge, _ = challenges.New()
+		asserted, err := rp.Login(a.get(t, rp.ID, rp.Origin, challenge), &challenges, credentials)
+		if err != nil || asserted.SignCount != a.signCount {
+			t.Fatalf("login: %+v, %v", asserted, err)
+		}
+		credentials[0] = asserted
+
+		//Assertions for another origin or relying party are rejected
+		challenge, _ = challenges.New()
+		if _, err := rp.Login(a.get(t, rp.ID, "https://evil.example", challenge), &challenges, credentials); err == nil {
+			t.Error("assertion of another origin accepted")
+		}
+		challenge, _ = challenges.New()
+		if _, err := rp.Login(a.get(t, "evil.example", rp.Origin, challenge), &challenges, credentials); err == nil {
+			t.Error("assertion of another relying party accepted")
+		}
+
+		//Tampered signatures and sign counts which don't move on are rejected
+		challenge, _ = challenges.New()
+		response := a.get(t, rp.ID, rp.Origin, challenge)
+		response.Response.ClientDataJSON = clientDataJSON("webauthn.get", challenge, rp.Origin+"/")
+		if _, err := rp.Login(response, &challenges, credentials); err == nil {
+			t.Error("tampered assertion accepted")
+		}
+		challenge, _ = challenges.New()
+		a.signCount = 0
+		if _, err := rp.Login(a.get(t, rp.ID, rp.Origin, challenge), &challenges, credentials); err == nil {
+			t.Error("assertion of a cloned credential accepted")
+		}
+
+		//Assertions of credentials which aren't registered are rejected
+		challenge, _ = challenges.New()
+		if _, err := rp.Login(newAuthenticator(t, eddsa).get(t, rp.ID, rp.Origin, challenge), &challenges, credentials); err == nil {
+			t.Error("assertion of an unknown credential accepted")
+		}
+
+		//Requiring user verification rejects assertions of presence only, and their challenge is still usable
+		verifying := rp
+		verifying.UserVerification = true
+		a.signCount = credentials[0].SignCount
+		challenge, _ = challenges.New()
+		if _, err := verifying.Login(a.get(t, rp.ID, rp.Origin, challenge), &challenges, credentials); err == nil {
+			t.Error("assertion without user verification accepted")
+		}
+		a.verified = true
+		if _, err := verifying.Login(a.get(t, rp.ID, rp.Origin, challenge), &challenges, credentials); err != nil {
+			t.Errorf("verified assertion: %v", err)
+		}
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	for _, invalid := range [][]byte{{}, {0x18}, {0x42, 0x01}, {0x9f}, {0xa1, 0x40, 0x01}, {0xfb, 0, 0, 0, 0, 0, 0, 0, 0}} {
+		if _, _, err := decodeCBOR(invalid); err == nil {
+			t.Errorf("% x decoded", invalid)
+		}
+	}
+
+	item, rest, err := decodeCBOR(append(encodeCBOR(map[interface{}]interface{}{1: -300, "a": []byte{1}}), 0xff))
+	if err != nil || len(rest) != 1 {
+		t.Fatal(err, rest)
+	}
+	decoded := item.(map[interface{}]interface{})
+	if decoded[int64(1)] != int64(-300) || len(decoded["a"].([]byte)) != 1 {
+		t.Errorf("decoded %v", decoded)
+	}
+}
+
+func TestChallenges(t *testing.T) {
+	var challenges, others Challenges
+
+	//Issuing challenges keeps nothing, so asking for many can't push out others
+	first, _ := challenges.New()
+	for i := 0; i < 1000; i++ {
+		_, _ = challenges.New()
+	}
+	if len(challenges.used) != 0 {
+		t.Errorf("%d challenges kept", len(challenges.used))
+	}
+	if !challenges.Use(first) || challenges.Use(first) {
+		t.Error("challenge isn't usable exactly once")
+	}
+
+	//Challenges of another server, tampered and made up ones are refused
+	other, _ := others.New()
+	own, _ := challenges.New()
+	tampered := []byte(own)
+	if tampered[12] == 'A' {
+		tampered[12] = 'B'
+	} else {
+		tampered[12] = 'A'
+	}
+	for _, challenge := range []string{other, string(tampered), "", "AAAA"} {
+		if challenges.Use(challenge) {
+			t.Errorf("challenge %q used", challenge)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"junjie.pro/informer/pkg/generator"
+	"junjie.pro/informer/pkg/library"
+	"sort"
+)
+
+func init() {
+	commands["rules"] = command{usage: "[-key key] [-platform name | -secure secure] [-clear] [rules]", run: runRules}
+	commands["regenerate"] = command{usage: "[-key key] [-length n] secure", run: runRegenerate}
+}
+
+// runRules Show or set password rules of a platform or a secure, in passwordrules syntax such as
+// "required: upper; allowed: ascii-printable; max-consecutive: 2". Without platform and secure, rules of all platforms are listed.
+// Rules of platforms belong to the set of secures key unlocks, the visible one without key.
+func runRules(args []string) error {
+	flagSet := newFlagSet("rules")
+	platform := flagSet.String("platform", "", "Platform the rules are for")
+	secureName := flagSet.String("secure", "", "Secure the rules are for, they override rules of its platform")
+	clear := flagSet.Bool("clear", false, "Remove the rules")
+	positional := parseFlags(flagSet, args)
+	if len(positional) > 1 || (*platform != "" && *secureName != "") {
+		flagSet.Usage()
+		return errors.New("rules are for either a platform or a secure, quote them as one argument")
+	}
+
+	switch {
+	case *secureName != "":
+		return setSecureRules(*secureName, positional, *clear)
+	case *platform != "":
+		return setPlatformRules(*platform, positional, *clear)
+	}
+
+	informerLibrary, err := readRulesLibrary()
+	if err != nil {
+		return err
+	}
+	defer informerLibrary.Wipe()
+	var platforms []string
+	for name := range informerLibrary.PlatformRules {
+		platforms = append(platforms, name)
+	}
+	sort.Strings(platforms)
+	for _, name := range platforms {
+		fmt.Printf("%s: %s\n", name, informerLibrary.PlatformRules[name])
+	}
+
+	return nil
+}
+
+// setPlatformRules Show rules of platform, or set them if given
+func setPlatformRules(platform string, positional []string, clear bool) error {
+	informerLibrary, err := readRulesLibrary()
+	if err != nil {
+		return err
+	}
+	defer informerLibrary.Wipe()
+	if len(positional) == 0 && !clear {
+		fmt.Println(informerLibrary.PlatformRules[platform])
+		return nil
+	}
+
+	rules := ""
+	if !clear {
+		rules = positional[0]
+	}
+	err = informerLibrary.SetPlatformRules(platform, rules)
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		err = informerLibrary.Lock([]byte(key))
+		if err != nil {
+			return err
+		}
+	}
+
+	return informerLibrary.WriteLibrary()
+}
+
+// readRulesLibrary Library unlocked by key if given, as rules of the sealed set are only seen by its key
+func readRulesLibrary() (library.InformerLibrary, error) {
+	if key == "" {
+		return library.ReadLibrary()
+	}
+
+	return unlockLibrary()
+}
+
+// setSecureRules Show rules of secure with the platform ones it falls back to, or set its own rules if given
+func setSecureRules(name string, positional []string, clear bool) error {
+	informerLibrary, err := unlockLibrary()
+	if err != nil {
+		return err
+	}
+	defer informerLibrary.Wipe()
+
+	k, err := findSecure(informerLibrary, name)
+	if err != nil {
+		return err
+	}
+	secure := *informerLibrary.SecureStore[k]
+	if len(positional) == 0 && !clear {
+		fmt.Println(informerLibrary.PasswordRules(secure))
+		return nil
+	}
+
+	secure.PasswordRules = ""
+	if !clear {
+		if _, err := generator.ParseRules(positional[0]); err != nil {
+			return err
+		}
+		secure.PasswordRules = positional[0]
+	}
+	informerLibrary.Update(k, secure)
+
+	err = informerLibrary.Lock([]byte(key))
+	if err != nil {
+		return err
+	}
+
+	return informerLibrary.WriteLibrary()
+}
+
+// runRegenerate Replace password of secure by a generated one satisfying its rules or rules of its platform, and print it
+func runRegenerate(args []string) error {
+	flagSet := newFlagSet("regenerate")
+	length := flagSet.Int("length", 0, "Characters of password, kept within rules (default 16)")
+	positional := parseFlags(flagSet, args)
+	if len(positional) != 1 {
+		flagSet.Usage()
+		return errors.New("secure is needed")
+	}
+
+	informerLibrary, err := unlockLibrary()
+	if err != nil {
+		return err
+	}
+	defer informerLibrary.Wipe()
+
+	k, err := findSecure(informerLibrary, positional[0])
+	if err != nil {
+		return err
+	}
+	password, err := informerLibrary.RegeneratePassword(k, *length)
+	if err != nil {
+		return err
+	}
+	fmt.Println(password)
+
+	err = informerLibrary.Lock([]byte(key))
+	if err != nil {
+		return err
+	}
+
+	return informerLibrary.WriteLibrary()
+}
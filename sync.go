@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"junjie.pro/informer/api"
+	"junjie.pro/informer/conf"
+	"junjie.pro/informer/pkg/library"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	commands["sync"] = command{usage: "[-server url] [-username name] [-password password] [-code code]", run: runSync}
+}
+
+// runSync Push local changes to the informer server, then pull changes made on it.
+// Server and username are remembered in the library after the first sync. Key must open the library on both sides.
+func runSync(args []string) error {
+	flagSet := newFlagSet("sync")
+	server := flagSet.String("server", "", "Address of informer server, such as http://localhost:8080")
+	username := flagSet.String("username", "", "Username of informer server")
+	password := flagSet.String("password", "", "Password of informer server, asked if not given")
+	code := flagSet.String("code", "", "Two-factor code of informer server, asked if the server needs one")
+	parseFlags(flagSet, args)
+
+	if key == "" {
+		return errors.New("key is needed")
+	}
+	informerLibrary, err := library.ReadLibrary()
+	if err != nil {
+		return err
+	}
+	err = informerLibrary.CheckSyncKey([]byte(key))
+	if err != nil {
+		return err
+	}
+	if informerLibrary.Upstream == nil {
+		informerLibrary.Upstream = &library.Upstream{}
+	}
+	if *server != "" {
+		informerLibrary.Upstream.Server = strings.TrimRight(*server, "/")
+	}
+	if *username != "" {
+		informerLibrary.Upstream.Username = *username
+	}
+	if informerLibrary.Upstream.Server == "" || informerLibrary.Upstream.Username == "" {
+		flagSet.Usage()
+		return errors.New("server and username are needed for the first sync")
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	if *password == "" {
+		fmt.Print("password: ")
+		scanner.Scan()
+		*password = scanner.Text()
+	}
+
+	user := conf.User{Username: informerLibrary.Upstream.Username, Password: *password, Code: *code}
+	client, err := login(informerLibrary.Upstream.Server, user)
+	//With two-factor authentication enabled, the code is asked once the server needs it
+	if errors.Is(err, errTwoFactorNeeded) && user.Code == "" {
+		fmt.Print("two-factor code: ")
+		scanner.Scan()
+		user.Code = scanner.Text()
+		client, err = login(informerLibrary.Upstream.Server, user)
+	}
+	if err != nil {
+		return err
+	}
+
+	//Server checks the key opens its library and pushed secures, so secures of different keys are never mixed
+	keyQuery := "?key=" + url.QueryEscape(key)
+
+	//Push local changes, conflicting ones are kept on server as copies
+	pending := informerLibrary.PendingChanges()
+	if len(pending) > 0 {
+		var pushed api.SyncBundle
+		err = syncRequest(client, http.MethodPost, informerLibrary.Upstream.Server+"/sync"+keyQuery, api.SyncBundle{Changes: pending}, &pushed)
+		if err != nil {
+			return err
+		}
+
+		for _, conflict := range pushed.Conflicts {
+			if conflict.CopyPrimaryKey != "" {
+				fmt.Println("conflict:", conflict.PrimaryKey, "changed on server, local change kept as", conflict.CopyPrimaryKey)
+			} else {
+				fmt.Println("conflict:", conflict.PrimaryKey, "changed on server, local removal dropped")
+			}
+		}
+
+		//Pushed changes are recorded at once, a failed pull mustn't push them again
+		informerLibrary.MarkPushed(len(pending), pushed.Changes)
+		err = informerLibrary.WriteLibrary()
+		if err != nil {
+			return err
+		}
+	}
+
+	//Pull changes made after last sync, including pushed ones and conflict copies
+	var pulled api.SyncBundle
+	since := strconv.FormatUint(informerLibrary.Upstream.Revision, 10)
+	err = syncRequest(client, http.MethodGet, informerLibrary.Upstream.Server+"/sync"+keyQuery+"&since="+since, nil, &pulled)
+	if err != nil {
+		return err
+	}
+	informerLibrary.ApplyPulled(pulled.Revision, pulled.Changes)
+
+	err = informerLibrary.WriteLibrary()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("pushed:", len(pending), "pulled:", len(pulled.Changes), "revision:", pulled.Revision)
+
+	return nil
+}
+
+// errTwoFactorNeeded Server needs a two-factor code besides the password
+var errTwoFactorNeeded = errors.New("login: two-factor code needed")
+
+// responseError Informer server didn't answer a request with success, message is given by the server
+type responseError struct {
+	request    string
+	status     int
+	statusText string
+	message    string
+}
+
+func (responseErr *responseError) Error() string {
+	return fmt.Sprintf("%s: %s %s", responseErr.request, responseErr.statusText, responseErr.message)
+}
+
+// login Log in to informer server, returned client keeps the login cookies
+func login(server string, user conf.User) (*http.Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Jar: jar}
+
+	err = syncRequest(client, http.MethodPost, server+"/login", user, nil)
+	var responseErr *responseError
+	if errors.As(err, &responseErr) && responseErr.status == http.StatusUnauthorized && responseErr.message == api.TwoFactorNeededMessage.Message {
+		return nil, errTwoFactorNeeded
+	}
+	if errors.As(err, &responseErr) && responseErr.message == api.PasswordDisabledMessage.Message {
+		return nil, errors.New("login: password login is disabled on server, enable two-factor authentication there to sync")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("login: %w", err)
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	if len(jar.Cookies(serverURL)) == 0 {
+		return nil, errors.New("login: server didn't give a token")
+	}
+
+	return client, nil
+}
+
+// syncRequest Send request as json, and decode json response into response if it isn't nil
+func syncRequest(client *http.Client, method string, location string, request interface{}, response interface{}) error {
+	var body bytes.Buffer
+	if request != nil {
+		err := json.NewEncoder(&body).Encode(request)
+		if err != nil {
+			return err
+		}
+	}
+
+	httpRequest, err := http.NewRequest(method, location, &body)
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	httpResponse, err := client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		var message api.Message
+		_ = json.NewDecoder(httpResponse.Body).Decode(&message)
+		return &responseError{request: method + " " + location, status: httpResponse.StatusCode, statusText: httpResponse.Status, message: message.Message}
+	}
+
+	if response == nil {
+		return nil
+	}
+
+	return json.NewDecoder(httpResponse.Body).Decode(response)
+}